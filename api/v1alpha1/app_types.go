@@ -18,14 +18,23 @@ package v1alpha1
 
 import (
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/robfig/cron/v3"
+	autoscale "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 
 	"github.com/k11n/konstellation/pkg/utils/objects"
 )
@@ -35,6 +44,11 @@ const (
 	TargetLabel = "k11n.dev/target"
 )
 
+var (
+	// error pages could replace a single status, or all of a class, i.e. 503 or 5xx
+	errorPageCodePattern = regexp.MustCompile(`^[45]([0-9]{2}|xx)$`)
+)
+
 // AppSpec defines the desired state of App
 type AppSpec struct {
 	Registry string `json:"registry,omitempty"`
@@ -52,6 +66,19 @@ type AppSpec struct {
 	// +optional
 	Configs []string `json:"configs,omitempty"`
 
+	// controls how the app config is exposed as env vars
+	// +kubebuilder:validation:Optional
+	// +nullable
+	// +optional
+	ConfigEnv *ConfigEnvSpec `json:"configEnv,omitempty"`
+
+	// JSON schema that the app config must conform to, in all targets
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +nullable
+	// +optional
+	ConfigSchema *runtime.RawExtension `json:"configSchema,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	// +optional
 	Scale ScaleSpec `json:"scale,omitempty"`
@@ -106,6 +133,46 @@ type AppCommonSpec struct {
 	// +kubebuilder:validation:Optional
 	// +optional
 	Probes ProbeConfig `json:"probes,omitempty"`
+
+	// how config changes are rolled out, with a new release (default) or live
+	// +kubebuilder:validation:Optional
+	// +optional
+	ConfigReload ConfigReloadMode `json:"configReload,omitempty"`
+
+	// how pods are notified of config changes when reloading live, sends SIGHUP by default
+	// +kubebuilder:validation:Optional
+	// +nullable
+	// +optional
+	ConfigReloadHook *ConfigReloadHook `json:"configReloadHook,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=release;live
+type ConfigReloadMode string
+
+const (
+	ConfigReloadRelease ConfigReloadMode = "release"
+	ConfigReloadLive    ConfigReloadMode = "live"
+
+	// configs are mounted here as files when reloading live
+	LiveConfigPath = "/etc/konstellation/config"
+)
+
+type ConfigReloadHook struct {
+	// signal sent to the main process of the app container, defaults to SIGHUP
+	// +optional
+	Signal string `json:"signal,omitempty"`
+
+	// when set, a POST request is sent to this path instead of a signal
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// name of the port to send the request to, defaults to the first port
+	// +optional
+	Port string `json:"port,omitempty"`
+
+	// restarts pods one at a time instead of notifying them, for apps that only read their config on start
+	// +optional
+	Restart bool `json:"restart,omitempty"`
 }
 
 // AppStatus defines the observed state of App
@@ -145,17 +212,89 @@ type PortSpec struct {
 
 type ScaleSpec struct {
 	TargetCPUUtilization int32 `json:"targetCPUUtilizationPercentage,omitempty"`
-	Min                  int32 `json:"min,omitempty"`
-	Max                  int32 `json:"max,omitempty"`
+	// requires a memory request
+	// +optional
+	TargetMemoryUtilization int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+	Min                     int32 `json:"min,omitempty"`
+	Max                     int32 `json:"max,omitempty"`
+	// metrics from Prometheus, served by the prometheus-adapter component
+	// +optional
+	Metrics []ScaleMetric `json:"metrics,omitempty"`
+	// scales the target to zero when it hasn't received requests for this long, i.e. 15m.
+	// min applies while it's active
+	// +optional
+	IdleTimeout *metav1.Duration `json:"idleTimeout,omitempty"`
+	// windows of time with different limits, the first active schedule is used
+	// +optional
+	Schedules []ScaleSchedule `json:"schedules,omitempty"`
 	// +optional
 	ScaleUp *ScaleBehavior `json:"scaleUp,omitempty"`
 	// +optional
 	ScaleDown *ScaleBehavior `json:"scaleDown,omitempty"`
 }
 
+type ScaleSchedule struct {
+	Name string `json:"name"`
+	// cron expression of when the window starts, i.e. 0 8 * * 1-5
+	Start string `json:"start"`
+	// how long the window lasts, i.e. 10h
+	Duration metav1.Duration `json:"duration"`
+	// time zone of the start time, i.e. America/Los_Angeles. UTC by default
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// overrides min of the target during the window
+	// +optional
+	Min int32 `json:"min,omitempty"`
+	// overrides max of the target during the window
+	// +optional
+	Max int32 `json:"max,omitempty"`
+}
+
+// +kubebuilder:validation:Enum=pods;external
+type ScaleMetricType string
+
+const (
+	// metrics of the app's pods, averaged across them
+	ScaleMetricPods ScaleMetricType = "pods"
+	// metrics that aren't associated with the app, such as the depth of a queue
+	ScaleMetricExternal ScaleMetricType = "external"
+)
+
+type ScaleMetric struct {
+	// name of the metric in Prometheus. counters ending in _total are available as a rate per second,
+	// i.e. http_requests_total as http_requests_per_second
+	Name string `json:"name"`
+	// pods by default
+	// +optional
+	Type ScaleMetricType `json:"type,omitempty"`
+	// value that each instance should handle, i.e. 100 requests per second, or 500m
+	Target resource.Quantity `json:"target"`
+	// labels that select the series of external metrics, i.e. queue: jobs
+	// +optional
+	Selector map[string]string `json:"selector,omitempty"`
+}
+
+const (
+	// steps are the number of instances that could be changed in this period
+	ScaleStepPeriod = 60
+	// longest delay that the autoscaler supports
+	MaxScaleDelay = 3600
+	// requests are counted from metrics that Prometheus scrapes periodically
+	MinIdleTimeout = 5 * time.Minute
+	// schedules are checked minute by minute, longer windows should be split up
+	MaxScheduleDuration = 7 * 24 * time.Hour
+)
+
 type ScaleBehavior struct {
-	Step  int32 `json:"step,omitempty"`
-	Delay int32 `json:"delay,omitempty"`
+	// max number of instances to add or remove in a minute
+	// +optional
+	Step int32 `json:"step,omitempty"`
+	// seconds of recommendations to consider before scaling, so that the app isn't scaled on a brief change.
+	// 0 for scaling up and 300 for scaling down by default
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	Delay *int32 `json:"delay,omitempty"`
 }
 
 type ProbeConfig struct {
@@ -181,9 +320,15 @@ type TargetConfig struct {
 	// +optional
 	// +kubebuilder:validation:Optional
 	DeployMode DeployMode `json:"deployMode,omitempty"`
+	// serves the maintenance page instead of the app. halted targets are always under maintenance
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
 	// if ingress is needed
 	// +optional
 	Ingress *IngressConfig `json:"ingress,omitempty"`
+	// exposes ports outside of the cluster through a load balancer, for protocols other than HTTP
+	// +optional
+	Expose *ExposeConfig `json:"expose,omitempty"`
 	// +optional
 	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
 	// +kubebuilder:validation:Optional
@@ -196,10 +341,31 @@ type TargetConfig struct {
 	Probes ProbeConfig `json:"probes,omitempty"`
 }
 
+// +kubebuilder:validation:Enum=public;internal
+type IngressVisibility string
+
+const (
+	IngressVisibilityPublic   IngressVisibility = "public"
+	IngressVisibilityInternal IngressVisibility = "internal"
+)
+
+type ExposeConfig struct {
+	// names of ports to expose, they should all use the same protocol
+	Ports []string `json:"ports"`
+
+	// custom annotations for the load balancer Service
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
 type IngressConfig struct {
 	Hosts []string `json:"hosts"`
+	// path prefixes routed to the app, a shorthand for routes without rewrites
 	// +kubebuilder:validation:Optional
 	Paths []string `json:"paths,omitempty"`
+	// path prefixes routed to the app. apps could share the same hosts as long as their paths are distinct
+	// +kubebuilder:validation:Optional
+	Routes []IngressRoute `json:"routes,omitempty"`
 	// +kubebuilder:validation:Optional
 	Port string `json:"port,omitempty"`
 
@@ -207,10 +373,121 @@ type IngressConfig struct {
 	// +optional
 	RequireHTTPS bool `json:"requireHttps,omitempty"`
 
+	// internal ingresses are served by a private load balancer, reachable only from within the VPC
+	// +optional
+	Visibility IngressVisibility `json:"visibility,omitempty"`
+
 	// custom annotations for the Ingress
 	// +optional
 	// +kubebuilder:validation:Optional
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// restricts access to requests from outside of the cluster
+	// +optional
+	Auth *IngressAuth `json:"auth,omitempty"`
+
+	// handles CORS requests for the app, so it doesn't need to
+	// +optional
+	CORS *CORSPolicy `json:"cors,omitempty"`
+
+	// limits the rate of requests that each client could make, enforced at the ingress gateway
+	// +optional
+	RateLimit *RateLimitConfig `json:"rateLimit,omitempty"`
+
+	// served while the target is under maintenance, defaults to a generic page
+	// +optional
+	MaintenancePage *MaintenancePage `json:"maintenancePage,omitempty"`
+
+	// HTML pages that replace error responses to browsers, by status code (i.e. 404) or class (i.e. 5xx)
+	// +optional
+	ErrorPages map[string]string `json:"errorPages,omitempty"`
+}
+
+type MaintenancePage struct {
+	// HTML content of the page, served with a 503
+	// +optional
+	HTML string `json:"html,omitempty"`
+	// redirects requests to this URL instead
+	// +optional
+	RedirectURL string `json:"redirectUrl,omitempty"`
+}
+
+type CORSPolicy struct {
+	// origins that could make requests, either exact matches or patterns with a * wildcard, e.g. https://*.example.com
+	AllowOrigins []string `json:"allowOrigins"`
+	// +optional
+	AllowMethods []string `json:"allowMethods,omitempty"`
+	// +optional
+	AllowHeaders []string `json:"allowHeaders,omitempty"`
+	// +optional
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+	// number of seconds that preflight responses could be cached for
+	// +optional
+	MaxAge int32 `json:"maxAge,omitempty"`
+	// +optional
+	AllowCredentials bool `json:"allowCredentials,omitempty"`
+}
+
+type RateLimitConfig struct {
+	// requests that each client could make per second
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerSecond int32 `json:"requestsPerSecond"`
+	// clients are identified by the value of this header, instead of their IP address
+	// +optional
+	Header string `json:"header,omitempty"`
+}
+
+type IngressAuth struct {
+	// authenticate users with an OpenID Connect provider
+	// +optional
+	OIDC *OIDCAuth `json:"oidc,omitempty"`
+
+	// name of a kubernetes.io/basic-auth secret in the target namespace
+	// +optional
+	BasicAuthSecret string `json:"basicAuthSecret,omitempty"`
+
+	// only accept requests from these CIDR blocks
+	// +optional
+	AllowedCIDRs []string `json:"allowedCidrs,omitempty"`
+}
+
+type OIDCAuth struct {
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"clientId"`
+	// name of a secret in the target namespace, with the client secret in the clientSecret key
+	ClientSecretName string `json:"clientSecretName"`
+
+	// endpoints are discovered from the issuer when left empty
+	// +optional
+	AuthorizationEndpoint string `json:"authorizationEndpoint,omitempty"`
+	// +optional
+	TokenEndpoint string `json:"tokenEndpoint,omitempty"`
+	// +optional
+	UserInfoEndpoint string `json:"userInfoEndpoint,omitempty"`
+
+	// scopes requested in addition to openid
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// set when the issuer's access tokens are JWTs. ALB passes the access token to the app after logging
+	// users in, and it's validated in the mesh only when this is set. otherwise requests authenticated by ALB
+	// are trusted as is
+	// +optional
+	JWTAccessTokens bool `json:"jwtAccessTokens,omitempty"`
+}
+
+type IngressRoute struct {
+	// +kubebuilder:validation:Pattern=^/
+	Path string `json:"path"`
+
+	// replaces the matched prefix before the request is passed to the app
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=^/
+	Rewrite string `json:"rewrite,omitempty"`
+
+	// removes the matched prefix, the same as rewriting it to /
+	// +optional
+	StripPrefix bool `json:"stripPrefix,omitempty"`
 }
 
 type AppReference struct {
@@ -310,6 +587,456 @@ func (a *App) GetAppTargetName(target string) string {
 	return fmt.Sprintf("%s-%s", a.Name, target)
 }
 
+// checks ingress configs of the app, as well as overrides in each target
+func (a *App) ValidateIngress() error {
+	for _, tc := range a.Spec.Targets {
+		if tc.Ingress == nil {
+			continue
+		}
+		if err := tc.Ingress.Validate(); err != nil {
+			return fmt.Errorf("invalid ingress for target %s: %v", tc.Name, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) ValidateScale() error {
+	if err := a.Spec.Scale.Validate(); err != nil {
+		return fmt.Errorf("invalid scale: %v", err)
+	}
+	for _, tc := range a.Spec.Targets {
+		if err := a.Spec.ScaleSpecForTarget(tc.Name).Validate(); err != nil {
+			return fmt.Errorf("invalid scale for target %s: %v", tc.Name, err)
+		}
+	}
+	return nil
+}
+
+func (a *App) ValidateExpose() error {
+	for _, tc := range a.Spec.Targets {
+		if tc.Expose == nil {
+			continue
+		}
+		if err := tc.Expose.Validate(a.Spec.Ports); err != nil {
+			return fmt.Errorf("invalid expose for target %s: %v", tc.Name, err)
+		}
+	}
+	return nil
+}
+
+// checks that exposed ports are declared. a load balancer could only use a single protocol
+func (c *ExposeConfig) Validate(ports []PortSpec) error {
+	if len(c.Ports) == 0 {
+		return fmt.Errorf("expose requires at least one port")
+	}
+	var protocol corev1.Protocol
+	for _, name := range c.Ports {
+		port := FindPort(ports, name)
+		if port == nil {
+			return fmt.Errorf("port %s is not declared", name)
+		}
+		if protocol != "" && port.GetProtocol() != protocol {
+			return fmt.Errorf("exposed ports should use the same protocol")
+		}
+		protocol = port.GetProtocol()
+	}
+	return nil
+}
+
+// returns exposed ports, in the order they are declared
+func (c *ExposeConfig) GetPorts(ports []PortSpec) []PortSpec {
+	var exposed []PortSpec
+	for _, port := range ports {
+		for _, name := range c.Ports {
+			if port.Name == name {
+				exposed = append(exposed, port)
+				break
+			}
+		}
+	}
+	return exposed
+}
+
+func FindPort(ports []PortSpec, name string) *PortSpec {
+	for i := range ports {
+		if ports[i].Name == name {
+			return &ports[i]
+		}
+	}
+	return nil
+}
+
+// TCP when not specified
+func (p *PortSpec) GetProtocol() corev1.Protocol {
+	if p.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return p.Protocol
+}
+
+// returns routes declared with both paths and routes, with longer prefixes first.
+// when nothing is declared, all requests are routed to the app
+func (c *IngressConfig) GetRoutes() []IngressRoute {
+	var routes []IngressRoute
+	for _, path := range c.Paths {
+		routes = append(routes, IngressRoute{Path: path})
+	}
+	routes = append(routes, c.Routes...)
+	if len(routes) == 0 {
+		routes = append(routes, IngressRoute{Path: "/"})
+	}
+	for i := range routes {
+		routes[i].Path = NormalizeIngressPath(routes[i].Path)
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].Path) > len(routes[j].Path)
+	})
+	return routes
+}
+
+// returns the path prefixes of all routes
+func (c *IngressConfig) GetPaths() []string {
+	var paths []string
+	for _, route := range c.GetRoutes() {
+		paths = append(paths, route.Path)
+	}
+	return paths
+}
+
+func (c *IngressConfig) Validate() error {
+	seen := make(map[string]bool)
+	for _, route := range c.GetRoutes() {
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("path %s should start with /", route.Path)
+		}
+		if seen[route.Path] {
+			return fmt.Errorf("path %s is routed more than once", route.Path)
+		}
+		seen[route.Path] = true
+		if route.Rewrite != "" && route.StripPrefix {
+			return fmt.Errorf("path %s could either be rewritten or have its prefix stripped, not both", route.Path)
+		}
+		if route.Rewrite != "" && !strings.HasPrefix(route.Rewrite, "/") {
+			return fmt.Errorf("rewrite %s should start with /", route.Rewrite)
+		}
+	}
+	if c.CORS != nil {
+		if err := c.CORS.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.RateLimit != nil {
+		if c.RateLimit.RequestsPerSecond <= 0 {
+			return fmt.Errorf("rateLimit requires requestsPerSecond")
+		}
+		if strings.ContainsAny(c.RateLimit.Header, " :") {
+			return fmt.Errorf("rateLimit header %s is not a valid header name", c.RateLimit.Header)
+		}
+	}
+	if c.MaintenancePage != nil {
+		if err := c.MaintenancePage.Validate(); err != nil {
+			return err
+		}
+	}
+	for code := range c.ErrorPages {
+		if !errorPageCodePattern.MatchString(code) {
+			return fmt.Errorf("error page %s should be a 4xx or 5xx status code, or a class like 5xx", code)
+		}
+	}
+	if c.Auth != nil {
+		return c.Auth.Validate()
+	}
+	return nil
+}
+
+func (p *MaintenancePage) Validate() error {
+	if p.HTML != "" && p.RedirectURL != "" {
+		return fmt.Errorf("maintenancePage could either serve html or redirect, not both")
+	}
+	if p.RedirectURL != "" {
+		u, err := url.Parse(p.RedirectURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("maintenancePage redirectUrl should be an absolute http(s) URL")
+		}
+	}
+	return nil
+}
+
+func (p *CORSPolicy) Validate() error {
+	if len(p.AllowOrigins) == 0 {
+		return fmt.Errorf("cors requires allowOrigins")
+	}
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" && p.AllowCredentials {
+			return fmt.Errorf("cors could not allow credentials from all origins")
+		}
+		if strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("cors origin %s could only contain a single wildcard", origin)
+		}
+	}
+	return nil
+}
+
+func (s *ScaleSpec) Validate() error {
+	if s.TargetCPUUtilization < 0 || s.TargetMemoryUtilization < 0 {
+		return fmt.Errorf("target utilization should not be negative")
+	}
+	if s.IdleTimeout != nil && s.IdleTimeout.Duration < MinIdleTimeout {
+		return fmt.Errorf("idleTimeout should be at least %v", MinIdleTimeout)
+	}
+	for i := range s.Schedules {
+		if err := s.Schedules[i].Validate(); err != nil {
+			return err
+		}
+	}
+	if err := s.ScaleUp.Validate(); err != nil {
+		return fmt.Errorf("scaleUp: %v", err)
+	}
+	if err := s.ScaleDown.Validate(); err != nil {
+		return fmt.Errorf("scaleDown: %v", err)
+	}
+	for _, m := range s.Metrics {
+		if m.Name == "" {
+			return fmt.Errorf("metrics require a name")
+		}
+		if m.Target.Sign() <= 0 {
+			return fmt.Errorf("metric %s requires a positive target", m.Name)
+		}
+		if len(m.Selector) != 0 && m.GetType() != ScaleMetricExternal {
+			return fmt.Errorf("metric %s could only have a selector when it's external", m.Name)
+		}
+	}
+	return nil
+}
+
+// returns the limits in effect at the time, and the schedule that set them
+func (s *ScaleSpec) BoundsAt(now time.Time) (min, max int32, schedule *ScaleSchedule) {
+	min, max = s.Min, s.Max
+	schedule = s.ActiveSchedule(now)
+	if schedule != nil {
+		if schedule.Min != 0 {
+			min = schedule.Min
+		}
+		if schedule.Max != 0 {
+			max = schedule.Max
+		}
+	}
+	if max < min {
+		max = min
+	}
+	return
+}
+
+// returns the first schedule whose window contains the time
+func (s *ScaleSpec) ActiveSchedule(now time.Time) *ScaleSchedule {
+	for i := range s.Schedules {
+		if s.Schedules[i].IsActive(now) {
+			return &s.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// returns when the active schedule could change next, looking ahead up to limit
+func (s *ScaleSpec) NextScheduleChange(now time.Time, limit time.Duration) time.Time {
+	next := now.Add(limit)
+	for i := range s.Schedules {
+		schedule := &s.Schedules[i]
+		cs, loc, err := schedule.parse()
+		if err != nil {
+			continue
+		}
+		localNow := now.In(loc)
+		if start := lastStart(cs, localNow, schedule.Duration.Duration); !start.IsZero() {
+			if end := start.Add(schedule.Duration.Duration); end.Before(next) {
+				next = end
+			}
+		}
+		if start := cs.Next(localNow); !start.IsZero() && start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+func (s *ScaleSchedule) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("schedules require a name")
+	}
+	if _, _, err := s.parse(); err != nil {
+		return fmt.Errorf("schedule %s: %v", s.Name, err)
+	}
+	if s.Duration.Duration < time.Minute {
+		return fmt.Errorf("schedule %s should last at least a minute", s.Name)
+	}
+	if s.Duration.Duration > MaxScheduleDuration {
+		return fmt.Errorf("schedule %s should last at most %v", s.Name, MaxScheduleDuration)
+	}
+	if s.Min < 0 || s.Max < 0 || (s.Max != 0 && s.Max < s.Min) {
+		return fmt.Errorf("schedule %s has invalid limits", s.Name)
+	}
+	return nil
+}
+
+// the window is active when the schedule started within its duration
+func (s *ScaleSchedule) IsActive(now time.Time) bool {
+	cs, loc, err := s.parse()
+	if err != nil {
+		return false
+	}
+	start := lastStart(cs, now.In(loc), s.Duration.Duration)
+	return !start.IsZero() && now.Before(start.Add(s.Duration.Duration))
+}
+
+func (s *ScaleSchedule) parse() (*cron.SpecSchedule, *time.Location, error) {
+	parsed, err := cron.ParseStandard(s.Start)
+	if err != nil {
+		return nil, nil, err
+	}
+	// windows need fixed start times, @every isn't supported
+	cs, ok := parsed.(*cron.SpecSchedule)
+	if !ok {
+		return nil, nil, fmt.Errorf("start should be a cron expression")
+	}
+	loc, err := loadLocation(s.TimeZone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cs, loc, nil
+}
+
+// returns the last time the schedule fired within the window before now, or the zero time
+func lastStart(cs *cron.SpecSchedule, now time.Time, window time.Duration) time.Time {
+	var last time.Time
+	for start := cs.Next(now.Add(-window)); !start.IsZero() && !start.After(now); start = cs.Next(start) {
+		last = start
+	}
+	return last
+}
+
+// time zones are loaded from the system database, cache them as schedules are evaluated on every reconcile
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// returns the behavior of the autoscaler, nil to use the defaults.
+// fields that aren't set are filled with the Kubernetes defaults, so the scaler matches what's stored
+func (s *ScaleSpec) ToHPABehavior() *autoscale.HorizontalPodAutoscalerBehavior {
+	if s.ScaleUp == nil && s.ScaleDown == nil {
+		return nil
+	}
+	scaleUp := &autoscale.HPAScalingRules{
+		StabilizationWindowSeconds: pointer.Int32Ptr(0),
+		Policies: []autoscale.HPAScalingPolicy{
+			{Type: autoscale.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+			{Type: autoscale.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+		},
+	}
+	// the scale down window is left to the controller's default of 5 minutes
+	scaleDown := &autoscale.HPAScalingRules{
+		Policies: []autoscale.HPAScalingPolicy{
+			{Type: autoscale.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+		},
+	}
+	s.ScaleUp.applyToHPAScalingRules(scaleUp)
+	s.ScaleDown.applyToHPAScalingRules(scaleDown)
+	return &autoscale.HorizontalPodAutoscalerBehavior{
+		ScaleUp:   scaleUp,
+		ScaleDown: scaleDown,
+	}
+}
+
+func (b *ScaleBehavior) Validate() error {
+	if b == nil {
+		return nil
+	}
+	if b.Step < 0 {
+		return fmt.Errorf("step should not be negative")
+	}
+	if b.Delay != nil && (*b.Delay < 0 || *b.Delay > MaxScaleDelay) {
+		return fmt.Errorf("delay should be between 0 and %d seconds", MaxScaleDelay)
+	}
+	return nil
+}
+
+func (b *ScaleBehavior) applyToHPAScalingRules(rules *autoscale.HPAScalingRules) {
+	selectPolicy := autoscale.MaxPolicySelect
+	rules.SelectPolicy = &selectPolicy
+	if b == nil {
+		return
+	}
+	if b.Step > 0 {
+		rules.Policies = []autoscale.HPAScalingPolicy{
+			{
+				Type:          autoscale.PodsScalingPolicy,
+				Value:         b.Step,
+				PeriodSeconds: ScaleStepPeriod,
+			},
+		}
+	}
+	if b.Delay != nil {
+		rules.StabilizationWindowSeconds = pointer.Int32Ptr(*b.Delay)
+	}
+}
+
+func (m *ScaleMetric) GetType() ScaleMetricType {
+	if m.Type == "" {
+		return ScaleMetricPods
+	}
+	return m.Type
+}
+
+func (a *IngressAuth) Validate() error {
+	if a.OIDC != nil {
+		if !strings.HasPrefix(a.OIDC.Issuer, "https://") {
+			return fmt.Errorf("oidc issuer should be a https URL")
+		}
+		if a.OIDC.ClientID == "" || a.OIDC.ClientSecretName == "" {
+			return fmt.Errorf("oidc requires both clientId and clientSecretName")
+		}
+		if a.BasicAuthSecret != "" {
+			// clients can't send both a bearer token and basic auth credentials
+			return fmt.Errorf("oidc and basicAuthSecret can't be used together")
+		}
+	}
+	for _, cidr := range a.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid CIDR %s", cidr)
+		}
+	}
+	return nil
+}
+
+// returns the prefix that replaces the path, or an empty string when it's passed through unchanged
+func (r *IngressRoute) GetRewrite() string {
+	if r.StripPrefix {
+		return "/"
+	}
+	return r.Rewrite
+}
+
+// removes trailing slashes, so /api and /api/ are treated as the same prefix
+func NormalizeIngressPath(path string) string {
+	path = strings.TrimRight(path, "/")
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
 func (p *Probe) ToCoreProbe() *corev1.Probe {
 	coreHander := corev1.Handler{
 		Exec: p.Handler.Exec,
@@ -2,10 +2,14 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	autoscale "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
 )
 
 func TestAppTargetResources(t *testing.T) {
@@ -61,3 +65,377 @@ func TestAppTargetResources(t *testing.T) {
 	resources = app.Spec.ResourcesForTarget("test")
 	assert.Equal(t, &cpu2, resources.Requests.Cpu())
 }
+
+func TestIngressRoutes(t *testing.T) {
+	ic := IngressConfig{}
+	assert.Equal(t, []IngressRoute{{Path: "/"}}, ic.GetRoutes())
+
+	ic.Paths = []string{"/"}
+	ic.Routes = []IngressRoute{
+		{Path: "/api/", StripPrefix: true},
+		{Path: "/docs", Rewrite: "/v2/docs"},
+	}
+	assert.Equal(t, []string{"/docs", "/api", "/"}, ic.GetPaths())
+	assert.NoError(t, ic.Validate())
+
+	routes := ic.GetRoutes()
+	assert.Equal(t, "/v2/docs", routes[0].GetRewrite())
+	assert.Equal(t, "/", routes[1].GetRewrite())
+	assert.Equal(t, "", routes[2].GetRewrite())
+
+	// same prefix declared twice
+	ic.Paths = []string{"/api"}
+	assert.Error(t, ic.Validate())
+
+	ic.Paths = nil
+	ic.Routes = []IngressRoute{{Path: "/api", Rewrite: "/v1", StripPrefix: true}}
+	assert.Error(t, ic.Validate())
+
+	ic.Routes = []IngressRoute{{Path: "api"}}
+	assert.Error(t, ic.Validate())
+}
+
+func TestIngressAuth(t *testing.T) {
+	auth := &IngressAuth{AllowedCIDRs: []string{"10.0.0.0/8"}}
+	assert.NoError(t, auth.Validate())
+
+	auth.AllowedCIDRs = []string{"10.0.0.1"}
+	assert.Error(t, auth.Validate())
+
+	auth = &IngressAuth{
+		OIDC: &OIDCAuth{
+			Issuer:           "https://accounts.example.com",
+			ClientID:         "client",
+			ClientSecretName: "oidc-secret",
+		},
+	}
+	assert.NoError(t, auth.Validate())
+
+	auth.BasicAuthSecret = "credentials"
+	assert.Error(t, auth.Validate())
+	auth.BasicAuthSecret = ""
+
+	auth.OIDC.Issuer = "http://accounts.example.com"
+	assert.Error(t, auth.Validate())
+
+	auth.OIDC.Issuer = "https://accounts.example.com"
+	auth.OIDC.ClientSecretName = ""
+	assert.Error(t, auth.Validate())
+
+	ic := IngressConfig{Auth: &IngressAuth{BasicAuthSecret: "credentials"}}
+	assert.NoError(t, ic.Validate())
+}
+
+func TestIngressPolicies(t *testing.T) {
+	ic := IngressConfig{
+		CORS: &CORSPolicy{
+			AllowOrigins:     []string{"https://*.example.com"},
+			AllowCredentials: true,
+		},
+		RateLimit: &RateLimitConfig{RequestsPerSecond: 10},
+	}
+	assert.NoError(t, ic.Validate())
+	ic.RateLimit.Header = "x-api-key"
+	assert.NoError(t, ic.Validate())
+	ic.RateLimit.Header = "x api key"
+	assert.Error(t, ic.Validate())
+	ic.RateLimit.Header = ""
+
+	ic.CORS.AllowOrigins = []string{"*"}
+	assert.Error(t, ic.Validate())
+	ic.CORS.AllowCredentials = false
+	assert.NoError(t, ic.Validate())
+
+	ic.CORS.AllowOrigins = nil
+	assert.Error(t, ic.Validate())
+	ic.CORS = nil
+
+	ic.RateLimit.RequestsPerSecond = 0
+	assert.Error(t, ic.Validate())
+}
+
+func TestIngressPages(t *testing.T) {
+	ic := IngressConfig{
+		MaintenancePage: &MaintenancePage{
+			RedirectURL: "https://status.example.com",
+		},
+		ErrorPages: map[string]string{
+			"404": "<h1>Not found</h1>",
+			"5xx": "<h1>Something went wrong</h1>",
+		},
+	}
+	assert.NoError(t, ic.Validate())
+
+	ic.MaintenancePage.HTML = "<h1>Be right back</h1>"
+	assert.Error(t, ic.Validate())
+	ic.MaintenancePage.RedirectURL = ""
+	assert.NoError(t, ic.Validate())
+
+	ic.MaintenancePage = &MaintenancePage{RedirectURL: "/maintenance"}
+	assert.Error(t, ic.Validate())
+	ic.MaintenancePage = nil
+
+	for _, code := range []string{"200", "3xx", "5x", "50x", "not-found"} {
+		ic.ErrorPages = map[string]string{code: "<h1>Error</h1>"}
+		assert.Error(t, ic.Validate(), code)
+	}
+
+	at := AppTarget{}
+	assert.False(t, at.InMaintenance())
+	at.Spec.DeployMode = DeployHalt
+	assert.True(t, at.InMaintenance())
+	at.Spec.DeployMode = DeployLatest
+	at.Spec.Maintenance = true
+	assert.True(t, at.InMaintenance())
+}
+
+func TestExposeConfig(t *testing.T) {
+	ports := []PortSpec{
+		{Name: "http", Port: 80},
+		{Name: "mqtt", Port: 1883},
+		{Name: "mqtts", Port: 8883, Protocol: corev1.ProtocolTCP},
+		{Name: "game", Port: 7777, Protocol: corev1.ProtocolUDP},
+	}
+
+	ec := ExposeConfig{Ports: []string{"mqtts", "mqtt"}}
+	assert.NoError(t, ec.Validate(ports))
+	exposed := ec.GetPorts(ports)
+	assert.Len(t, exposed, 2)
+	assert.Equal(t, "mqtt", exposed[0].Name)
+	assert.Equal(t, corev1.ProtocolTCP, exposed[0].GetProtocol())
+
+	// mixed protocols
+	ec.Ports = []string{"mqtt", "game"}
+	assert.Error(t, ec.Validate(ports))
+
+	ec.Ports = []string{"unknown"}
+	assert.Error(t, ec.Validate(ports))
+
+	ec.Ports = nil
+	assert.Error(t, ec.Validate(ports))
+
+	at := AppTarget{}
+	at.Spec.Ports = ports
+	assert.False(t, at.NeedsExpose())
+	at.Spec.Expose = &ExposeConfig{Ports: []string{"game"}}
+	assert.True(t, at.NeedsExpose())
+	assert.True(t, at.IsPortExposed("game"))
+	assert.False(t, at.IsPortExposed("http"))
+}
+
+func TestScaleSpec(t *testing.T) {
+	app := &App{
+		Spec: AppSpec{
+			Scale: ScaleSpec{
+				Min:                  1,
+				Max:                  5,
+				TargetCPUUtilization: 60,
+			},
+			Targets: []TargetConfig{
+				{
+					Name: "production",
+					Scale: ScaleSpec{
+						Metrics: []ScaleMetric{
+							{Name: "http_requests_per_second", Target: resource.MustParse("100")},
+							{
+								Name:     "queue_depth",
+								Type:     ScaleMetricExternal,
+								Target:   resource.MustParse("30"),
+								Selector: map[string]string{"queue": "jobs"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, app.ValidateScale())
+
+	scale := app.Spec.ScaleSpecForTarget("production")
+	assert.Equal(t, int32(60), scale.TargetCPUUtilization)
+	assert.Len(t, scale.Metrics, 2)
+	assert.Equal(t, ScaleMetricPods, scale.Metrics[0].GetType())
+
+	// selectors are only for external metrics
+	app.Spec.Targets[0].Scale.Metrics[0].Selector = map[string]string{"path": "/"}
+	assert.Error(t, app.ValidateScale())
+	app.Spec.Targets[0].Scale.Metrics[0].Selector = nil
+
+	app.Spec.Targets[0].Scale.Metrics[1].Target = resource.Quantity{}
+	assert.Error(t, app.ValidateScale())
+
+	at := AppTarget{}
+	at.Spec.Scale = ScaleSpec{Min: 1, Max: 5, TargetMemoryUtilization: 70}
+	// utilization requires a request
+	assert.False(t, at.NeedsAutoscaler())
+	at.Spec.Resources.Requests = corev1.ResourceList{
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	assert.True(t, at.NeedsAutoscaler())
+	assert.True(t, at.ScalesOnMemory())
+	assert.False(t, at.ScalesOnCPU())
+
+	at.Spec.Resources.Requests = nil
+	at.Spec.Scale.Metrics = scale.Metrics
+	assert.True(t, at.NeedsAutoscaler())
+
+	at.Spec.Scale.Max = 1
+	assert.False(t, at.NeedsAutoscaler())
+}
+
+func TestScaleBehavior(t *testing.T) {
+	scale := ScaleSpec{Min: 1, Max: 10}
+	assert.Nil(t, scale.ToHPABehavior())
+
+	scale.ScaleUp = &ScaleBehavior{Step: 2}
+	scale.ScaleDown = &ScaleBehavior{Step: 1, Delay: pointer.Int32Ptr(600)}
+	assert.NoError(t, scale.Validate())
+
+	behavior := scale.ToHPABehavior()
+	assert.Equal(t, int32(0), *behavior.ScaleUp.StabilizationWindowSeconds)
+	assert.Equal(t, []autoscale.HPAScalingPolicy{
+		{Type: autoscale.PodsScalingPolicy, Value: 2, PeriodSeconds: ScaleStepPeriod},
+	}, behavior.ScaleUp.Policies)
+	assert.Equal(t, autoscale.MaxPolicySelect, *behavior.ScaleUp.SelectPolicy)
+	assert.Equal(t, int32(600), *behavior.ScaleDown.StabilizationWindowSeconds)
+	assert.Equal(t, int32(1), behavior.ScaleDown.Policies[0].Value)
+
+	// only the window is changed, policies are defaulted
+	scale.ScaleUp = &ScaleBehavior{Delay: pointer.Int32Ptr(60)}
+	scale.ScaleDown = nil
+	behavior = scale.ToHPABehavior()
+	assert.Len(t, behavior.ScaleUp.Policies, 2)
+	assert.Equal(t, int32(60), *behavior.ScaleUp.StabilizationWindowSeconds)
+	assert.Nil(t, behavior.ScaleDown.StabilizationWindowSeconds)
+	assert.Len(t, behavior.ScaleDown.Policies, 1)
+
+	// scaling down right away
+	scale.ScaleDown = &ScaleBehavior{Delay: pointer.Int32Ptr(0)}
+	behavior = scale.ToHPABehavior()
+	assert.Equal(t, int32(0), *behavior.ScaleDown.StabilizationWindowSeconds)
+
+	scale.ScaleDown.Delay = pointer.Int32Ptr(MaxScaleDelay + 1)
+	assert.Error(t, scale.Validate())
+	scale.ScaleDown.Delay = nil
+	scale.ScaleDown.Step = -1
+	assert.Error(t, scale.Validate())
+}
+
+func TestScaleSchedules(t *testing.T) {
+	scale := ScaleSpec{
+		Min: 1,
+		Max: 4,
+		Schedules: []ScaleSchedule{
+			{
+				Name:     "business-hours",
+				Start:    "0 8 * * 1-5",
+				Duration: metav1.Duration{Duration: 10 * time.Hour},
+				Min:      4,
+				Max:      20,
+			},
+			{
+				Name:     "batch",
+				Start:    "0 2 * * *",
+				Duration: metav1.Duration{Duration: time.Hour},
+				Max:      8,
+			},
+		},
+	}
+	assert.NoError(t, scale.Validate())
+
+	// a Monday
+	monday := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	min, max, schedule := scale.BoundsAt(monday.Add(9 * time.Hour))
+	assert.Equal(t, "business-hours", schedule.Name)
+	assert.Equal(t, int32(4), min)
+	assert.Equal(t, int32(20), max)
+
+	min, max, schedule = scale.BoundsAt(monday.Add(2*time.Hour + 30*time.Minute))
+	assert.Equal(t, "batch", schedule.Name)
+	assert.Equal(t, int32(1), min)
+	assert.Equal(t, int32(8), max)
+
+	// the window ends after its duration
+	min, max, schedule = scale.BoundsAt(monday.Add(18 * time.Hour))
+	assert.Nil(t, schedule)
+	assert.Equal(t, int32(1), min)
+	assert.Equal(t, int32(4), max)
+
+	// next change is the start or end of a window
+	assert.Equal(t, monday.Add(2*time.Hour), scale.NextScheduleChange(monday.Add(time.Hour+30*time.Minute), time.Hour))
+	assert.Equal(t, monday.Add(3*time.Hour), scale.NextScheduleChange(monday.Add(2*time.Hour+30*time.Minute), time.Hour))
+	assert.Equal(t, monday.Add(5*time.Hour), scale.NextScheduleChange(monday.Add(4*time.Hour), time.Hour))
+
+	// schedules are in the time zone
+	scale.Schedules[0].TimeZone = "America/New_York"
+	if _, err := time.LoadLocation(scale.Schedules[0].TimeZone); err == nil {
+		_, _, schedule = scale.BoundsAt(monday.Add(9 * time.Hour))
+		assert.Nil(t, schedule)
+		_, _, schedule = scale.BoundsAt(monday.Add(14 * time.Hour))
+		assert.Equal(t, "business-hours", schedule.Name)
+	}
+
+	scale.Schedules[0].TimeZone = "Nowhere/Special"
+	assert.Error(t, scale.Validate())
+	scale.Schedules[0].TimeZone = ""
+	scale.Schedules[0].Start = "every day"
+	assert.Error(t, scale.Validate())
+	scale.Schedules[0].Start = "@every 1h"
+	assert.Error(t, scale.Validate())
+	scale.Schedules[0].Start = "0 8 * * *"
+	scale.Schedules[0].Duration.Duration = 0
+	assert.Error(t, scale.Validate())
+}
+
+func TestScaleToZero(t *testing.T) {
+	at := AppTarget{}
+	at.Spec.Scale = ScaleSpec{
+		Min:         2,
+		Max:         2,
+		IdleTimeout: &metav1.Duration{Duration: 15 * time.Minute},
+	}
+	assert.NoError(t, at.Spec.Scale.Validate())
+
+	// requests are needed to tell if it's idle
+	assert.False(t, at.ScalesToZero())
+	at.Spec.Ports = []PortSpec{{Name: "http", Port: 8080}}
+	assert.True(t, at.ScalesToZero())
+	assert.False(t, at.IsIdle())
+	assert.Equal(t, int32(2), at.DesiredInstances())
+
+	now := metav1.Now()
+	at.Status.IdleSince = &now
+	assert.True(t, at.IsIdle())
+	assert.Equal(t, int32(0), at.DesiredInstances())
+	assert.False(t, at.NeedsAutoscaler())
+
+	at.Spec.Scale.IdleTimeout.Duration = time.Minute
+	assert.Error(t, at.Spec.Scale.Validate())
+}
+
+func TestScaleBoundsForTraffic(t *testing.T) {
+	at := AppTarget{}
+	at.Spec.Scale = ScaleSpec{
+		Min: 4,
+		Max: 10,
+	}
+
+	min, max := at.ScaleBoundsForTraffic(100)
+	assert.Equal(t, int32(4), min)
+	assert.Equal(t, int32(10), max)
+
+	min, max = at.ScaleBoundsForTraffic(25)
+	assert.Equal(t, int32(1), min)
+	assert.Equal(t, int32(3), max)
+
+	// releases without traffic keep an instance
+	min, max = at.ScaleBoundsForTraffic(0)
+	assert.Equal(t, int32(1), min)
+	assert.Equal(t, int32(1), max)
+
+	assert.Equal(t, int32(8), at.InstancesForTraffic(6, 75))
+	assert.Equal(t, int32(4), at.InstancesForTraffic(1, 50))
+	assert.Equal(t, int32(10), at.InstancesForTraffic(5, 25))
+	assert.Equal(t, int32(6), at.InstancesForTraffic(6, 0))
+}
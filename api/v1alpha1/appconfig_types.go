@@ -17,14 +17,23 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
 	"github.com/pkg/errors"
 	"github.com/spf13/cast"
+	"github.com/xeipuuv/gojsonschema"
 	"gopkg.in/yaml.v3"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/pkg/utils/objects"
 )
 
 var (
@@ -36,12 +45,62 @@ const (
 	ConfigHashLabel   = "k11n.dev/configHash"
 	SharedConfigLabel = "k11n.dev/sharedConfig"
 
+	ConfigSkippedKeysAnnotation = "k11n.dev/skippedConfigKeys"
+	ConfigFilesAnnotation       = "k11n.dev/configFiles"
+
 	ConfigTypeApp    ConfigType = "app"
 	ConfigTypeShared ConfigType = "shared"
+
+	ListMergeReplace ListMergeStrategy = "replace"
+	ListMergeAppend  ListMergeStrategy = "append"
+
+	EnvListJoin  EnvListMode = "join"
+	EnvListIndex EnvListMode = "index"
+
+	defaultEnvListSeparator = ","
+
+	ConfigFormatYAML ConfigFormat = "yaml"
+	ConfigFormatJSON ConfigFormat = "json"
+	ConfigFormatTOML ConfigFormat = "toml"
+	ConfigFormatEnv  ConfigFormat = "env"
 )
 
 type ConfigType string
 
+// +kubebuilder:validation:Enum=replace;append
+type ListMergeStrategy string
+
+// +kubebuilder:validation:Enum=join;index
+type EnvListMode string
+
+// +kubebuilder:validation:Enum=yaml;json;toml;env
+type ConfigFormat string
+
+// ConfigEnvSpec controls how the app config is turned into env vars
+type ConfigEnvSpec struct {
+	// flattens nested config into env vars, i.e. db.host => DB_HOST
+	// +optional
+	Flatten bool `json:"flatten,omitempty"`
+
+	// prefix prepended to every env var generated from the config, i.e. MYAPP_
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// how lists are flattened: join (default) into a single var, or index into KEY_0, KEY_1, ...
+	// +optional
+	Lists EnvListMode `json:"lists,omitempty"`
+
+	// separator used when joining lists, defaults to ","
+	// +optional
+	ListSeparator string `json:"listSeparator,omitempty"`
+}
+
+// SkippedEnvKey is a config key that could not be converted into an env var
+type SkippedEnvKey struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:scope=Cluster
 // +kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.type`
@@ -53,6 +112,34 @@ type AppConfig struct {
 
 	Type       ConfigType `json:"type"`
 	ConfigYaml []byte     `json:"config"`
+
+	// how lists in this config are merged into the base config, replace by default
+	// +optional
+	ListMerge ListMergeStrategy `json:"listMerge,omitempty"`
+
+	// format that the config is edited in and passed to the app as, yaml by default.
+	// configs are always stored as YAML
+	// +optional
+	Format ConfigFormat `json:"format,omitempty"`
+
+	// renders Go templates in config values, i.e. {{ .Target }}. off by default, so that values containing
+	// {{ are passed through as is
+	// +optional
+	Templated bool `json:"templated,omitempty"`
+
+	// JSON schema for shared configs, set on the base config and applies to all targets.
+	// schemas for app configs are defined on the App
+	// +optional
+	Schema []byte `json:"schema,omitempty"`
+}
+
+// ConfigSchemaError contains all of the violations found when validating against the schema
+type ConfigSchemaError struct {
+	Errors []string
+}
+
+func (e *ConfigSchemaError) Error() string {
+	return fmt.Sprintf("config does not match schema:\n  %s", strings.Join(e.Errors, "\n  "))
 }
 
 // +kubebuilder:object:root=true
@@ -104,44 +191,335 @@ func (c *AppConfig) SetConfigYAML(conf []byte) error {
 	return nil
 }
 
+func (c *AppConfig) GetFormat() ConfigFormat {
+	if c.Format == "" {
+		return ConfigFormatYAML
+	}
+	return c.Format
+}
+
+// sets config from content in the given format, it'll be converted to YAML for storage
+func (c *AppConfig) SetConfigContent(content []byte, format ConfigFormat) error {
+	if format == "" || format == ConfigFormatYAML {
+		return c.SetConfigYAML(content)
+	}
+	if err := c.CheckEditFormat(format); err != nil {
+		return err
+	}
+	config, err := DecodeConfig(content, format)
+	if err != nil {
+		return errors.Wrapf(err, "config contains invalid %s", format)
+	}
+	if format == ConfigFormatEnv {
+		// env values are all strings, keep the types of existing values
+		existing := c.GetConfig()
+		for key, val := range config {
+			config[key] = envValueAs(existing[key], val.(string))
+		}
+	}
+	return c.SetConfig(config)
+}
+
+// env files only hold flat values, so a config could be edited as env only when its keys are env var names
+// and its values are scalars. other configs are flattened when passed to the app, but can't be edited as env
+func (c *AppConfig) CheckEditFormat(format ConfigFormat) error {
+	if format != ConfigFormatEnv {
+		return nil
+	}
+	config := c.GetConfig()
+	for _, key := range sortedKeys(config) {
+		if !allowedEnvVar.MatchString(key) {
+			return fmt.Errorf("%s is not an env var name, config can't be edited as env", key)
+		}
+		if _, ok := scalarToString(config[key]); !ok {
+			return fmt.Errorf("%s is not a string, number or boolean, config can't be edited as env", key)
+		}
+	}
+	return nil
+}
+
+func envValueAs(existing interface{}, val string) interface{} {
+	var converted interface{}
+	var err error
+	switch existing.(type) {
+	case bool:
+		converted, err = cast.ToBoolE(val)
+	case int:
+		converted, err = cast.ToIntE(val)
+	case float64:
+		converted, err = cast.ToFloat64E(val)
+	default:
+		return val
+	}
+	if err != nil {
+		return val
+	}
+	return converted
+}
+
+// returns config content in the config's format
+func (c *AppConfig) GetConfigContent() ([]byte, error) {
+	format := c.GetFormat()
+	if format == ConfigFormatYAML {
+		return c.ConfigYaml, nil
+	}
+	config := c.GetConfig()
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	return EncodeConfig(config, format)
+}
+
+// file extension for configs in this format
+func (f ConfigFormat) Extension() string {
+	if f == "" {
+		return "." + string(ConfigFormatYAML)
+	}
+	return "." + string(f)
+}
+
+func ConfigFormatForExtension(ext string) (ConfigFormat, error) {
+	switch ext {
+	case "", ".yaml", ".yml":
+		return ConfigFormatYAML, nil
+	case ".json":
+		return ConfigFormatJSON, nil
+	case ".toml":
+		return ConfigFormatTOML, nil
+	case ".env":
+		return ConfigFormatEnv, nil
+	}
+	return "", fmt.Errorf("unsupported config extension: %s", ext)
+}
+
+func DecodeConfig(content []byte, format ConfigFormat) (config map[string]interface{}, err error) {
+	config = make(map[string]interface{})
+	switch format {
+	case ConfigFormatYAML, "":
+		err = yaml.Unmarshal(content, &config)
+	case ConfigFormatJSON:
+		err = json.Unmarshal(content, &config)
+	case ConfigFormatTOML:
+		err = toml.Unmarshal(content, &config)
+	case ConfigFormatEnv:
+		var envMap map[string]string
+		envMap, err = godotenv.Unmarshal(string(content))
+		for key, val := range envMap {
+			config[key] = val
+		}
+	default:
+		err = fmt.Errorf("unsupported config format: %s", format)
+	}
+	return
+}
+
+// encodes config in the given format. nested values are flattened for env files, which can't be decoded
+// back into the same config
+func EncodeConfig(config map[string]interface{}, format ConfigFormat) ([]byte, error) {
+	switch format {
+	case ConfigFormatYAML, "":
+		return yaml.Marshal(config)
+	case ConfigFormatJSON:
+		return json.MarshalIndent(config, "", "  ")
+	case ConfigFormatTOML:
+		buf := bytes.NewBuffer(nil)
+		if err := toml.NewEncoder(buf).Encode(config); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case ConfigFormatEnv:
+		f := flattenConfig(config, &ConfigEnvSpec{Flatten: true})
+		content, err := godotenv.Marshal(f.data)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(content + "\n"), nil
+	}
+	return nil, fmt.Errorf("unsupported config format: %s", format)
+}
+
+// validates config against a JSON schema (in JSON or YAML). Errors will contain the path of offending fields
+func (c *AppConfig) ValidateSchema(schema []byte) error {
+	var schemaObj interface{}
+	if err := yaml.Unmarshal(schema, &schemaObj); err != nil {
+		return errors.Wrap(err, "schema is invalid")
+	}
+
+	config := c.GetConfig()
+	if config == nil {
+		config = make(map[string]interface{})
+	}
+	res, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schemaObj), gojsonschema.NewGoLoader(config))
+	if err != nil {
+		return errors.Wrap(err, "could not validate config")
+	}
+	if res.Valid() {
+		return nil
+	}
+
+	schemaErr := &ConfigSchemaError{}
+	for _, resErr := range res.Errors() {
+		// templated values are only known once rendered for a target, they can't be checked here
+		if str, ok := resErr.Value().(string); ok && c.Templated && strings.Contains(str, "{{") {
+			continue
+		}
+		schemaErr.Errors = append(schemaErr.Errors, fmt.Sprintf("%s: %s", resErr.Field(), resErr.Description()))
+	}
+	if len(schemaErr.Errors) == 0 {
+		return nil
+	}
+	return schemaErr
+}
+
+// deep merges other config into this one, nested keys in other take precedence
 func (c *AppConfig) MergeWith(other *AppConfig) {
 	config := c.GetConfig()
 	otherConfig := other.GetConfig()
 	if config == nil {
 		config = make(map[string]interface{})
 	}
-	for key, val := range otherConfig {
-		config[key] = val
-	}
+	objects.MergeMap(config, otherConfig, other.ListMerge == ListMergeAppend)
 	c.SetConfig(config)
+	c.Templated = c.Templated || other.Templated
 }
 
 func (c *AppConfig) ToEnvMap() map[string]string {
-	data := make(map[string]string)
-	for key, val := range c.GetConfig() {
-		var strVal string
-		switch val.(type) {
-		case string:
-			strVal = val.(string)
-		case int64, int, uint, uint64, float32, float64:
-			strVal = cast.ToString(val)
-		default:
-			// not eligible to be an env var
-			continue
-		}
+	data, _ := c.ToEnv(nil)
+	return data
+}
 
-		// ensure key is valid env chars
-		key = strings.ToUpper(key)
-		key = strings.ReplaceAll(key, "-", "_")
-		if allowedEnvVar.MatchString(key) {
-			data[key] = strVal
-		}
+// converts config into env vars, along with keys that couldn't be converted.
+// without flattening, only top level strings and numbers are included
+func (c *AppConfig) ToEnv(spec *ConfigEnvSpec) (data map[string]string, skipped []SkippedEnvKey) {
+	if spec == nil {
+		spec = &ConfigEnvSpec{}
 	}
+	f := flattenConfig(c.GetConfig(), spec)
 
 	// include config.yaml as a file
-	data[ConfigEnvVar] = string(c.ConfigYaml)
+	f.data[ConfigEnvVar] = string(c.ConfigYaml)
 
-	return data
+	return f.data, f.skipped
+}
+
+func flattenConfig(config map[string]interface{}, spec *ConfigEnvSpec) *envFlattener {
+	f := &envFlattener{
+		spec:    spec,
+		data:    make(map[string]string),
+		sources: make(map[string]string),
+	}
+	prefix := envName(spec.Prefix)
+	for _, key := range sortedKeys(config) {
+		f.add(key, prefix+envName(key), config[key])
+	}
+	return f
+}
+
+type envFlattener struct {
+	spec    *ConfigEnvSpec
+	data    map[string]string
+	sources map[string]string
+	skipped []SkippedEnvKey
+}
+
+func (f *envFlattener) add(path, name string, val interface{}) {
+	switch v := val.(type) {
+	case nil:
+		f.skip(path, "value is null")
+	case bool:
+		if !f.spec.Flatten {
+			f.skip(path, "booleans are only included when flattening")
+			return
+		}
+		f.set(path, name, strconv.FormatBool(v))
+	case map[string]interface{}:
+		if !f.spec.Flatten {
+			f.skip(path, "nested values are only included when flattening")
+			return
+		}
+		for _, key := range sortedKeys(v) {
+			f.add(path+"."+key, name+"_"+envName(key), v[key])
+		}
+	case []interface{}:
+		if !f.spec.Flatten {
+			f.skip(path, "lists are only included when flattening")
+			return
+		}
+		if f.spec.Lists == EnvListIndex {
+			for i, item := range v {
+				f.add(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("%s_%d", name, i), item)
+			}
+			return
+		}
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			strVal, ok := scalarToString(item)
+			if !ok {
+				f.skip(path, "list contains non-scalar values, use index mode to include")
+				return
+			}
+			items = append(items, strVal)
+		}
+		sep := f.spec.ListSeparator
+		if sep == "" {
+			sep = defaultEnvListSeparator
+		}
+		f.set(path, name, strings.Join(items, sep))
+	default:
+		strVal, ok := scalarToString(v)
+		if !ok {
+			f.skip(path, fmt.Sprintf("unsupported value type %T", v))
+			return
+		}
+		f.set(path, name, strVal)
+	}
+}
+
+func (f *envFlattener) set(path, name, val string) {
+	if !allowedEnvVar.MatchString(name) {
+		f.skip(path, fmt.Sprintf("%s is not a valid env var name", name))
+		return
+	}
+	if name == ConfigEnvVar {
+		f.skip(path, fmt.Sprintf("%s is reserved for the full config", name))
+		return
+	}
+	if source, ok := f.sources[name]; ok {
+		f.skip(path, fmt.Sprintf("%s is already set by %s", name, source))
+		return
+	}
+	f.sources[name] = path
+	f.data[name] = val
+}
+
+func (f *envFlattener) skip(path, reason string) {
+	f.skipped = append(f.skipped, SkippedEnvKey{Key: path, Reason: reason})
+}
+
+func scalarToString(val interface{}) (string, bool) {
+	switch v := val.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64, int, uint, uint64, float32, float64:
+		return cast.ToString(v), true
+	}
+	return "", false
+}
+
+func envName(key string) string {
+	key = strings.ToUpper(key)
+	return strings.ReplaceAll(key, "-", "_")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 func NewAppConfig(app, target string) *AppConfig {
@@ -0,0 +1,208 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppConfigMergeWith(t *testing.T) {
+	base := NewAppConfig("app", "")
+	err := base.SetConfigYAML([]byte(`
+db:
+  host: localhost
+  port: 5432
+hosts:
+  - a
+  - b
+name: base
+`))
+	assert.NoError(t, err)
+
+	target := NewAppConfig("app", "prod")
+	err = target.SetConfigYAML([]byte(`
+db:
+  host: db.prod
+hosts:
+  - c
+`))
+	assert.NoError(t, err)
+
+	// nested keys should be preserved, lists replaced
+	merged := base.DeepCopy()
+	merged.MergeWith(target)
+	config := merged.GetConfig()
+	db := config["db"].(map[string]interface{})
+	assert.Equal(t, "db.prod", db["host"])
+	assert.Equal(t, 5432, db["port"])
+	assert.Equal(t, []interface{}{"c"}, config["hosts"])
+	assert.Equal(t, "base", config["name"])
+
+	// append lists when requested
+	target.ListMerge = ListMergeAppend
+	merged = base.DeepCopy()
+	merged.MergeWith(target)
+	config = merged.GetConfig()
+	assert.Equal(t, []interface{}{"a", "b", "c"}, config["hosts"])
+
+	// templating on the target config applies to the merged config
+	assert.False(t, merged.Templated)
+	target.Templated = true
+	merged = base.DeepCopy()
+	merged.MergeWith(target)
+	assert.True(t, merged.Templated)
+}
+
+func TestAppConfigValidateSchema(t *testing.T) {
+	schema := []byte(`
+type: object
+required: [db]
+properties:
+  db:
+    type: object
+    properties:
+      port:
+        type: integer
+`)
+	ac := NewAppConfig("app", "")
+	assert.NoError(t, ac.SetConfigYAML([]byte("db:\n  port: 5432\n")))
+	assert.NoError(t, ac.ValidateSchema(schema))
+
+	assert.NoError(t, ac.SetConfigYAML([]byte("db:\n  port: abc\n")))
+	err := ac.ValidateSchema(schema)
+	assert.Error(t, err)
+	schemaErr, ok := err.(*ConfigSchemaError)
+	assert.True(t, ok)
+	assert.Len(t, schemaErr.Errors, 1)
+	assert.Contains(t, schemaErr.Errors[0], "db.port")
+
+	assert.NoError(t, ac.SetConfigYAML([]byte("dbs: {}\n")))
+	assert.Error(t, ac.ValidateSchema(schema))
+
+	// templated values aren't validated until they are rendered
+	assert.NoError(t, ac.SetConfigYAML([]byte("db:\n  port: \"{{ .Port }}\"\n")))
+	assert.Error(t, ac.ValidateSchema(schema))
+	ac.Templated = true
+	assert.NoError(t, ac.ValidateSchema(schema))
+}
+
+func TestAppConfigToEnv(t *testing.T) {
+	ac := NewAppConfig("app", "")
+	err := ac.SetConfigYAML([]byte(`
+name: myapp
+debug: true
+db:
+  host: localhost
+  port: 5432
+hosts:
+  - a
+  - b
+servers:
+  - name: one
+`))
+	assert.NoError(t, err)
+
+	t.Run("default", func(t *testing.T) {
+		data, skipped := ac.ToEnv(nil)
+		assert.Equal(t, "myapp", data["NAME"])
+		assert.NotContains(t, data, "DEBUG")
+		assert.NotContains(t, data, "DB_HOST")
+		assert.Contains(t, data, ConfigEnvVar)
+		assert.Len(t, skipped, 4)
+		assert.Equal(t, "db", skipped[0].Key)
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		data, skipped := ac.ToEnv(&ConfigEnvSpec{
+			Flatten: true,
+			Prefix:  "my-",
+		})
+		assert.Equal(t, "myapp", data["MY_NAME"])
+		assert.Equal(t, "true", data["MY_DEBUG"])
+		assert.Equal(t, "localhost", data["MY_DB_HOST"])
+		assert.Equal(t, "5432", data["MY_DB_PORT"])
+		assert.Equal(t, "a,b", data["MY_HOSTS"])
+		assert.Equal(t, []SkippedEnvKey{
+			{Key: "servers", Reason: "list contains non-scalar values, use index mode to include"},
+		}, skipped)
+	})
+
+	t.Run("index lists", func(t *testing.T) {
+		data, skipped := ac.ToEnv(&ConfigEnvSpec{
+			Flatten: true,
+			Lists:   EnvListIndex,
+		})
+		assert.Equal(t, "a", data["HOSTS_0"])
+		assert.Equal(t, "b", data["HOSTS_1"])
+		assert.Equal(t, "one", data["SERVERS_0_NAME"])
+		assert.Empty(t, skipped)
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		conflicting := NewAppConfig("app", "")
+		err := conflicting.SetConfigYAML([]byte(`
+db:
+  host: localhost
+db_host: other
+`))
+		assert.NoError(t, err)
+		data, skipped := conflicting.ToEnv(&ConfigEnvSpec{Flatten: true})
+		assert.Equal(t, "localhost", data["DB_HOST"])
+		assert.Len(t, skipped, 1)
+		assert.Equal(t, "db_host", skipped[0].Key)
+	})
+}
+
+func TestAppConfigFormats(t *testing.T) {
+	ac := NewAppConfig("app", "")
+	ac.Format = ConfigFormatTOML
+	err := ac.SetConfigContent([]byte(`
+name = "myapp"
+
+[db]
+host = "localhost"
+port = 5432
+`), ac.GetFormat())
+	assert.NoError(t, err)
+
+	// stored as YAML
+	config := ac.GetConfig()
+	assert.Equal(t, "myapp", config["name"])
+	assert.Equal(t, 5432, config["db"].(map[string]interface{})["port"])
+
+	content, err := ac.GetConfigContent()
+	assert.NoError(t, err)
+	decoded, err := DecodeConfig(content, ConfigFormatTOML)
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost", decoded["db"].(map[string]interface{})["host"])
+
+	ac.Format = ConfigFormatJSON
+	content, err = ac.GetConfigContent()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name": "myapp", "db": {"host": "localhost", "port": 5432}}`, string(content))
+
+	ac.Format = ConfigFormatEnv
+	content, err = ac.GetConfigContent()
+	assert.NoError(t, err)
+	assert.Equal(t, "DB_HOST=\"localhost\"\nDB_PORT=\"5432\"\nNAME=\"myapp\"\n", string(content))
+
+	// nested configs are flattened for the app, but can't be edited as env
+	err = ac.SetConfigContent([]byte("NAME=other\n"), ConfigFormatEnv)
+	assert.Error(t, err)
+	assert.Equal(t, "myapp", ac.GetConfig()["name"])
+
+	// flat configs keep the types of their values
+	assert.NoError(t, ac.SetConfigYAML([]byte("NAME: myapp\nPORT: 5432\nDEBUG: true\n")))
+	assert.NoError(t, ac.CheckEditFormat(ConfigFormatEnv))
+	err = ac.SetConfigContent([]byte("NAME=other\nPORT=8080\nDEBUG=false\nHOST=localhost\n"), ConfigFormatEnv)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"NAME":  "other",
+		"PORT":  8080,
+		"DEBUG": false,
+		"HOST":  "localhost",
+	}, ac.GetConfig())
+
+	err = ac.SetConfigContent([]byte("name = "), ConfigFormatTOML)
+	assert.Error(t, err)
+}
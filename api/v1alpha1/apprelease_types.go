@@ -38,6 +38,10 @@ type AppReleaseSpec struct {
 	TrafficPercentage int32       `json:"trafficPercentage"`
 
 	AppCommonSpec `json:",inline"`
+
+	// config that should be live, when it has been reloaded in place since the release was created
+	// +optional
+	LiveConfig string `json:"liveConfig,omitempty"`
 }
 
 // AppReleaseStatus defines the observed state of AppRelease
@@ -52,6 +56,15 @@ type AppReleaseStatus struct {
 	// +kubebuilder:validation:Optional
 	// +nullable
 	PodErrors []PodStatus `json:"podErrors,omitempty"`
+
+	// config that pods have been notified of, when reloading live
+	// +optional
+	LiveConfig string `json:"liveConfig,omitempty"`
+
+	// when mounted config files were last updated
+	// +kubebuilder:validation:Optional
+	// +nullable
+	LiveConfigUpdatedAt *metav1.Time `json:"liveConfigUpdatedAt,omitempty"`
 }
 
 type PodStatus struct {
@@ -115,6 +128,19 @@ type AppReleaseList struct {
 	Items           []AppRelease `json:"items"`
 }
 
+// returns the config that's current for this release
+func (ar *AppRelease) CurrentConfig() string {
+	if ar.Spec.LiveConfig != "" {
+		return ar.Spec.LiveConfig
+	}
+	return ar.Spec.Config
+}
+
+// name of the ConfigMap containing config files when reloading live
+func (ar *AppRelease) ConfigFilesName() string {
+	return ar.Name + "-config"
+}
+
 func (s *AppReleaseSpec) ContainerPorts() []corev1.ContainerPort {
 	ports := []corev1.ContainerPort{}
 	for _, p := range s.Ports {
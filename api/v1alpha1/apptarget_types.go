@@ -19,8 +19,11 @@ package v1alpha1
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/k11n/konstellation/pkg/utils/files"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 )
@@ -38,6 +41,9 @@ type AppTargetSpec struct {
 	// +kubebuilder:validation:Required
 	DeployMode DeployMode `json:"deployMode"`
 
+	// +optional
+	Maintenance bool `json:"maintenance,omitempty"`
+
 	AppCommonSpec `json:",inline"`
 
 	// +kubebuilder:validation:Optional
@@ -45,6 +51,12 @@ type AppTargetSpec struct {
 	// +optional
 	Configs []string `json:"configs,omitempty"`
 
+	// controls how the app config is exposed as env vars
+	// +kubebuilder:validation:Optional
+	// +nullable
+	// +optional
+	ConfigEnv *ConfigEnvSpec `json:"configEnv,omitempty"`
+
 	// +kubebuilder:validation:Optional
 	// +optional
 	Scale ScaleSpec `json:"scale,omitempty"`
@@ -57,6 +69,9 @@ type AppTargetSpec struct {
 	// +kubebuilder:validation:Optional
 	// +nullable
 	Prometheus *PrometheusSpec `json:"prometheus,omitempty"`
+
+	// +optional
+	Expose *ExposeConfig `json:"expose,omitempty"`
 }
 
 type AppTargetPhase string
@@ -80,6 +95,33 @@ type AppTargetStatus struct {
 	NumReady     int32        `json:"numReady"`
 	NumAvailable int32        `json:"numAvailable"`
 	Hostname     string       `json:"hostname,omitempty"`
+	// address of the load balancer that exposed ports are reachable at,
+	// also reported as the hostname when there's no ingress
+	ExposedAddress string `json:"exposedAddress,omitempty"`
+	// when the target was scaled to zero for not receiving requests
+	// +kubebuilder:validation:Optional
+	// +nullable
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
+	// when requests last woke up the target, set by the activator
+	// +kubebuilder:validation:Optional
+	// +nullable
+	ActivatedAt *metav1.Time `json:"activatedAt,omitempty"`
+	// when instances of the target became available, after it's been deployed or woken up
+	// +kubebuilder:validation:Optional
+	// +nullable
+	AvailableSince *metav1.Time `json:"availableSince,omitempty"`
+	// resources recommended from observed usage, when recommendations are enabled on the operator
+	// +kubebuilder:validation:Optional
+	// +nullable
+	Recommendation *ResourceRecommendation `json:"recommendation,omitempty"`
+}
+
+// ResourceRecommendation is computed from percentiles of the target's CPU and memory usage
+type ResourceRecommendation struct {
+	Resources corev1.ResourceRequirements `json:"resources"`
+	// period of usage that it's computed from
+	Window    metav1.Duration `json:"window"`
+	UpdatedAt metav1.Time     `json:"updatedAt"`
 }
 
 // +kubebuilder:object:root=true
@@ -99,6 +141,14 @@ type AppTarget struct {
 
 	Spec   AppTargetSpec   `json:"spec,omitempty"`
 	Status AppTargetStatus `json:"status,omitempty"`
+
+	// bounds resolved for a reconcile, so that schedules are evaluated once
+	scaleBounds *scaleBounds
+}
+
+type scaleBounds struct {
+	min int32
+	max int32
 }
 
 // +kubebuilder:object:root=true
@@ -122,15 +172,73 @@ func (at *AppTarget) TargetNamespace() string {
 }
 
 func (at *AppTarget) DesiredInstances() int32 {
-	if at.Spec.DeployMode == DeployHalt {
+	if at.Spec.DeployMode == DeployHalt || at.IsIdle() {
 		return 0
 	}
-	instances := at.Spec.Scale.Min
+	min, max := at.ScaleBounds()
+	instances := min
 	if at.Status.NumDesired > instances {
 		instances = at.Status.NumDesired
 	}
-	if instances > at.Spec.Scale.Max {
-		instances = at.Spec.Scale.Max
+	if instances > max {
+		instances = max
+	}
+	return instances
+}
+
+// targets could only be idle when they serve requests
+func (at *AppTarget) ScalesToZero() bool {
+	return at.Spec.Scale.IdleTimeout != nil && at.NeedsService()
+}
+
+func (at *AppTarget) IsIdle() bool {
+	return at.ScalesToZero() && at.Status.IdleSince != nil
+}
+
+// min and max instances at the moment, after applying schedules
+func (at *AppTarget) ScaleBounds() (min, max int32) {
+	if at.scaleBounds != nil {
+		return at.scaleBounds.min, at.scaleBounds.max
+	}
+	min, max, _ = at.Spec.Scale.BoundsAt(time.Now())
+	return
+}
+
+// resolves the bounds at the time, so that they stay consistent while the target is reconciled
+func (at *AppTarget) ResolveScaleBounds(now time.Time) {
+	min, max, _ := at.Spec.Scale.BoundsAt(now)
+	at.scaleBounds = &scaleBounds{min: min, max: max}
+}
+
+// during a deploy, each release is autoscaled within its share of the bounds, proportional to the percentage
+// of traffic it receives. a release always has at least one instance
+func (at *AppTarget) ScaleBoundsForTraffic(percentage int32) (min, max int32) {
+	min, max = at.ScaleBounds()
+	if percentage < 100 {
+		min = int32(math.Ceil(float64(min) * float64(percentage) / 100))
+		max = int32(math.Ceil(float64(max) * float64(percentage) / 100))
+	}
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return
+}
+
+// estimates the instances needed to serve all of the traffic, from a release that's serving a percentage of it.
+// the estimate is within the current bounds
+func (at *AppTarget) InstancesForTraffic(instances int32, percentage int32) int32 {
+	min, max := at.ScaleBounds()
+	if percentage > 0 && percentage < 100 {
+		instances = int32(math.Ceil(float64(instances) * 100 / float64(percentage)))
+	}
+	if instances < min {
+		instances = min
+	}
+	if instances > max {
+		instances = max
 	}
 	return instances
 }
@@ -147,18 +255,47 @@ func (at *AppTarget) NeedsIngress() bool {
 	return len(at.Spec.Ingress.Hosts) > 0
 }
 
-func (at *AppTarget) NeedsAutoscaler() bool {
-	cpu := at.Spec.Resources.Requests.Cpu()
-	if cpu == nil {
+// ingress traffic is served the maintenance page while the target is under maintenance or halted
+func (at *AppTarget) InMaintenance() bool {
+	return at.Spec.Maintenance || at.Spec.DeployMode == DeployHalt
+}
+
+func (at *AppTarget) NeedsExpose() bool {
+	return at.Spec.Expose != nil && len(at.Spec.Expose.GetPorts(at.Spec.Ports)) > 0
+}
+
+// returns true if the port is exposed through a load balancer
+func (at *AppTarget) IsPortExposed(name string) bool {
+	if at.Spec.Expose == nil {
 		return false
 	}
-	if at.Spec.Scale.TargetCPUUtilization == 0 {
+	for _, port := range at.Spec.Expose.Ports {
+		if port == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (at *AppTarget) NeedsAutoscaler() bool {
+	if at.IsIdle() {
 		return false
 	}
-	if at.Spec.Scale.Min == at.Spec.Scale.Max {
+	if min, max := at.ScaleBounds(); min == max {
 		return false
 	}
-	return true
+	return at.ScalesOnCPU() || at.ScalesOnMemory() || len(at.Spec.Scale.Metrics) != 0
+}
+
+// utilization is relative to requests, so it's only used when there's a request
+func (at *AppTarget) ScalesOnCPU() bool {
+	_, ok := at.Spec.Resources.Requests[corev1.ResourceCPU]
+	return ok && at.Spec.Scale.TargetCPUUtilization > 0
+}
+
+func (at *AppTarget) ScalesOnMemory() bool {
+	_, ok := at.Spec.Resources.Requests[corev1.ResourceMemory]
+	return ok && at.Spec.Scale.TargetMemoryUtilization > 0
 }
 
 func (at *AppTarget) GetHash() string {
@@ -170,10 +307,12 @@ func (at *AppTarget) UpdateHash() error {
 	atCopy := at.DeepCopy()
 	// clear fields that we don't need to include in hash
 	atCopy.Spec.Ingress = nil
+	atCopy.Spec.Expose = nil
 	atCopy.Status = AppTargetStatus{}
 	atCopy.Labels = nil
 	atCopy.Annotations = nil
 	atCopy.Spec.DeployMode = DeployLatest
+	atCopy.Spec.Maintenance = false
 	atCopy.Spec.Scale = ScaleSpec{}
 	encoder := json.NewSerializerWithOptions(json.DefaultMetaFactory, nil, nil,
 		json.SerializerOptions{
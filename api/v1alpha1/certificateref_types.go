@@ -17,21 +17,116 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // CertificateRefSpec defines the desired state of CertificateRef
 type CertificateRefSpec struct {
-	ProviderID         string      `json:"providerId"`
-	Domain             string      `json:"domain"`
-	Issuer             string      `json:"issuer"`
-	Status             string      `json:"status"`
+	ProviderID string `json:"providerId"`
+	Domain     string `json:"domain"`
+	Issuer     string `json:"issuer"`
+	Status     string `json:"status"`
+	// not set until the certificate has been issued
+	// +kubebuilder:validation:Optional
+	// +nullable
 	ExpiresAt          metav1.Time `json:"expiresAt"`
 	KeyAlgorithm       string      `json:"keyAlgorithm"`
 	SignatureAlgorithm string      `json:"signatureAlgorithm"`
+
+	// where the certificate was obtained, the cloud provider's certificate manager by default
+	// +optional
+	Source CertificateSource `json:"source,omitempty"`
+
+	// secret in the ingress namespace containing the certificate and key, for ACME certificates
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// DNS records that need to exist in order to validate domain ownership
+	// +optional
+	ValidationRecords []CertificateValidationRecord `json:"validationRecords,omitempty"`
+
+	// when the certificate should be renewed, for certificates that Konstellation renews
+	// +kubebuilder:validation:Optional
+	// +nullable
+	RenewAt *metav1.Time `json:"renewAt,omitempty"`
+
+	// error encountered during the last attempt to issue or renew the certificate
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// when issuing is attempted again after a failure
+	// +kubebuilder:validation:Optional
+	// +nullable
+	RetryAt *metav1.Time `json:"retryAt,omitempty"`
+
+	// order that's being validated by the ACME CA
+	// +kubebuilder:validation:Optional
+	// +nullable
+	ACMEOrder *ACMEOrder `json:"acmeOrder,omitempty"`
+}
+
+type ACMEOrder struct {
+	URL       string      `json:"url"`
+	CreatedAt metav1.Time `json:"createdAt"`
+	// challenges that have been set up to validate the domain
+	// +optional
+	Challenges []ACMEChallenge `json:"challenges,omitempty"`
+}
+
+type ACMEChallenge struct {
+	// domain being validated, without the wildcard
+	Domain string `json:"domain"`
+	// http-01 or dns-01
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// CertificateRefStatus defines the observed state of CertificateRef
+type CertificateRefStatus struct {
+	// +optional
+	Conditions []CertificateCondition `json:"conditions,omitempty"`
+}
+
+type CertificateCondition struct {
+	Type               CertificateConditionType `json:"type"`
+	Status             corev1.ConditionStatus   `json:"status"`
+	LastTransitionTime metav1.Time              `json:"lastTransitionTime,omitempty"`
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type CertificateConditionType string
+
+const (
+	// certificate expires within the warning period
+	CertificateExpiringSoon CertificateConditionType = "ExpiringSoon"
+	CertificateExpired      CertificateConditionType = "Expired"
+)
+
+type CertificateValidationRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
 }
 
+// +kubebuilder:validation:Enum=provider;acme
+type CertificateSource string
+
+const (
+	// imported into or requested from the cloud provider
+	CertificateSourceProvider CertificateSource = "provider"
+	// issued by an ACME CA, and stored as a secret in the cluster
+	CertificateSourceACME CertificateSource = "acme"
+)
+
 // +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster
 
 // CertificateRef is the Schema for the certificaterefs API
@@ -39,7 +134,8 @@ type CertificateRef struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec CertificateRefSpec `json:"spec,omitempty"`
+	Spec   CertificateRefSpec   `json:"spec,omitempty"`
+	Status CertificateRefStatus `json:"status,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -51,6 +147,63 @@ type CertificateRefList struct {
 	Items           []CertificateRef `json:"items"`
 }
 
+func (c *CertificateRef) GetSource() CertificateSource {
+	if c.Spec.Source == "" {
+		return CertificateSourceProvider
+	}
+	return c.Spec.Source
+}
+
+// returns true when the certificate has been issued and could be used to serve traffic
+func (c *CertificateRef) IsReady() bool {
+	return c.Spec.Status == "READY"
+}
+
+func (c *CertificateRef) IsExpired(now time.Time) bool {
+	return !c.Spec.ExpiresAt.IsZero() && !now.Before(c.Spec.ExpiresAt.Time)
+}
+
+// returns true when the certificate expires within the given period
+func (c *CertificateRef) IsExpiringSoon(now time.Time, within time.Duration) bool {
+	return !c.Spec.ExpiresAt.IsZero() && !c.IsExpired(now) && c.Spec.ExpiresAt.Sub(now) <= within
+}
+
+// returns true when the certificate is expired or expiring soon, according to its conditions
+func (c *CertificateRef) IsAtRisk() bool {
+	for _, t := range []CertificateConditionType{CertificateExpired, CertificateExpiringSoon} {
+		if cond := c.Status.GetCondition(t); cond != nil && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CertificateRefStatus) GetCondition(condType CertificateConditionType) *CertificateCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == condType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// updates the condition, returns true if its status has changed
+func (s *CertificateRefStatus) SetCondition(condType CertificateConditionType, status corev1.ConditionStatus, reason, message string) bool {
+	cond := s.GetCondition(condType)
+	if cond == nil {
+		s.Conditions = append(s.Conditions, CertificateCondition{Type: condType})
+		cond = &s.Conditions[len(s.Conditions)-1]
+	}
+	changed := cond.Status != status
+	if changed {
+		cond.LastTransitionTime = metav1.Now()
+	}
+	cond.Status = status
+	cond.Reason = reason
+	cond.Message = message
+	return changed
+}
+
 func init() {
 	SchemeBuilder.Register(&CertificateRef{}, &CertificateRefList{})
 }
@@ -0,0 +1,49 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCertificateExpiry(t *testing.T) {
+	now := time.Now()
+	within := 21 * 24 * time.Hour
+	cert := CertificateRef{}
+	assert.False(t, cert.IsExpired(now))
+	assert.False(t, cert.IsExpiringSoon(now, within))
+
+	cert.Spec.ExpiresAt = metav1.NewTime(now.Add(30 * 24 * time.Hour))
+	assert.False(t, cert.IsExpired(now))
+	assert.False(t, cert.IsExpiringSoon(now, within))
+
+	cert.Spec.ExpiresAt = metav1.NewTime(now.Add(10 * 24 * time.Hour))
+	assert.False(t, cert.IsExpired(now))
+	assert.True(t, cert.IsExpiringSoon(now, within))
+
+	cert.Spec.ExpiresAt = metav1.NewTime(now.Add(-time.Hour))
+	assert.True(t, cert.IsExpired(now))
+	assert.False(t, cert.IsExpiringSoon(now, within))
+}
+
+func TestCertificateConditions(t *testing.T) {
+	cert := CertificateRef{}
+	assert.False(t, cert.IsAtRisk())
+
+	assert.True(t, cert.Status.SetCondition(CertificateExpiringSoon, corev1.ConditionFalse, "Valid", ""))
+	assert.False(t, cert.IsAtRisk())
+	// unchanged status
+	assert.False(t, cert.Status.SetCondition(CertificateExpiringSoon, corev1.ConditionFalse, "Valid", ""))
+
+	assert.True(t, cert.Status.SetCondition(CertificateExpiringSoon, corev1.ConditionTrue, "ExpiringSoon", "expires in 5 days"))
+	assert.True(t, cert.IsAtRisk())
+	assert.Len(t, cert.Status.Conditions, 1)
+	cond := cert.Status.GetCondition(CertificateExpiringSoon)
+	assert.Equal(t, "expires in 5 days", cond.Message)
+	assert.False(t, cond.LastTransitionTime.IsZero())
+
+	assert.Nil(t, cert.Status.GetCondition(CertificateExpired))
+}
@@ -24,6 +24,7 @@ import (
 type IngressRequestSpec struct {
 	Hosts []string `json:"hosts"`
 
+	// path prefixes requested on each of the hosts
 	// +optional
 	Paths []string `json:"paths,omitempty"`
 
@@ -35,11 +36,50 @@ type IngressRequestSpec struct {
 
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +optional
+	Auth *IngressAuth `json:"auth,omitempty"`
+
+	// +optional
+	Visibility IngressVisibility `json:"visibility,omitempty"`
 }
 
 // IngressRequestStatus defines the observed state of IngressRequest
 type IngressRequestStatus struct {
 	Address string `json:"address"`
+
+	// paths that are already claimed by other requests, these are not routed
+	// +optional
+	Conflicts []IngressConflict `json:"conflicts,omitempty"`
+
+	// records for the hosts, when a DNS provider is configured for the cluster
+	// +optional
+	DNS *IngressDNSStatus `json:"dns,omitempty"`
+}
+
+type IngressConflict struct {
+	Host string `json:"host"`
+	Path string `json:"path"`
+	// name of the IngressRequest that the path is routed to
+	ClaimedBy string `json:"claimedBy"`
+}
+
+type IngressDNSStatus struct {
+	// records currently managed for the request
+	// +optional
+	Records []IngressDNSRecord `json:"records,omitempty"`
+
+	// true when records of all hosts point to the address
+	Synced bool `json:"synced"`
+
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type IngressDNSRecord struct {
+	Host string `json:"host"`
+	// address that the host resolves to
+	Target string `json:"target"`
 }
 
 // +kubebuilder:object:root=true
@@ -63,6 +103,23 @@ type IngressRequestList struct {
 	Items           []IngressRequest `json:"items"`
 }
 
+// returns requested paths, defaulting to all requests
+// internal requests are served by a private load balancer
+func (ir *IngressRequest) IsInternal() bool {
+	return ir.Spec.Visibility == IngressVisibilityInternal
+}
+
+func (ir *IngressRequest) GetPaths() []string {
+	if len(ir.Spec.Paths) == 0 {
+		return []string{"/"}
+	}
+	var paths []string
+	for _, path := range ir.Spec.Paths {
+		paths = append(paths, NormalizeIngressPath(path))
+	}
+	return paths
+}
+
 func init() {
 	SchemeBuilder.Register(&IngressRequest{}, &IngressRequestList{})
 }
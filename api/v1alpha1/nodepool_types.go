@@ -17,7 +17,19 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// keys of labels and taints that were applied to a node from its nodepool, so that ones removed from the
+	// nodepool are removed from the node
+	NodepoolLabelsAnnotation = "k11n.dev/nodepoolLabels"
+	NodepoolTaintsAnnotation = "k11n.dev/nodepoolTaints"
 )
 
 // NodepoolSpec defines the desired state of Nodepool
@@ -29,6 +41,19 @@ type NodepoolSpec struct {
 	DiskSizeGiB int              `json:"diskSizeGiB" desc:"Disk size (GiB)"`
 	RequiresGPU bool             `json:"requiresGPU" desc:"Needs GPU"`
 	AWS         *AWSNodepoolSpec `json:"aws,omitempty"`
+	// other machine types that nodes could use when the main type is out of capacity.
+	// they should have the same CPU and memory as the main type
+	// +kubebuilder:validation:Optional
+	// +optional
+	AdditionalMachineTypes []string `json:"additionalMachineTypes,omitempty" desc:"Additional machine types"`
+	// labels that are set on every node in the pool
+	// +kubebuilder:validation:Optional
+	// +optional
+	Labels map[string]string `json:"labels,omitempty" desc:"Node labels"`
+	// taints that are set on every node in the pool, only pods that tolerate them are scheduled on it
+	// +kubebuilder:validation:Optional
+	// +optional
+	Taints []corev1.Taint `json:"taints,omitempty" desc:"Node taints"`
 }
 
 // NodepoolStatus defines the observed state of Nodepool
@@ -38,14 +63,56 @@ type NodepoolStatus struct {
 	Nodes    []string           `json:"nodes"`
 	NumReady int                `json:"numReady"`
 	AWS      *AWSNodepoolStatus `json:"aws,omitempty"`
+	// spec that the nodes are currently running with, changes to spec are applied by the operator
+	// +kubebuilder:validation:Optional
+	AppliedSpec *NodepoolSpec `json:"appliedSpec,omitempty"`
+	// set while the nodepool is being replaced by a new one, when changes can't be made in place
+	// +kubebuilder:validation:Optional
+	Replacement *NodepoolReplacement `json:"replacement,omitempty"`
 }
 
+type NodepoolReplacement struct {
+	// name of the new nodepool
+	Name  string                   `json:"name"`
+	Phase NodepoolReplacementPhase `json:"phase"`
+	// when the current phase started
+	StartedAt metav1.Time `json:"startedAt"`
+	// spec of the new nodepool
+	Spec NodepoolSpec `json:"spec"`
+}
+
+// +kubebuilder:validation:Enum=creating;draining;deleting;failed
+type NodepoolReplacementPhase string
+
+const (
+	// new nodepool is being created
+	NodepoolReplacementCreating NodepoolReplacementPhase = "creating"
+	// pods are being evicted from existing nodes
+	NodepoolReplacementDraining NodepoolReplacementPhase = "draining"
+	// existing nodepool is being deleted
+	NodepoolReplacementDeleting NodepoolReplacementPhase = "deleting"
+	// new nodepool didn't become ready and was rolled back, it's retried after the spec changes again
+	NodepoolReplacementFailed NodepoolReplacementPhase = "failed"
+)
+
 type AWSNodepoolSpec struct {
 	AMIType             string `json:"amiType" desc:"AMI Type"`
 	SSHKeypair          string `json:"sshKeypair" desc:"SSH keypair"`
 	ConnectFromAnywhere bool   `json:"connectFromAnywhere" desc:"Allow connection from internet"`
+	// spot instances are discounted, but could be interrupted. defaults to on-demand
+	// +kubebuilder:validation:Optional
+	// +optional
+	CapacityType CapacityType `json:"capacityType,omitempty" desc:"Capacity type"`
 }
 
+// +kubebuilder:validation:Enum=on-demand;spot
+type CapacityType string
+
+const (
+	CapacityTypeOnDemand CapacityType = "on-demand"
+	CapacityTypeSpot     CapacityType = "spot"
+)
+
 type AWSNodepoolStatus struct {
 	// set only after nodepool is created
 	// +kubebuilder:validation:Optional
@@ -82,3 +149,123 @@ type NodepoolList struct {
 func init() {
 	SchemeBuilder.Register(&Nodepool{}, &NodepoolList{})
 }
+
+func (s *AWSNodepoolSpec) IsSpot() bool {
+	return s != nil && s.CapacityType == CapacityTypeSpot
+}
+
+// all of the machine types that nodes could use, main type first
+func (s *NodepoolSpec) GetMachineTypes() []string {
+	types := []string{s.MachineType}
+	for _, t := range s.AdditionalMachineTypes {
+		isDup := false
+		for _, existing := range types {
+			if existing == t {
+				isDup = true
+				break
+			}
+		}
+		if !isDup {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// returns true when changes from the existing spec can't be made to the nodepool in place.
+// sizes, labels and taints could be updated, anything else requires nodes to be replaced with a new nodepool
+func (s *NodepoolSpec) RequiresReplacement(existing *NodepoolSpec) bool {
+	if s.Autoscale != existing.Autoscale ||
+		s.MachineType != existing.MachineType ||
+		s.DiskSizeGiB != existing.DiskSizeGiB ||
+		s.RequiresGPU != existing.RequiresGPU {
+		return true
+	}
+	types, existingTypes := s.GetMachineTypes(), existing.GetMachineTypes()
+	if len(types) != len(existingTypes) {
+		return true
+	}
+	for i := range types {
+		if types[i] != existingTypes[i] {
+			return true
+		}
+	}
+
+	if (s.AWS == nil) != (existing.AWS == nil) {
+		return true
+	}
+	return s.AWS != nil && *s.AWS != *existing.AWS
+}
+
+func (s *NodepoolSpec) Validate() error {
+	if s.MachineType == "" {
+		return fmt.Errorf("machineType is required")
+	}
+	for key, val := range s.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return fmt.Errorf("invalid label %s: %s", key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(val); len(errs) != 0 {
+			return fmt.Errorf("invalid value for label %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, taint := range s.Taints {
+		if errs := validation.IsQualifiedName(taint.Key); len(errs) != 0 {
+			return fmt.Errorf("invalid taint %s: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		if errs := validation.IsValidLabelValue(taint.Value); len(errs) != 0 {
+			return fmt.Errorf("invalid value for taint %s: %s", taint.Key, strings.Join(errs, ", "))
+		}
+		switch taint.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return fmt.Errorf("invalid effect for taint %s: %s", taint.Key, taint.Effect)
+		}
+	}
+	return nil
+}
+
+// parses comma separated labels in the form of key=value
+func ParseNodeLabels(val string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, item := range splitList(val) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid label %s, expected key=value", item)
+		}
+		labels[parts[0]] = parts[1]
+	}
+	return labels, nil
+}
+
+// parses comma separated taints in the same form as kubectl taint, key[=value]:Effect
+func ParseNodeTaints(val string) ([]corev1.Taint, error) {
+	var taints []corev1.Taint
+	for _, item := range splitList(val) {
+		idx := strings.LastIndex(item, ":")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid taint %s, expected key=value:Effect", item)
+		}
+		taint := corev1.Taint{
+			Effect: corev1.TaintEffect(item[idx+1:]),
+		}
+		parts := strings.SplitN(item[:idx], "=", 2)
+		taint.Key = parts[0]
+		if len(parts) == 2 {
+			taint.Value = parts[1]
+		}
+		taints = append(taints, taint)
+	}
+	return taints, nil
+}
+
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,86 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNodepoolMachineTypes(t *testing.T) {
+	nps := NodepoolSpec{
+		MachineType:            "m5.large",
+		AdditionalMachineTypes: []string{"m5a.large", "m5.large", "m4.large"},
+	}
+	assert.Equal(t, []string{"m5.large", "m5a.large", "m4.large"}, nps.GetMachineTypes())
+
+	assert.False(t, nps.AWS.IsSpot())
+	nps.AWS = &AWSNodepoolSpec{}
+	assert.False(t, nps.AWS.IsSpot())
+	nps.AWS.CapacityType = CapacityTypeSpot
+	assert.True(t, nps.AWS.IsSpot())
+}
+
+func TestNodepoolLabelsAndTaints(t *testing.T) {
+	labels, err := ParseNodeLabels("workload=batch, k11n.dev/team=data")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"workload": "batch", "k11n.dev/team": "data"}, labels)
+
+	_, err = ParseNodeLabels("workload")
+	assert.Error(t, err)
+
+	taints, err := ParseNodeTaints("dedicated=batch:NoSchedule,noisy:PreferNoSchedule")
+	assert.NoError(t, err)
+	assert.Equal(t, []corev1.Taint{
+		{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "noisy", Effect: corev1.TaintEffectPreferNoSchedule},
+	}, taints)
+
+	_, err = ParseNodeTaints("dedicated=batch")
+	assert.Error(t, err)
+
+	nps := NodepoolSpec{
+		MachineType: "m5.large",
+		Labels:      labels,
+		Taints:      taints,
+	}
+	assert.NoError(t, nps.Validate())
+
+	nps.Taints = []corev1.Taint{{Key: "dedicated", Effect: "Sometimes"}}
+	assert.Error(t, nps.Validate())
+
+	nps.Taints = nil
+	nps.Labels = map[string]string{"bad key": "val"}
+	assert.Error(t, nps.Validate())
+}
+
+func TestNodepoolRequiresReplacement(t *testing.T) {
+	existing := NodepoolSpec{
+		MinSize:     1,
+		MaxSize:     3,
+		MachineType: "m5.large",
+		DiskSizeGiB: 20,
+		AWS:         &AWSNodepoolSpec{AMIType: "AL2_x86_64"},
+	}
+
+	nps := *existing.DeepCopy()
+	nps.MinSize = 2
+	nps.MaxSize = 10
+	nps.Labels = map[string]string{"workload": "batch"}
+	nps.Taints = []corev1.Taint{{Key: "dedicated", Effect: corev1.TaintEffectNoSchedule}}
+	assert.False(t, nps.RequiresReplacement(&existing))
+
+	nps.AdditionalMachineTypes = []string{"m5.large"}
+	assert.False(t, nps.RequiresReplacement(&existing))
+
+	nps.AdditionalMachineTypes = []string{"m5a.large"}
+	assert.True(t, nps.RequiresReplacement(&existing))
+
+	nps = *existing.DeepCopy()
+	nps.MachineType = "m5.xlarge"
+	assert.True(t, nps.RequiresReplacement(&existing))
+
+	nps = *existing.DeepCopy()
+	nps.AWS.CapacityType = CapacityTypeSpot
+	assert.True(t, nps.RequiresReplacement(&existing))
+}
@@ -23,9 +23,46 @@ package v1alpha1
 import (
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACMEChallenge) DeepCopyInto(out *ACMEChallenge) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACMEChallenge.
+func (in *ACMEChallenge) DeepCopy() *ACMEChallenge {
+	if in == nil {
+		return nil
+	}
+	out := new(ACMEChallenge)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ACMEOrder) DeepCopyInto(out *ACMEOrder) {
+	*out = *in
+	in.CreatedAt.DeepCopyInto(&out.CreatedAt)
+	if in.Challenges != nil {
+		in, out := &in.Challenges, &out.Challenges
+		*out = make([]ACMEChallenge, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ACMEOrder.
+func (in *ACMEOrder) DeepCopy() *ACMEOrder {
+	if in == nil {
+		return nil
+	}
+	out := new(ACMEOrder)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AWSClusterSpec) DeepCopyInto(out *AWSClusterSpec) {
 	*out = *in
@@ -202,6 +239,11 @@ func (in *AppCommonSpec) DeepCopyInto(out *AppCommonSpec) {
 	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	in.Probes.DeepCopyInto(&out.Probes)
+	if in.ConfigReloadHook != nil {
+		in, out := &in.ConfigReloadHook, &out.ConfigReloadHook
+		*out = new(ConfigReloadHook)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppCommonSpec.
@@ -224,6 +266,11 @@ func (in *AppConfig) DeepCopyInto(out *AppConfig) {
 		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
+	if in.Schema != nil {
+		in, out := &in.Schema, &out.Schema
+		*out = make([]byte, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppConfig.
@@ -407,6 +454,10 @@ func (in *AppReleaseStatus) DeepCopyInto(out *AppReleaseStatus) {
 		*out = make([]PodStatus, len(*in))
 		copy(*out, *in)
 	}
+	if in.LiveConfigUpdatedAt != nil {
+		in, out := &in.LiveConfigUpdatedAt, &out.LiveConfigUpdatedAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppReleaseStatus.
@@ -428,6 +479,16 @@ func (in *AppSpec) DeepCopyInto(out *AppSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConfigEnv != nil {
+		in, out := &in.ConfigEnv, &out.ConfigEnv
+		*out = new(ConfigEnvSpec)
+		**out = **in
+	}
+	if in.ConfigSchema != nil {
+		in, out := &in.ConfigSchema, &out.ConfigSchema
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Scale.DeepCopyInto(&out.Scale)
 	if in.Prometheus != nil {
 		in, out := &in.Prometheus, &out.Prometheus
@@ -541,6 +602,11 @@ func (in *AppTargetSpec) DeepCopyInto(out *AppTargetSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ConfigEnv != nil {
+		in, out := &in.ConfigEnv, &out.ConfigEnv
+		*out = new(ConfigEnvSpec)
+		**out = **in
+	}
 	in.Scale.DeepCopyInto(&out.Scale)
 	if in.Ingress != nil {
 		in, out := &in.Ingress, &out.Ingress
@@ -552,6 +618,11 @@ func (in *AppTargetSpec) DeepCopyInto(out *AppTargetSpec) {
 		*out = new(PrometheusSpec)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(ExposeConfig)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppTargetSpec.
@@ -572,6 +643,23 @@ func (in *AppTargetStatus) DeepCopyInto(out *AppTargetStatus) {
 		in, out := &in.LastScaledAt, &out.LastScaledAt
 		*out = (*in).DeepCopy()
 	}
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
+	if in.ActivatedAt != nil {
+		in, out := &in.ActivatedAt, &out.ActivatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.AvailableSince != nil {
+		in, out := &in.AvailableSince, &out.AvailableSince
+		*out = (*in).DeepCopy()
+	}
+	if in.Recommendation != nil {
+		in, out := &in.Recommendation, &out.Recommendation
+		*out = new(ResourceRecommendation)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppTargetStatus.
@@ -674,12 +762,64 @@ func (in *BuildStatus) DeepCopy() *BuildStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CORSPolicy) DeepCopyInto(out *CORSPolicy) {
+	*out = *in
+	if in.AllowOrigins != nil {
+		in, out := &in.AllowOrigins, &out.AllowOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowMethods != nil {
+		in, out := &in.AllowMethods, &out.AllowMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowHeaders != nil {
+		in, out := &in.AllowHeaders, &out.AllowHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposeHeaders != nil {
+		in, out := &in.ExposeHeaders, &out.ExposeHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CORSPolicy.
+func (in *CORSPolicy) DeepCopy() *CORSPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(CORSPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateCondition) DeepCopyInto(out *CertificateCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateCondition.
+func (in *CertificateCondition) DeepCopy() *CertificateCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CertificateRef) DeepCopyInto(out *CertificateRef) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateRef.
@@ -736,6 +876,24 @@ func (in *CertificateRefList) DeepCopyObject() runtime.Object {
 func (in *CertificateRefSpec) DeepCopyInto(out *CertificateRefSpec) {
 	*out = *in
 	in.ExpiresAt.DeepCopyInto(&out.ExpiresAt)
+	if in.ValidationRecords != nil {
+		in, out := &in.ValidationRecords, &out.ValidationRecords
+		*out = make([]CertificateValidationRecord, len(*in))
+		copy(*out, *in)
+	}
+	if in.RenewAt != nil {
+		in, out := &in.RenewAt, &out.RenewAt
+		*out = (*in).DeepCopy()
+	}
+	if in.RetryAt != nil {
+		in, out := &in.RetryAt, &out.RetryAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ACMEOrder != nil {
+		in, out := &in.ACMEOrder, &out.ACMEOrder
+		*out = new(ACMEOrder)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateRefSpec.
@@ -748,6 +906,43 @@ func (in *CertificateRefSpec) DeepCopy() *CertificateRefSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateRefStatus) DeepCopyInto(out *CertificateRefStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]CertificateCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateRefStatus.
+func (in *CertificateRefStatus) DeepCopy() *CertificateRefStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateRefStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CertificateValidationRecord) DeepCopyInto(out *CertificateValidationRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CertificateValidationRecord.
+func (in *CertificateValidationRecord) DeepCopy() *CertificateValidationRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(CertificateValidationRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterConfig) DeepCopyInto(out *ClusterConfig) {
 	*out = *in
@@ -910,6 +1105,63 @@ func (in *ComponentSpec) DeepCopy() *ComponentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigEnvSpec) DeepCopyInto(out *ConfigEnvSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigEnvSpec.
+func (in *ConfigEnvSpec) DeepCopy() *ConfigEnvSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigEnvSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigReloadHook) DeepCopyInto(out *ConfigReloadHook) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigReloadHook.
+func (in *ConfigReloadHook) DeepCopy() *ConfigReloadHook {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigReloadHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExposeConfig) DeepCopyInto(out *ExposeConfig) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExposeConfig.
+func (in *ExposeConfig) DeepCopy() *ExposeConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ExposeConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HTTPGetAction) DeepCopyInto(out *HTTPGetAction) {
 	*out = *in
@@ -955,6 +1207,31 @@ func (in *Handler) DeepCopy() *Handler {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressAuth) DeepCopyInto(out *IngressAuth) {
+	*out = *in
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(OIDCAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllowedCIDRs != nil {
+		in, out := &in.AllowedCIDRs, &out.AllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressAuth.
+func (in *IngressAuth) DeepCopy() *IngressAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressConfig) DeepCopyInto(out *IngressConfig) {
 	*out = *in
@@ -968,6 +1245,11 @@ func (in *IngressConfig) DeepCopyInto(out *IngressConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Routes != nil {
+		in, out := &in.Routes, &out.Routes
+		*out = make([]IngressRoute, len(*in))
+		copy(*out, *in)
+	}
 	if in.Annotations != nil {
 		in, out := &in.Annotations, &out.Annotations
 		*out = make(map[string]string, len(*in))
@@ -975,6 +1257,33 @@ func (in *IngressConfig) DeepCopyInto(out *IngressConfig) {
 			(*out)[key] = val
 		}
 	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(IngressAuth)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = new(CORSPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RateLimit != nil {
+		in, out := &in.RateLimit, &out.RateLimit
+		*out = new(RateLimitConfig)
+		**out = **in
+	}
+	if in.MaintenancePage != nil {
+		in, out := &in.MaintenancePage, &out.MaintenancePage
+		*out = new(MaintenancePage)
+		**out = **in
+	}
+	if in.ErrorPages != nil {
+		in, out := &in.ErrorPages, &out.ErrorPages
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressConfig.
@@ -987,13 +1296,63 @@ func (in *IngressConfig) DeepCopy() *IngressConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressConflict) DeepCopyInto(out *IngressConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressConflict.
+func (in *IngressConflict) DeepCopy() *IngressConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDNSRecord) DeepCopyInto(out *IngressDNSRecord) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDNSRecord.
+func (in *IngressDNSRecord) DeepCopy() *IngressDNSRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDNSRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressDNSStatus) DeepCopyInto(out *IngressDNSStatus) {
+	*out = *in
+	if in.Records != nil {
+		in, out := &in.Records, &out.Records
+		*out = make([]IngressDNSRecord, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressDNSStatus.
+func (in *IngressDNSStatus) DeepCopy() *IngressDNSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressDNSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressRequest) DeepCopyInto(out *IngressRequest) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressRequest.
@@ -1066,6 +1425,11 @@ func (in *IngressRequestSpec) DeepCopyInto(out *IngressRequestSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(IngressAuth)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressRequestSpec.
@@ -1081,6 +1445,16 @@ func (in *IngressRequestSpec) DeepCopy() *IngressRequestSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IngressRequestStatus) DeepCopyInto(out *IngressRequestStatus) {
 	*out = *in
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]IngressConflict, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(IngressDNSStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressRequestStatus.
@@ -1093,6 +1467,21 @@ func (in *IngressRequestStatus) DeepCopy() *IngressRequestStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressRoute) DeepCopyInto(out *IngressRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressRoute.
+func (in *IngressRoute) DeepCopy() *IngressRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LinkedServiceAccount) DeepCopyInto(out *LinkedServiceAccount) {
 	*out = *in
@@ -1217,6 +1606,21 @@ func (in *LinkedServiceAccountStatus) DeepCopy() *LinkedServiceAccountStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenancePage) DeepCopyInto(out *MaintenancePage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenancePage.
+func (in *MaintenancePage) DeepCopy() *MaintenancePage {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenancePage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Nodepool) DeepCopyInto(out *Nodepool) {
 	*out = *in
@@ -1276,6 +1680,23 @@ func (in *NodepoolList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodepoolReplacement) DeepCopyInto(out *NodepoolReplacement) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodepoolReplacement.
+func (in *NodepoolReplacement) DeepCopy() *NodepoolReplacement {
+	if in == nil {
+		return nil
+	}
+	out := new(NodepoolReplacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodepoolSpec) DeepCopyInto(out *NodepoolSpec) {
 	*out = *in
@@ -1284,6 +1705,25 @@ func (in *NodepoolSpec) DeepCopyInto(out *NodepoolSpec) {
 		*out = new(AWSNodepoolSpec)
 		**out = **in
 	}
+	if in.AdditionalMachineTypes != nil {
+		in, out := &in.AdditionalMachineTypes, &out.AdditionalMachineTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Taints != nil {
+		in, out := &in.Taints, &out.Taints
+		*out = make([]v1.Taint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodepoolSpec.
@@ -1309,6 +1749,16 @@ func (in *NodepoolStatus) DeepCopyInto(out *NodepoolStatus) {
 		*out = new(AWSNodepoolStatus)
 		**out = **in
 	}
+	if in.AppliedSpec != nil {
+		in, out := &in.AppliedSpec, &out.AppliedSpec
+		*out = new(NodepoolSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replacement != nil {
+		in, out := &in.Replacement, &out.Replacement
+		*out = new(NodepoolReplacement)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodepoolStatus.
@@ -1321,6 +1771,26 @@ func (in *NodepoolStatus) DeepCopy() *NodepoolStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCAuth) DeepCopyInto(out *OIDCAuth) {
+	*out = *in
+	if in.Scopes != nil {
+		in, out := &in.Scopes, &out.Scopes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCAuth.
+func (in *OIDCAuth) DeepCopy() *OIDCAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodStatus) DeepCopyInto(out *PodStatus) {
 	*out = *in
@@ -1426,9 +1896,47 @@ func (in *PrometheusSpec) DeepCopy() *PrometheusSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RateLimitConfig) DeepCopyInto(out *RateLimitConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RateLimitConfig.
+func (in *RateLimitConfig) DeepCopy() *RateLimitConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(RateLimitConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceRecommendation) DeepCopyInto(out *ResourceRecommendation) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	out.Window = in.Window
+	in.UpdatedAt.DeepCopyInto(&out.UpdatedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceRecommendation.
+func (in *ResourceRecommendation) DeepCopy() *ResourceRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleBehavior) DeepCopyInto(out *ScaleBehavior) {
 	*out = *in
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleBehavior.
@@ -1441,18 +1949,74 @@ func (in *ScaleBehavior) DeepCopy() *ScaleBehavior {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleMetric) DeepCopyInto(out *ScaleMetric) {
+	*out = *in
+	out.Target = in.Target.DeepCopy()
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleMetric.
+func (in *ScaleMetric) DeepCopy() *ScaleMetric {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleMetric)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScaleSchedule) DeepCopyInto(out *ScaleSchedule) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScaleSchedule.
+func (in *ScaleSchedule) DeepCopy() *ScaleSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ScaleSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ScaleSpec) DeepCopyInto(out *ScaleSpec) {
 	*out = *in
+	if in.IdleTimeout != nil {
+		in, out := &in.IdleTimeout, &out.IdleTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Metrics != nil {
+		in, out := &in.Metrics, &out.Metrics
+		*out = make([]ScaleMetric, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]ScaleSchedule, len(*in))
+		copy(*out, *in)
+	}
 	if in.ScaleUp != nil {
 		in, out := &in.ScaleUp, &out.ScaleUp
 		*out = new(ScaleBehavior)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.ScaleDown != nil {
 		in, out := &in.ScaleDown, &out.ScaleDown
 		*out = new(ScaleBehavior)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 }
 
@@ -1466,6 +2030,21 @@ func (in *ScaleSpec) DeepCopy() *ScaleSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkippedEnvKey) DeepCopyInto(out *SkippedEnvKey) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkippedEnvKey.
+func (in *SkippedEnvKey) DeepCopy() *SkippedEnvKey {
+	if in == nil {
+		return nil
+	}
+	out := new(SkippedEnvKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetConfig) DeepCopyInto(out *TargetConfig) {
 	*out = *in
@@ -1474,6 +2053,11 @@ func (in *TargetConfig) DeepCopyInto(out *TargetConfig) {
 		*out = new(IngressConfig)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Expose != nil {
+		in, out := &in.Expose, &out.Expose
+		*out = new(ExposeConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
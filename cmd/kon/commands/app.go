@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -22,14 +23,17 @@ import (
 	"github.com/spf13/cast"
 	"github.com/thoas/go-funk"
 	"github.com/urfave/cli/v2"
+	autoscale "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
 	"github.com/k11n/konstellation/cmd/kon/kube"
 	"github.com/k11n/konstellation/cmd/kon/utils"
+	"github.com/k11n/konstellation/pkg/components/prometheus"
 	"github.com/k11n/konstellation/pkg/resources"
 	utilscli "github.com/k11n/konstellation/pkg/utils/cli"
 )
@@ -187,6 +191,24 @@ var AppCommands = []*cli.Command{
 					releaseFlag,
 				},
 			},
+			{
+				Name:      "recommend",
+				Usage:     "Recommend resource requests and limits from the app's usage",
+				ArgsUsage: "<app>",
+				Action:    appRecommend,
+				Flags: []cli.Flag{
+					targetFlag,
+					&cli.DurationFlag{
+						Name:  "window",
+						Usage: "period of usage to base recommendations on",
+						Value: prometheus.DefaultRecommendationWindow,
+					},
+					&cli.BoolFlag{
+						Name:  "patch",
+						Usage: "print a JSON patch for the app, to apply with kubectl patch app <app> --type json",
+					},
+				},
+			},
 			{
 				Name:      "restart",
 				Usage:     "Restart the current app",
@@ -280,11 +302,12 @@ func appList(c *cli.Context) error {
 					hosts = fmt.Sprintf("%d hosts", numHosts)
 				}
 			}
+			_, maxInstances := at.ScaleBounds()
 			table.Append([]string{
 				at.Spec.App,
 				build.ShortName(),
 				at.Status.DeployUpdatedAt.Format(cliDateFormat),
-				fmt.Sprintf("%d (max %d)", at.Status.NumAvailable, at.Spec.Scale.Max),
+				fmt.Sprintf("%d (max %d)", at.Status.NumAvailable, maxInstances),
 				string(at.Status.Phase),
 				hosts,
 			})
@@ -356,23 +379,104 @@ func appStatus(c *cli.Context) error {
 			portsStr = append(portsStr, fmt.Sprintf("%s-%d", port.Name, port.Port))
 		}
 		atTable.Append([]string{"Ports:", strings.Join(portsStr, ", ")})
-		atTable.Append([]string{"Scale:", fmt.Sprintf("%d min, %d max", at.Spec.Scale.Min, at.Spec.Scale.Max)})
+		minInstances, maxInstances, schedule := at.Spec.Scale.BoundsAt(time.Now())
+		atTable.Append([]string{"Scale:", fmt.Sprintf("%d min, %d max", minInstances, maxInstances)})
+		if len(at.Spec.Scale.Schedules) != 0 {
+			profile := "default"
+			if schedule != nil {
+				profile = schedule.Name
+			}
+			atTable.Append([]string{"Schedule:", profile})
+		}
+		if at.IsIdle() {
+			atTable.Append([]string{"Idle:", fmt.Sprintf("since %s, scaled to zero until the next request",
+				at.Status.IdleSince.Format(cliDateFormat))})
+		}
+		if at.NeedsAutoscaler() {
+			// the autoscaler has the behavior that's in effect, it could be ahead of the target during deploys
+			behavior := at.Spec.Scale.ToHPABehavior()
+			scalers := autoscale.HorizontalPodAutoscalerList{}
+			err = kclient.List(context.TODO(), &scalers, client.InNamespace(at.TargetNamespace()), client.MatchingLabels{
+				resources.AppLabel:    at.Spec.App,
+				resources.TargetLabel: at.Spec.Target,
+			})
+			if err != nil {
+				return err
+			}
+			if len(scalers.Items) != 0 {
+				behavior = scalers.Items[0].Spec.Behavior
+			}
+			var up, down *autoscale.HPAScalingRules
+			if behavior != nil {
+				up, down = behavior.ScaleUp, behavior.ScaleDown
+			}
+			atTable.Append([]string{"Scale up:", describeScalingRules(up, defaultScaleUpRules)})
+			atTable.Append([]string{"Scale down:", describeScalingRules(down, defaultScaleDownRules)})
+		}
+		if rec := at.Status.Recommendation; rec != nil {
+			reqs := rec.Resources
+			atTable.Append([]string{"Recommended:", fmt.Sprintf("cpu %s (limit %s), memory %s (limit %s)",
+				quantityString(reqs.Requests, corev1.ResourceCPU), quantityString(reqs.Limits, corev1.ResourceCPU),
+				quantityString(reqs.Requests, corev1.ResourceMemory), quantityString(reqs.Limits, corev1.ResourceMemory))})
+		}
 
 		if at.Spec.Ingress != nil {
 			atTable.Append([]string{"Hosts:", strings.Join(at.Spec.Ingress.Hosts, ", ")})
-			atTable.Append([]string{"Load balancer:", at.Status.Hostname})
+			atTable.Append([]string{"Paths:", strings.Join(at.Spec.Ingress.GetPaths(), ", ")})
+			loadBalancer := at.Status.Hostname
+			if at.Spec.Ingress.Visibility == v1alpha1.IngressVisibilityInternal {
+				loadBalancer += " (internal)"
+			}
+			atTable.Append([]string{"Load balancer:", loadBalancer})
+
+			// paths claimed by other apps aren't routed here
+			ir := &v1alpha1.IngressRequest{}
+			err = kclient.Get(context.TODO(), client.ObjectKey{Namespace: at.TargetNamespace(), Name: at.ScopedName()}, ir)
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+			var conflicts []string
+			for _, conflict := range ir.Status.Conflicts {
+				conflicts = append(conflicts, fmt.Sprintf("%s%s (claimed by %s)", conflict.Host, conflict.Path, conflict.ClaimedBy))
+			}
+			if len(conflicts) != 0 {
+				atTable.Append([]string{"Conflicts:", strings.Join(conflicts, ", ")})
+			}
+		}
+		if at.NeedsExpose() {
+			var exposed []string
+			for _, port := range at.Spec.Expose.GetPorts(at.Spec.Ports) {
+				exposed = append(exposed, fmt.Sprintf("%s-%d/%s", port.Name, port.Port, port.GetProtocol()))
+			}
+			atTable.Append([]string{"Exposed:", strings.Join(exposed, ", ")})
+			exposedAddress := at.Status.ExposedAddress
+			if exposedAddress == "" {
+				exposedAddress = "pending"
+			}
+			atTable.Append([]string{"Exposed address:", exposedAddress})
 		}
 
 		if at.Spec.DeployMode == v1alpha1.DeployHalt {
 			atTable.Append([]string{"Deploy mode:", string(at.Spec.DeployMode)})
 		}
+		if at.InMaintenance() && at.NeedsIngress() {
+			atTable.Append([]string{"Maintenance:", "serving maintenance page"})
+		}
+		liveReload := at.Spec.ConfigReload == v1alpha1.ConfigReloadLive
+		if liveReload {
+			atTable.Append([]string{"Config reload:", string(at.Spec.ConfigReload)})
+		}
 		atTable.Render()
 		fmt.Println()
 
 		table := tablewriter.NewWriter(os.Stdout)
-		table.SetHeader([]string{
+		header := []string{
 			"Release", "Build", "Date", "Pods", "Status", "Traffic",
-		})
+		}
+		if liveReload {
+			header = append(header, "Live Config")
+		}
+		table.SetHeader(header)
 
 		// find all releases of this app
 		releases, err := resources.GetAppReleases(kclient, app.Name, target.Name)
@@ -396,6 +500,13 @@ func appStatus(c *cli.Context) error {
 				release.Status.State.String(),
 				fmt.Sprintf("%d%%", release.Spec.TrafficPercentage),
 			}
+			if liveReload {
+				liveConfig := release.CurrentConfig()
+				if release.Status.LiveConfig != liveConfig {
+					liveConfig += " (reloading)"
+				}
+				vals = append(vals, liveConfig)
+			}
 
 			if release.Status.State == v1alpha1.ReleaseStateReleasing && release.Status.NumAvailable == 0 &&
 				release.Spec.NumDesired > 0 {
@@ -632,6 +743,15 @@ func appLoad(c *cli.Context) error {
 		return errorshelper.Wrap(err, "could not load app")
 	}
 	app := obj.(*v1alpha1.App)
+	if err = app.ValidateIngress(); err != nil {
+		return err
+	}
+	if err = app.ValidateExpose(); err != nil {
+		return err
+	}
+	if err = app.ValidateScale(); err != nil {
+		return err
+	}
 
 	kclient := ac.kubernetesClient()
 	if _, err := resources.UpdateResource(kclient, app, nil, nil); err != nil {
@@ -804,7 +924,10 @@ func appLocal(c *cli.Context) error {
 	// find the config map
 	var cm *corev1.ConfigMap
 	if appConfig != nil || len(sharedConfigs) > 0 {
-		cm = resources.CreateConfigMap(app.Name, appConfig, sharedConfigs)
+		cm, err = resources.CreateConfigMap(app.Name, appConfig, sharedConfigs, app.Spec.ConfigEnv)
+		if err != nil {
+			return err
+		}
 	}
 
 	// find dependencies
@@ -1010,6 +1133,129 @@ func appPods(c *cli.Context) error {
 	return nil
 }
 
+func appRecommend(c *cli.Context) error {
+	appName, err := getAppArg(c)
+	if err != nil {
+		return err
+	}
+
+	ac, err := getActiveCluster()
+	if err != nil {
+		return err
+	}
+	kclient := ac.kubernetesClient()
+
+	app, err := resources.GetAppByName(kclient, appName)
+	if err != nil {
+		return err
+	}
+
+	// query usage through Prometheus in the cluster
+	proxy, err := utilscli.NewKubeProxyForService(kclient, resources.KonSystemNamespace, "prometheus-k8s", 9090)
+	if err != nil {
+		return err
+	}
+	if err = proxy.Start(); err != nil {
+		return err
+	}
+	defer proxy.Stop()
+	// give it a second for port-forward to start
+	time.Sleep(1 * time.Second)
+
+	qapi, err := prometheus.NewQueryAPI(proxy.URL())
+	if err != nil {
+		return err
+	}
+
+	requiredTarget := c.String("target")
+	window := c.Duration("window")
+	printPatch := c.Bool("patch")
+	recommended := make(map[string]corev1.ResourceRequirements)
+	for _, target := range app.Spec.Targets {
+		if requiredTarget != "" && target.Name != requiredTarget {
+			continue
+		}
+
+		reqs, err := prometheus.RecommendResources(context.Background(), qapi, target.Name, app.Name, window, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not recommend resources for target %s: %v\n", target.Name, err)
+			continue
+		}
+		recommended[target.Name] = *reqs
+		if printPatch {
+			continue
+		}
+
+		var current corev1.ResourceRequirements
+		at, err := resources.GetAppTargetWithLabels(kclient, app.Name, target.Name)
+		if err == nil {
+			current = at.Spec.Resources
+		} else if err != resources.ErrNotFound {
+			return err
+		}
+
+		fmt.Println("Target: ", target.Name)
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Resource", "Request", "Recommended", "Limit", "Recommended"})
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			table.Append([]string{
+				string(name),
+				quantityString(current.Requests, name),
+				quantityString(reqs.Requests, name),
+				quantityString(current.Limits, name),
+				quantityString(reqs.Limits, name),
+			})
+		}
+		utils.FormatStandardTable(table)
+		table.Render()
+		fmt.Println()
+	}
+
+	if len(recommended) == 0 {
+		return fmt.Errorf("no recommendations for %s, it needs to have been running for a while", app.Name)
+	}
+	if printPatch {
+		patch, err := resourcesPatch(app, recommended)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(patch))
+	} else {
+		fmt.Printf("Recommendations are based on usage over %s. Use --patch to update the app with them\n",
+			durafmt.Parse(window).String())
+	}
+	return nil
+}
+
+// JSON patch that sets resources on each of the app's targets
+func resourcesPatch(app *v1alpha1.App, recommended map[string]corev1.ResourceRequirements) ([]byte, error) {
+	type operation struct {
+		Op    string                      `json:"op"`
+		Path  string                      `json:"path"`
+		Value corev1.ResourceRequirements `json:"value"`
+	}
+	ops := make([]operation, 0, len(recommended))
+	for i, target := range app.Spec.Targets {
+		reqs, ok := recommended[target.Name]
+		if !ok {
+			continue
+		}
+		ops = append(ops, operation{
+			Op:    "add",
+			Path:  fmt.Sprintf("/spec/targets/%d/resources", i),
+			Value: reqs,
+		})
+	}
+	return json.Marshal(ops)
+}
+
+func quantityString(list corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := list[name]; ok {
+		return q.String()
+	}
+	return "-"
+}
+
 func appRestart(c *cli.Context) error {
 	app, err := getAppArg(c)
 	if err != nil {
@@ -1345,3 +1591,55 @@ func parseImageInfo(val string, ai *appInfo) {
 		ai.DockerImage = ai.DockerImage[slashIdx+1:]
 	}
 }
+
+var (
+	// Kubernetes defaults, used when the app doesn't define the behavior
+	defaultScaleUpRules = &autoscale.HPAScalingRules{
+		StabilizationWindowSeconds: pointer.Int32Ptr(0),
+		Policies: []autoscale.HPAScalingPolicy{
+			{Type: autoscale.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+			{Type: autoscale.PodsScalingPolicy, Value: 4, PeriodSeconds: 15},
+		},
+	}
+	defaultScaleDownRules = &autoscale.HPAScalingRules{
+		StabilizationWindowSeconds: pointer.Int32Ptr(300),
+		Policies: []autoscale.HPAScalingPolicy{
+			{Type: autoscale.PercentScalingPolicy, Value: 100, PeriodSeconds: 15},
+		},
+	}
+)
+
+// describes the policies that are in effect, rules that aren't set are defaulted by Kubernetes
+func describeScalingRules(rules, defaults *autoscale.HPAScalingRules) string {
+	window := *defaults.StabilizationWindowSeconds
+	policies := defaults.Policies
+	selectPolicy := autoscale.MaxPolicySelect
+	if rules != nil {
+		if rules.StabilizationWindowSeconds != nil {
+			window = *rules.StabilizationWindowSeconds
+		}
+		if len(rules.Policies) != 0 {
+			policies = rules.Policies
+		}
+		if rules.SelectPolicy != nil {
+			selectPolicy = *rules.SelectPolicy
+		}
+	}
+	if selectPolicy == autoscale.DisabledPolicySelect {
+		return "disabled"
+	}
+
+	var limits []string
+	for _, policy := range policies {
+		if policy.Type == autoscale.PercentScalingPolicy {
+			limits = append(limits, fmt.Sprintf("%d%% per %ds", policy.Value, policy.PeriodSeconds))
+		} else {
+			limits = append(limits, fmt.Sprintf("%d instances per %ds", policy.Value, policy.PeriodSeconds))
+		}
+	}
+	description := strings.Join(limits, " or ")
+	if len(limits) > 1 {
+		description += fmt.Sprintf(" (%s)", strings.ToLower(string(selectPolicy)))
+	}
+	return fmt.Sprintf("%s, after %ds", description, window)
+}
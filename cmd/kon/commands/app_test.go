@@ -4,6 +4,12 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	autoscale "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/utils/pointer"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
 )
 
 func TestParseImageInfo(t *testing.T) {
@@ -27,3 +33,39 @@ func TestParseImageInfo(t *testing.T) {
 	assert.Equal(t, "1", ai.DockerTag)
 	assert.Equal(t, "ecr.registry.com", ai.Registry)
 }
+
+func TestDescribeScalingRules(t *testing.T) {
+	assert.Equal(t, "100% per 15s or 4 instances per 15s (max), after 0s",
+		describeScalingRules(nil, defaultScaleUpRules))
+	assert.Equal(t, "100% per 15s, after 300s",
+		describeScalingRules(nil, defaultScaleDownRules))
+
+	scale := v1alpha1.ScaleSpec{
+		ScaleDown: &v1alpha1.ScaleBehavior{Step: 1, Delay: pointer.Int32Ptr(600)},
+	}
+	behavior := scale.ToHPABehavior()
+	assert.Equal(t, "1 instances per 60s, after 600s",
+		describeScalingRules(behavior.ScaleDown, defaultScaleDownRules))
+
+	disabled := autoscale.DisabledPolicySelect
+	assert.Equal(t, "disabled",
+		describeScalingRules(&autoscale.HPAScalingRules{SelectPolicy: &disabled}, defaultScaleDownRules))
+}
+
+func TestResourcesPatch(t *testing.T) {
+	app := &v1alpha1.App{}
+	app.Spec.Targets = []v1alpha1.TargetConfig{
+		{Name: "staging"},
+		{Name: "production"},
+	}
+	recommended := map[string]corev1.ResourceRequirements{
+		"production": {
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("250m"),
+			},
+		},
+	}
+	patch, err := resourcesPatch(app, recommended)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"op":"add","path":"/spec/targets/1/resources","value":{"requests":{"cpu":"250m"}}}]`, string(patch))
+}
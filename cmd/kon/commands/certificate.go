@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/pkg/errors"
@@ -42,6 +43,18 @@ var CertificateCommands = []*cli.Command{
 				Usage:  "Sync certificates from cloud provider",
 				Action: certSync,
 			},
+			{
+				Name:   "request",
+				Usage:  "Requests a new certificate from provider's certificate management",
+				Action: certRequest,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "domain",
+						Usage:    "the domain to request a certificate for, i.e. *.mydomain.com",
+						Required: true,
+					},
+				},
+			},
 			{
 				Name:   "import",
 				Usage:  "Imports an existing certificate to provider's certificate management",
@@ -86,18 +99,41 @@ func certList(c *cli.Context) error {
 
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{
-		"ID", "Domain", "Issuer", "Expiration", "Status",
+		"ID", "Domain", "Source", "Issuer", "Expiration", "Status",
 	})
 
 	for _, c := range certs {
 		cs := &c.Spec
-		table.Append([]string{
-			cs.ProviderID,
+		id := cs.ProviderID
+		if id == "" {
+			id = c.Name
+		}
+		expiration := ""
+		if !cs.ExpiresAt.IsZero() {
+			expiration = cs.ExpiresAt.Format("2006-01-02")
+		}
+		row := []string{
+			id,
 			cs.Domain,
+			string(c.GetSource()),
 			cs.Issuer,
-			cs.ExpiresAt.Format("2006-01-02"),
+			expiration,
 			cs.Status,
-		})
+		}
+		// highlight certificates that need attention
+		if c.IsExpired(time.Now()) {
+			row[len(row)-1] = "EXPIRED"
+		} else if c.IsAtRisk() {
+			row[len(row)-1] = "EXPIRING_SOON"
+		} else {
+			table.Append(row)
+			continue
+		}
+		colors := make([]tablewriter.Colors, len(row))
+		for i := range colors {
+			colors[i] = tablewriter.Colors{tablewriter.FgRedColor}
+		}
+		table.Rich(row, colors)
 	}
 	utils.FormatStandardTable(table)
 	table.Render()
@@ -151,6 +187,10 @@ func certSync(c *cli.Context) error {
 		if seenCerts[existingCert.Name] {
 			continue
 		}
+		// certificates issued in cluster are not tracked by the provider
+		if existingCert.GetSource() != v1alpha1.CertificateSourceProvider {
+			continue
+		}
 		if err := kclient.Delete(context.TODO(), &existingCert); err != nil {
 			return err
 		}
@@ -215,6 +255,42 @@ func certImport(c *cli.Context) error {
 	return err
 }
 
+func certRequest(c *cli.Context) error {
+	ac, err := getActiveCluster()
+	if err != nil {
+		return err
+	}
+	domain := c.String("domain")
+	kclient := ac.kubernetesClient()
+
+	certificate, err := ac.Manager.CertificateProvider().RequestCertificate(context.TODO(), domain)
+	if err != nil {
+		return err
+	}
+
+	if _, err = syncCertificate(kclient, certificate); err != nil {
+		return errors.Wrap(err, "Could not sync requested cert, please sync again later")
+	}
+
+	fmt.Printf("Requested certificate for %s\n", certificate.Domain)
+	if len(certificate.ValidationRecords) == 0 {
+		fmt.Println("Validation records are not available yet, run `kon certificate sync` to retrieve them")
+		return nil
+	}
+
+	fmt.Println("To validate ownership of the domain, create the following DNS records:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Name", "Type", "Value"})
+	for _, record := range certificate.ValidationRecords {
+		table.Append([]string{record.Name, record.Type, record.Value})
+	}
+	utils.FormatStandardTable(table)
+	table.Render()
+	fmt.Println("Once validated, the certificate will become READY after `kon certificate sync`, and will be renewed automatically by the provider")
+
+	return nil
+}
+
 func syncCertificate(kclient client.Client, cert *types.Certificate) (updated bool, err error) {
 	certRef := &v1alpha1.CertificateRef{
 		ObjectMeta: metav1.ObjectMeta{
@@ -230,8 +306,16 @@ func syncCertificate(kclient client.Client, cert *types.Certificate) (updated bo
 			Status:             cert.Status.String(),
 			KeyAlgorithm:       cert.KeyAlgorithm,
 			SignatureAlgorithm: cert.SignatureAlgorithm,
+			Source:             v1alpha1.CertificateSourceProvider,
 		},
 	}
+	for _, record := range cert.ValidationRecords {
+		certRef.Spec.ValidationRecords = append(certRef.Spec.ValidationRecords, v1alpha1.CertificateValidationRecord{
+			Name:  record.Name,
+			Type:  record.Type,
+			Value: record.Value,
+		})
+	}
 	if cert.ExpiresAt != nil {
 		certRef.Spec.ExpiresAt = metav1.NewTime(*cert.ExpiresAt)
 	}
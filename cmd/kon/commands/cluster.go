@@ -731,7 +731,7 @@ func clusterStatus(c *cli.Context) error {
 	for _, np := range nodepools {
 		npTable.Append([]string{
 			np.Name,
-			np.Spec.MachineType,
+			strings.Join(np.Spec.GetMachineTypes(), ", "),
 			fmt.Sprintf("%d of %d", np.Status.NumReady, np.Spec.MaxSize),
 		})
 	}
@@ -13,6 +13,7 @@ import (
 	"github.com/k11n/konstellation/cmd/kon/kube"
 	"github.com/k11n/konstellation/cmd/kon/providers"
 	"github.com/k11n/konstellation/cmd/kon/utils"
+	"github.com/k11n/konstellation/pkg/components/ingress"
 	"github.com/k11n/konstellation/pkg/resources"
 	utilscli "github.com/k11n/konstellation/pkg/utils/cli"
 )
@@ -115,6 +116,9 @@ func (c *activeCluster) configureNodepool() error {
 	if _, err = resources.UpdateResource(kclient, np, nil, nil); err != nil {
 		return err
 	}
+	if err = saveNodepoolStatus(kclient, np); err != nil {
+		return err
+	}
 
 	fmt.Printf("Successfully created nodepool %s\n", np.GetObjectMeta().GetName())
 	return nil
@@ -134,8 +138,17 @@ func (c *activeCluster) installComponents(force bool) error {
 		installed[comp.Name] = comp.Version
 	}
 
+	// use the ingress controller that's selected for the cluster
+	ingressComp, err := ingress.NewIngressForCluster(cc)
+	if err != nil {
+		return err
+	}
+
 	provider := GetCloud(c.Manager.Cloud())
 	for _, compInstaller := range provider.GetComponents() {
+		if _, ok := compInstaller.(ingress.IngressComponent); ok {
+			compInstaller = ingressComp
+		}
 		if !force && installed[compInstaller.Name()] != "" {
 			continue
 		}
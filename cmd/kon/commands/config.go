@@ -16,6 +16,7 @@ import (
 	"github.com/k11n/konstellation/cmd/kon/utils"
 	"github.com/k11n/konstellation/pkg/resources"
 	utilscli "github.com/k11n/konstellation/pkg/utils/cli"
+	"github.com/k11n/konstellation/pkg/utils/files"
 )
 
 // app configs
@@ -69,6 +70,22 @@ var ConfigCommands = []*cli.Command{
 						Name:  "target",
 						Usage: "edit config only for a specific target (target values will override the base config)",
 					},
+					&cli.StringFlag{
+						Name:  "list-merge",
+						Usage: "how lists in a target config are merged with the base config: replace or append",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "format to edit the config in and pass it to the app as: yaml, json, toml, or env",
+					},
+					&cli.BoolFlag{
+						Name:  "templated",
+						Usage: "render templates in config values, i.e. {{ .Target }}. pass --templated=false to turn off",
+					},
+					&cli.StringFlag{
+						Name:  "schema",
+						Usage: "JSON schema file to validate a shared config against (app config schemas are set on the App)",
+					},
 				},
 			},
 			{
@@ -84,6 +101,12 @@ var ConfigCommands = []*cli.Command{
 				Usage:     "Show config for a release of the app",
 				Action:    configShow,
 				ArgsUsage: "<release>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "env",
+						Usage: "show only env vars generated from the app config, along with keys that were skipped",
+					},
+				},
 			},
 		},
 	},
@@ -158,15 +181,30 @@ func configShow(c *cli.Context) error {
 		return err
 	}
 
-	if ar.Spec.Config == "" {
+	if ar.CurrentConfig() == "" {
 		return fmt.Errorf("release %s does not have a config", release)
 	}
 
-	cm, err := resources.GetConfigMap(kclient, ar.Spec.Target, ar.Spec.Config)
+	// show config that's live when it's been reloaded in place
+	cm, err := resources.GetConfigMap(kclient, ar.Spec.Target, ar.CurrentConfig())
 	if err != nil {
 		return err
 	}
 
+	envOnly := c.Bool("env")
+	excluded := make(map[string]bool)
+	if envOnly {
+		// leave out full configs, which are files rather than env vars
+		excluded[v1alpha1.ConfigEnvVar] = true
+		at, err := resources.GetAppTargetWithLabels(kclient, ar.Spec.App, ar.Spec.Target)
+		if err != nil {
+			return err
+		}
+		for _, name := range at.Spec.Configs {
+			excluded[resources.SharedConfigEnvName(name)] = true
+		}
+	}
+
 	keys := funk.Keys(cm.Data).([]string)
 	sort.Strings(keys)
 
@@ -175,6 +213,9 @@ func configShow(c *cli.Context) error {
 	table.SetRowLine(true)
 	table.SetAlignment(tablewriter.ALIGN_LEFT)
 	for _, key := range keys {
+		if excluded[key] {
+			continue
+		}
 		table.Append([]string{
 			key,
 			cm.Data[key],
@@ -182,6 +223,32 @@ func configShow(c *cli.Context) error {
 	}
 	table.Render()
 
+	if !envOnly {
+		return nil
+	}
+
+	skipped, err := resources.GetSkippedEnvKeys(cm)
+	if err != nil {
+		return err
+	}
+	if len(skipped) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println("Config keys not available as env vars")
+	table = tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Key", "Reason"})
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, item := range skipped {
+		table.Append([]string{
+			item.Key,
+			item.Reason,
+		})
+	}
+	utils.FormatStandardTable(table)
+	table.Render()
+
 	return nil
 }
 
@@ -210,8 +277,47 @@ func configEdit(c *cli.Context) error {
 		return err
 	}
 
+	switch listMerge := v1alpha1.ListMergeStrategy(c.String("list-merge")); listMerge {
+	case "":
+	case v1alpha1.ListMergeReplace, v1alpha1.ListMergeAppend:
+		appConfig.ListMerge = listMerge
+	default:
+		return fmt.Errorf("invalid list-merge strategy: %s", listMerge)
+	}
+
+	switch format := v1alpha1.ConfigFormat(c.String("format")); format {
+	case "":
+	case v1alpha1.ConfigFormatYAML, v1alpha1.ConfigFormatJSON, v1alpha1.ConfigFormatTOML, v1alpha1.ConfigFormatEnv:
+		appConfig.Format = format
+	default:
+		return fmt.Errorf("invalid config format: %s", format)
+	}
+
+	if c.IsSet("templated") {
+		appConfig.Templated = c.Bool("templated")
+	}
+
+	if schemaFile := c.String("schema"); schemaFile != "" {
+		if confType != v1alpha1.ConfigTypeShared || target != "" {
+			return fmt.Errorf("--schema can only be set on the base config of a shared config")
+		}
+		schema, err := files.ReadFile(schemaFile)
+		if err != nil {
+			return err
+		}
+		appConfig.Schema = schema
+	}
+
+	if err = appConfig.CheckEditFormat(appConfig.GetFormat()); err != nil {
+		return err
+	}
+
 	// launch editor
-	data, err := utilscli.ExecuteUserEditor(appConfig.ConfigYaml, fmt.Sprintf("%s.yaml", appConfig.Name))
+	content, err := appConfig.GetConfigContent()
+	if err != nil {
+		return errors.Wrapf(err, "could not convert config to %s", appConfig.GetFormat())
+	}
+	data, err := utilscli.ExecuteUserEditor(content, appConfig.Name+appConfig.GetFormat().Extension())
 	if err != nil {
 		return err
 	}
@@ -221,9 +327,16 @@ func configEdit(c *cli.Context) error {
 	}
 
 	// persist
-	if err = appConfig.SetConfigYAML(data); err != nil {
+	if err = appConfig.SetConfigContent(data, appConfig.GetFormat()); err != nil {
 		return errors.Wrap(err, "could not update config")
 	}
+	schema, err := resources.GetConfigSchema(kclient, appConfig)
+	if err != nil {
+		return err
+	}
+	if err = resources.ValidateAppConfig(kclient, appConfig, schema); err != nil {
+		return errors.Wrap(err, "config not saved")
+	}
 	err = resources.SaveAppConfig(kclient, appConfig)
 	if err != nil {
 		return err
@@ -1,18 +1,24 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cast"
 	"github.com/urfave/cli/v2"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/cmd/kon/kube"
 	"github.com/k11n/konstellation/cmd/kon/utils"
 	"github.com/k11n/konstellation/pkg/resources"
+	utilscli "github.com/k11n/konstellation/pkg/utils/cli"
 )
 
 var NodepoolCommands = []*cli.Command{
@@ -34,6 +40,18 @@ var NodepoolCommands = []*cli.Command{
 				Usage:  "create a new Nodepool",
 				Action: nodepoolCreate,
 			},
+			{
+				Name:   "edit",
+				Usage:  "edit a Nodepool, its nodes are replaced when changes can't be made in place",
+				Action: nodepoolEdit,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "nodepool",
+						Usage:    "Name of Nodepool to edit",
+						Required: true,
+					},
+				},
+			},
 			{
 				Name:   "destroy",
 				Usage:  "destroys a Nodepool",
@@ -65,18 +83,24 @@ func nodepoolList(c *cli.Context) error {
 		"Min Size",
 		"Max Size",
 		"Disk Size",
+		"Taints",
 		//"GPU",
 	})
 
 	err = resources.ForEach(kclient, &v1alpha1.NodepoolList{}, func(item interface{}) error {
 		np := item.(v1alpha1.Nodepool)
+		taints := make([]string, 0, len(np.Spec.Taints))
+		for _, taint := range np.Spec.Taints {
+			taints = append(taints, taint.ToString())
+		}
 		table.Append([]string{
 			np.Name,
-			np.Spec.MachineType,
+			strings.Join(np.Spec.GetMachineTypes(), ", "),
 			cast.ToString(np.Status.NumReady),
 			cast.ToString(np.Spec.MinSize),
 			cast.ToString(np.Spec.MaxSize),
 			fmt.Sprintf("%d GiB", np.Spec.DiskSizeGiB),
+			strings.Join(taints, ", "),
 			//cast.ToString(np.Spec.RequiresGPU),
 		})
 		return nil
@@ -126,12 +150,90 @@ func nodepoolCreate(c *cli.Context) error {
 	if _, err = resources.UpdateResource(ac.kubernetesClient(), nodepool, nil, nil); err != nil {
 		return err
 	}
+	if err = saveNodepoolStatus(ac.kubernetesClient(), nodepool); err != nil {
+		return err
+	}
 
 	fmt.Println("Successfully created nodepool", nodepool.Name)
 
 	return nil
 }
 
+func nodepoolEdit(c *cli.Context) error {
+	npName := c.String("nodepool")
+
+	ac, err := getActiveCluster()
+	if err != nil {
+		return err
+	}
+
+	kclient := ac.kubernetesClient()
+	existing := &v1alpha1.Nodepool{}
+	err = kclient.Get(context.Background(), client.ObjectKey{Name: npName}, existing)
+	if err != nil {
+		return err
+	}
+	if r := existing.Status.Replacement; r != nil && r.Phase != v1alpha1.NodepoolReplacementFailed {
+		return fmt.Errorf("nodepool %s is being replaced by %s", npName, r.Name)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = kube.GetKubeEncoder().Encode(existing, buf); err != nil {
+		return err
+	}
+	data, err := utilscli.ExecuteUserEditor(buf.Bytes(), fmt.Sprintf("%s.yaml", npName))
+	if err != nil {
+		return err
+	}
+	obj, _, err := kube.GetKubeDecoder().Decode(data, nil, &v1alpha1.Nodepool{})
+	if err != nil {
+		return err
+	}
+	nps := obj.(*v1alpha1.Nodepool).Spec
+
+	if apiequality.Semantic.DeepEqual(nps, existing.Spec) {
+		fmt.Println("Nodepool was not changed")
+		return nil
+	}
+	if err = nps.Validate(); err != nil {
+		return err
+	}
+	if nps.MinSize > nps.MaxSize {
+		return fmt.Errorf("minSize cannot be greater than maxSize")
+	}
+
+	applied := &existing.Spec
+	if existing.Status.AppliedSpec != nil {
+		applied = existing.Status.AppliedSpec
+	}
+	if nps.RequiresReplacement(applied) {
+		fmt.Printf("Changes to %s can't be made in place. A new nodepool will be created, then nodes of %s will be drained and removed.\n",
+			npName, npName)
+		if err := utils.ExplicitConfirmationPrompt("Do you want to proceed?"); err != nil {
+			return err
+		}
+	}
+
+	// changes are applied by the operator
+	existing.Spec = nps
+	if err = kclient.Update(context.Background(), existing); err != nil {
+		return err
+	}
+
+	fmt.Printf("Nodepool updated, check progress with kubectl get nodepool %s -o yaml\n", npName)
+	return nil
+}
+
+// saves the cloud status of the nodepool, i.e. its autoscaling group, onto the latest version of the resource
+func saveNodepoolStatus(kclient client.Client, np *v1alpha1.Nodepool) error {
+	existing := &v1alpha1.Nodepool{}
+	if err := kclient.Get(context.Background(), client.ObjectKey{Name: np.Name}, existing); err != nil {
+		return err
+	}
+	existing.Status.AWS = np.Status.AWS
+	return kclient.Status().Update(context.Background(), existing)
+}
+
 func nodepoolDestroy(c *cli.Context) error {
 	npName := c.String("nodepool")
 
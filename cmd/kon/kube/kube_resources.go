@@ -18,6 +18,8 @@ import (
 	"github.com/k11n/konstellation/pkg/components/kubedash"
 	"github.com/k11n/konstellation/pkg/components/metricsserver"
 	"github.com/k11n/konstellation/pkg/components/prometheus"
+	"github.com/k11n/konstellation/pkg/components/prometheusadapter"
+	"github.com/k11n/konstellation/pkg/components/ratelimit"
 )
 
 var (
@@ -32,7 +34,9 @@ var (
 		&kubedash.KubeDash{},
 		&autoscaler.ClusterAutoScaler{},
 		&istio.IstioInstaller{},
+		&ratelimit.RateLimitService{},
 		&prometheus.KubePrometheus{},
+		&prometheusadapter.PrometheusAdapter{},
 		&grafana.GrafanaOperator{},
 		&konstellation.Konstellation{},
 	}
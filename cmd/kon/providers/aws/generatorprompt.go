@@ -162,6 +162,20 @@ func (g *PromptConfigGenerator) CreateNodepoolConfig(cc *v1alpha1.ClusterConfig)
 		}
 	}
 
+	capacityPrompt := utils.NewPromptSelect(
+		"Capacity type",
+		[]string{"on-demand", "spot (discounted, but nodes could be interrupted)"},
+	)
+	idx, _, err := capacityPrompt.Run()
+	if err != nil {
+		return
+	}
+	if idx == 1 {
+		nps.AWS.CapacityType = v1alpha1.CapacityTypeSpot
+	} else {
+		nps.AWS.CapacityType = v1alpha1.CapacityTypeOnDemand
+	}
+
 	instanceConfirmed := false
 	for !instanceConfirmed {
 		//// node instance config
@@ -177,19 +191,25 @@ func (g *PromptConfigGenerator) CreateNodepoolConfig(cc *v1alpha1.ClusterConfig)
 		//	nps.RequiresGPU = true
 		//}
 		var instance *kaws.EC2InstancePricing
-		instance, err = promptInstanceType(g.session, g.region, nps.RequiresGPU)
+		var similar []string
+		instance, similar, err = promptInstanceType(g.session, g.region, nps.RequiresGPU, nps.AWS.IsSpot())
 		if err != nil {
 			return
 		}
 		nps.MachineType = instance.InstanceType
 
+		nps.AdditionalMachineTypes, err = promptAdditionalInstanceTypes(similar, nps.AWS.IsSpot())
+		if err != nil {
+			return
+		}
+
 		nps.MinSize, nps.MaxSize, err = promptInstanceSizing()
 		if err != nil {
 			return
 		}
 
 		// compute budget and inform
-		instanceConfirmed, err = promptConfirmBudget(instance, nps.MinSize, nps.MaxSize)
+		instanceConfirmed, err = promptConfirmBudget(instance, nps.MinSize, nps.MaxSize, nps.AWS.IsSpot())
 		if err != nil {
 			return
 		}
@@ -207,6 +227,10 @@ func (g *PromptConfigGenerator) CreateNodepoolConfig(cc *v1alpha1.ClusterConfig)
 	nps.DiskSizeGiB = cast.ToInt(sizeStr)
 	nps.Autoscale = true
 
+	if err = promptNodeLabelsAndTaints(&nps); err != nil {
+		return
+	}
+
 	//autoscalePrompt := promptui.Prompt{
 	//	Label:     "Use autoscaler",
 	//	IsConfirm: true,
@@ -483,7 +507,7 @@ func promptCreateKeypair(ec2Svc *ec2.EC2, name string) (keyName string, err erro
 	return
 }
 
-func promptInstanceType(session *session.Session, region string, gpu bool) (instance *kaws.EC2InstancePricing, err error) {
+func promptInstanceType(session *session.Session, region string, gpu bool, spot bool) (instance *kaws.EC2InstancePricing, similar []string, err error) {
 	// find all ec2 instances and create listing for price
 	pricingSvc := pricing.New(session, aws.NewConfig().WithRegion("us-east-1"))
 	instances, err := kaws.ListEC2Instances(pricingSvc, region, true)
@@ -507,19 +531,33 @@ func promptInstanceType(session *session.Session, region string, gpu bool) (inst
 		filteredInstances = append(filteredInstances, inst)
 	}
 
+	if spot {
+		if err = kaws.SetSpotPrices(ec2.New(session), filteredInstances); err != nil {
+			return
+		}
+		// only types that are offered as spot in the region
+		filteredInstances = funk.Filter(filteredInstances, func(inst *kaws.EC2InstancePricing) bool {
+			return inst.SpotPriceUSD > 0
+		}).([]*kaws.EC2InstancePricing)
+	}
+
 	instanceLabels := make([]string, 0, len(filteredInstances))
 	for _, inst := range filteredInstances {
 		var label string
+		price := instancePrice(inst, spot)
 		if gpu {
 			// instance type, VCPUs, GPUs, memory, network, price
 			label = fmt.Sprintf("%-14v %2v vCPUs    %2v GPUs    Memory: %-7v    Network: %-17v    $%0.2f/hr ($%.0f/mo)",
-				inst.InstanceType, inst.VCPUs, inst.GPUs, inst.Memory, inst.NetworkPerformance, inst.OnDemandPriceUSD,
-				inst.OnDemandPriceUSD*24*30)
+				inst.InstanceType, inst.VCPUs, inst.GPUs, inst.Memory, inst.NetworkPerformance, price,
+				price*24*30)
 		} else {
 			// instance type, VCPUs, memory, network, price
 			label = fmt.Sprintf("%-14v %2v vCPUs    Memory: %-7v    Network: %-17v    $%0.2f/hr ($%.0f/mo)",
-				inst.InstanceType, inst.VCPUs, inst.Memory, inst.NetworkPerformance, inst.OnDemandPriceUSD,
-				inst.OnDemandPriceUSD*24*30)
+				inst.InstanceType, inst.VCPUs, inst.Memory, inst.NetworkPerformance, price,
+				price*24*30)
+		}
+		if spot {
+			label += fmt.Sprintf("    on-demand $%0.2f/hr", inst.OnDemandPriceUSD)
 		}
 		instanceLabels = append(instanceLabels, label)
 	}
@@ -537,9 +575,99 @@ func promptInstanceType(session *session.Session, region string, gpu bool) (inst
 	}
 
 	instance = filteredInstances[idx]
+
+	// other types that could stand in for the chosen one
+	for _, inst := range filteredInstances {
+		if inst.InstanceType != instance.InstanceType && inst.VCPUs == instance.VCPUs && inst.Memory == instance.Memory {
+			similar = append(similar, inst.InstanceType)
+		}
+	}
+	return
+}
+
+func promptAdditionalInstanceTypes(similar []string, spot bool) (types []string, err error) {
+	if len(similar) == 0 {
+		return
+	}
+
+	fmt.Printf("Instance types with the same CPU and memory: %s\n", strings.Join(similar, ", "))
+	if spot {
+		fmt.Println("Spot capacity could run out for a single instance type, using a few of them is recommended")
+	}
+	typesPrompt := promptui.Prompt{
+		Label: "Additional instance types to use when out of capacity (comma separated, optional)",
+		Validate: func(val string) error {
+			for _, t := range splitPromptList(val) {
+				if !funk.ContainsString(similar, t) {
+					return fmt.Errorf("%s doesn't have the same CPU and memory", t)
+				}
+			}
+			return nil
+		},
+	}
+	val, err := typesPrompt.Run()
+	if err != nil {
+		return
+	}
+	types = splitPromptList(val)
 	return
 }
 
+func promptNodeLabelsAndTaints(nps *v1alpha1.NodepoolSpec) error {
+	labelsPrompt := promptui.Prompt{
+		Label: "Node labels, key=value (comma separated, optional)",
+		Validate: func(val string) error {
+			labels, err := v1alpha1.ParseNodeLabels(val)
+			if err != nil {
+				return err
+			}
+			return (&v1alpha1.NodepoolSpec{MachineType: nps.MachineType, Labels: labels}).Validate()
+		},
+	}
+	val, err := labelsPrompt.Run()
+	if err != nil {
+		return err
+	}
+	if nps.Labels, err = v1alpha1.ParseNodeLabels(val); err != nil {
+		return err
+	}
+
+	taintsPrompt := promptui.Prompt{
+		Label: "Node taints, key=value:Effect (comma separated, optional)",
+		Validate: func(val string) error {
+			taints, err := v1alpha1.ParseNodeTaints(val)
+			if err != nil {
+				return err
+			}
+			return (&v1alpha1.NodepoolSpec{MachineType: nps.MachineType, Taints: taints}).Validate()
+		},
+	}
+	val, err = taintsPrompt.Run()
+	if err != nil {
+		return err
+	}
+	nps.Taints, err = v1alpha1.ParseNodeTaints(val)
+	return err
+}
+
+func splitPromptList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// hourly price of the instance
+func instancePrice(instance *kaws.EC2InstancePricing, spot bool) float32 {
+	if spot {
+		return instance.SpotPriceUSD
+	}
+	return instance.OnDemandPriceUSD
+}
+
 func promptInstanceSizing() (minNodes int64, maxNodes int64, err error) {
 	sizePrompt := promptui.Prompt{
 		Label:    "Minimum/initial number of nodes (recommend 3 minimum for a production setup)",
@@ -562,12 +690,18 @@ func promptInstanceSizing() (minNodes int64, maxNodes int64, err error) {
 	return
 }
 
-func promptConfirmBudget(instance *kaws.EC2InstancePricing, minNodes, maxNodes int64) (bool, error) {
-	instanceMonthlyCost := instance.OnDemandPriceUSD * 24 * 30
+func promptConfirmBudget(instance *kaws.EC2InstancePricing, minNodes, maxNodes int64, spot bool) (bool, error) {
+	instanceMonthlyCost := instancePrice(instance, spot) * 24 * 30
 	minCost := instanceMonthlyCost * float32(minNodes)
 	maxCost := instanceMonthlyCost * float32(maxNodes)
-	fmt.Printf("Using %d-%d %s nodes will cost between $%0.2f to $%0.2f a month\n",
-		minNodes, maxNodes, instance.InstanceType, minCost, maxCost)
+	if spot {
+		fmt.Printf("Using %d-%d %s spot nodes will cost between $%0.2f to $%0.2f a month at current prices (on-demand: $%0.2f to $%0.2f)\n",
+			minNodes, maxNodes, instance.InstanceType, minCost, maxCost,
+			instance.OnDemandPriceUSD*24*30*float32(minNodes), instance.OnDemandPriceUSD*24*30*float32(maxNodes))
+	} else {
+		fmt.Printf("Using %d-%d %s nodes will cost between $%0.2f to $%0.2f a month\n",
+			minNodes, maxNodes, instance.InstanceType, minCost, maxCost)
+	}
 	confirmPrompt := promptui.Prompt{
 		Label:     "OK to continue",
 		IsConfirm: true,
@@ -10,7 +10,6 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/iam"
@@ -270,11 +269,6 @@ func (a *AWSManager) CreateCluster(cc *v1alpha1.ClusterConfig) error {
 func (a *AWSManager) CreateNodepool(cc *v1alpha1.ClusterConfig, np *v1alpha1.Nodepool) error {
 	fmt.Println("Creating nodepool...")
 
-	sess, err := a.awsSession()
-	if err != nil {
-		return err
-	}
-
 	// check aws nodepool status. if it doesn't exist, then create it
 	kubeProvider := a.KubernetesProvider()
 	ready, err := kubeProvider.IsNodepoolReady(context.Background(), cc.Name, np.Name)
@@ -302,33 +296,15 @@ func (a *AWSManager) CreateNodepool(cc *v1alpha1.ClusterConfig, np *v1alpha1.Nod
 		}
 	}
 
-	np.Status.AWS = &v1alpha1.AWSNodepoolStatus{}
-
 	// now grab the autoscaling group for this
-	asSvc := autoscaling.New(sess)
-	err = asSvc.DescribeAutoScalingGroupsPagesWithContext(context.Background(), &autoscaling.DescribeAutoScalingGroupsInput{},
-		func(res *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
-			for _, asg := range res.AutoScalingGroups {
-				var cluster, nodeGroup string
-				for _, tag := range asg.Tags {
-					switch *tag.Key {
-					case "eks:cluster-name":
-						cluster = *tag.Value
-					case "eks:nodegroup-name":
-						nodeGroup = *tag.Value
-					}
-				}
-				if cluster == cc.Name && nodeGroup == np.Name {
-					// found the cluster, update nodegroup
-					np.Status.AWS.ASGID = *asg.AutoScalingGroupName
-					return false
-				}
-			}
-			return true
-		},
-	)
-
-	return err
+	asgID, err := kubeProvider.GetNodepoolASG(context.Background(), cc.Name, np.Name)
+	if err != nil {
+		return err
+	}
+	np.Status.AWS = &v1alpha1.AWSNodepoolStatus{
+		ASGID: asgID,
+	}
+	return nil
 }
 
 func (a *AWSManager) ActivateCluster(cc *v1alpha1.ClusterConfig) error {
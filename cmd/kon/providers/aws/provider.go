@@ -23,6 +23,7 @@ import (
 	kaws "github.com/k11n/konstellation/pkg/cloud/aws"
 	"github.com/k11n/konstellation/pkg/components"
 	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/components/terminationhandler"
 	"github.com/k11n/konstellation/pkg/utils/async"
 )
 
@@ -177,8 +178,10 @@ func (a *AWSProvider) Setup() error {
 }
 
 func (a *AWSProvider) GetComponents() []components.ComponentInstaller {
-	comps := make([]components.ComponentInstaller, 0, len(kube.KubeComponents)+1)
+	comps := make([]components.ComponentInstaller, 0, len(kube.KubeComponents)+2)
 	comps = append(comps, kube.KubeComponents...)
+	// drains spot nodes before they are reclaimed
+	comps = append(comps, &terminationhandler.NodeTerminationHandler{})
 	comps = append(comps, &ingress.AWSALBIngress{})
 	return comps
 }
@@ -78,6 +78,64 @@ resource "aws_iam_role_policy" "eks_node_role_autoscaler_policy" {
   EOF
 }
 
+// lets the operator manage DNS records of ingress hosts, when a DNS provider is configured
+resource "aws_iam_role_policy" "eks_node_role_dns_policy" {
+  name = "dns-policy"
+  role = aws_iam_role.eks_node_role.id
+
+  policy = <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+      {
+          "Action": [
+              "route53:ChangeResourceRecordSets",
+              "route53:ListHostedZones",
+              "route53:ListResourceRecordSets",
+              "elasticloadbalancing:DescribeLoadBalancers"
+          ],
+          "Resource": "*",
+          "Effect": "Allow"
+      }
+  ]
+}
+  EOF
+}
+
+// lets the operator apply nodepool changes, creating a new node group when nodes need to be replaced
+resource "aws_iam_role_policy" "eks_node_role_nodepool_policy" {
+  name = "nodepool-policy"
+  role = aws_iam_role.eks_node_role.id
+
+  policy = <<EOF
+{
+  "Version": "2012-10-17",
+  "Statement": [
+      {
+          "Action": [
+              "eks:CreateNodegroup",
+              "eks:DeleteNodegroup",
+              "eks:DescribeNodegroup",
+              "eks:ListNodegroups",
+              "eks:UpdateNodegroupConfig",
+              "ec2:DescribeSubnets",
+              "iam:GetRole"
+          ],
+          "Resource": "*",
+          "Effect": "Allow"
+      },
+      {
+          "Action": [
+              "iam:PassRole"
+          ],
+          "Resource": "${aws_iam_role.eks_node_role.arn}",
+          "Effect": "Allow"
+      }
+  ]
+}
+  EOF
+}
+
 resource "aws_iam_role_policy_attachment" "eks_node_role_eks_worker_policy" {
   role       = aws_iam_role.eks_node_role.id
   policy_arn = "arn:aws:iam::aws:policy/AmazonEKSWorkerNodePolicy"
@@ -224,6 +224,7 @@ func newAppTargetForApp(app *v1alpha1.App, target string, build *v1alpha1.Build)
 			},
 			DeployMode: app.Spec.DeployModeForTarget(target),
 			Configs:    app.Spec.Configs,
+			ConfigEnv:  app.Spec.ConfigEnv,
 			Scale:      *app.Spec.ScaleSpecForTarget(target),
 			Prometheus: app.Spec.Prometheus,
 		},
@@ -233,6 +234,8 @@ func newAppTargetForApp(app *v1alpha1.App, target string, build *v1alpha1.Build)
 	// TODO: this should never be nil
 	if tc != nil {
 		at.Spec.Ingress = tc.Ingress
+		at.Spec.Expose = tc.Expose
+		at.Spec.Maintenance = tc.Maintenance
 	}
 
 	return at
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-logr/logr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const appConfigValidatePath = "/validate-k11n-dev-v1alpha1-appconfig"
+
+// AppConfigValidator rejects configs that don't conform to their schema
+type AppConfigValidator struct {
+	client.Client
+	Log     logr.Logger
+	decoder *admission.Decoder
+}
+
+// +kubebuilder:webhook:path=/validate-k11n-dev-v1alpha1-appconfig,mutating=false,failurePolicy=ignore,groups=k11n.dev,resources=appconfigs,verbs=create;update,versions=v1alpha1,name=vappconfig.k11n.dev
+// +kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations,verbs=get;update
+
+func (v *AppConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	ac := &v1alpha1.AppConfig{}
+	if err := v.decoder.Decode(req, ac); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	schema, err := resources.GetConfigSchema(v.Client, ac)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err = resources.ValidateAppConfig(v.Client, ac, schema); err != nil {
+		v.Log.Info("rejected invalid config", "config", ac.Name, "error", err.Error())
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+func (v *AppConfigValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+func (v *AppConfigValidator) SetupWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(appConfigValidatePath, &webhook.Admission{Handler: v})
+	return nil
+}
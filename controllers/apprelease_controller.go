@@ -24,11 +24,14 @@ import (
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscale "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -40,13 +43,18 @@ import (
 // AppReleaseReconciler reconciles a AppRelease object
 type AppReleaseReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log        logr.Logger
+	Scheme     *runtime.Scheme
+	KubeClient kubernetes.Interface
+	KubeConfig *rest.Config
 }
 
 // +kubebuilder:rbac:groups=k11n.dev,resources=appreleases;builds;,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=configmaps;pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=delete
+// +kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
 // +kubebuilder:rbac:groups=k11n.dev,resources=appreleases/status,verbs=get;update;patch
 
 func (r *AppReleaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -86,17 +94,41 @@ func (r *AppReleaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		return ctrl.Result{RequeueAfter: 1 * time.Minute}, err
 	}
 
+	// update config files when reloading config live. when reloading with restarts, pods started with a
+	// different hash are restarted
+	liveHash, filesUpdated, err := r.syncLiveConfigFiles(ar)
+	if err != nil {
+		return res, err
+	}
+	if liveHash != "" && restartsOnConfigReload(ar) {
+		rs.Spec.Template.Annotations = map[string]string{
+			liveConfigHashAnnotation: liveHash,
+		}
+	}
+
 	shouldUpdate := true
-	if ar.Spec.Role == v1alpha1.ReleaseRoleActive && ar.Labels[resources.TargetReleaseLabel] == "1" {
-		// when we are reconciling the active release, it means autoscaler is in charge of setting the numDesired field
-		// on the replicaset. We don't want to proceed with updates
+	autoscaled, err := r.hasAutoscaler(ctx, ar)
+	if err != nil {
+		return res, err
+	}
+	if autoscaled || (ar.Spec.Role == v1alpha1.ReleaseRoleActive && ar.Labels[resources.TargetReleaseLabel] == "1") {
+		// when we are reconciling the active release, or releases that are autoscaled during a deploy,
+		// autoscaler is in charge of setting the numDesired field on the replicaset. We don't want to proceed with updates
 		key, err := client.ObjectKeyFromObject(rs)
 		if err != nil {
 			return res, err
 		}
-		err = r.Client.Get(ctx, key, rs)
-		if err == nil && ar.Spec.NumDesired != 0 && *rs.Spec.Replicas != 0 {
-			shouldUpdate = false
+		existing := &appsv1.ReplicaSet{}
+		err = r.Client.Get(ctx, key, existing)
+		if err == nil && ar.Spec.NumDesired != 0 && *existing.Spec.Replicas != 0 {
+			if existing.Spec.Template.Annotations[liveConfigHashAnnotation] == rs.Spec.Template.Annotations[liveConfigHashAnnotation] {
+				shouldUpdate = false
+				rs = existing
+			} else {
+				// live config changed, update the pod template but keep the autoscaled size
+				rs.Spec.Replicas = existing.Spec.Replicas
+				rs.Status = existing.Status
+			}
 		}
 	}
 
@@ -181,6 +213,15 @@ func (r *AppReleaseReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 		}
 	}
 
+	// make sure pods are using the live config
+	requeue, err := r.reconcileLiveConfig(ctx, ar, &status, liveHash, filesUpdated)
+	if err != nil {
+		return res, err
+	}
+	if requeue > 0 {
+		res.RequeueAfter = requeue
+	}
+
 	if !apiequality.Semantic.DeepEqual(status, ar.Status) {
 		//reqLogger.Info("status changed", "old", ar.Status, "new", status)
 		ar.Status = status
@@ -234,10 +275,23 @@ func getPodError(pod corev1.Pod) *v1alpha1.PodStatus {
 	return podError
 }
 
+// releases could be autoscaled in the middle of a deploy, when they each get a share of traffic
+func (r *AppReleaseReconciler) hasAutoscaler(ctx context.Context, ar *v1alpha1.AppRelease) (bool, error) {
+	scaler := autoscale.HorizontalPodAutoscaler{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: ar.Namespace, Name: autoscalerName(ar)}, &scaler)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (r *AppReleaseReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.AppRelease{}).
 		Owns(&appsv1.ReplicaSet{}).
+		Owns(&corev1.ConfigMap{}).
 		Complete(r)
 }
 
@@ -304,10 +358,19 @@ func (r *AppReleaseReconciler) newReplicaSetForAR(ar *v1alpha1.AppRelease, build
 		return strings.Compare(container.Env[i].Name, container.Env[j].Name) < 0
 	})
 
+	// mount config files that are updated in place
+	var volumes []corev1.Volume
+	if ar.Spec.ConfigReload == v1alpha1.ConfigReloadLive {
+		volume, mount := configVolumeForAR(ar)
+		volumes = append(volumes, volume)
+		container.VolumeMounts = append(container.VolumeMounts, mount)
+	}
+
 	podSpec := corev1.PodSpec{
 		Containers: []corev1.Container{
 			container,
 		},
+		Volumes: volumes,
 	}
 
 	if ar.Spec.ServiceAccount != "" {
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	// kubelet syncs mounted ConfigMaps periodically, give it time before notifying pods
+	liveConfigSyncDelay = 90 * time.Second
+	// when restarting to reload, pods are replaced one at a time, checking on progress at this interval
+	liveConfigRestartInterval = 10 * time.Second
+	configVolumeName          = "konstellation-config"
+	defaultReloadSignal       = "HUP"
+	// set on the pod template, pods with a different hash are restarted to pick up config changes
+	liveConfigHashAnnotation = "k11n.dev/liveConfigHash"
+)
+
+var reloadHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+/**
+ * Keeps mounted config files in sync with the live config.
+ * returns the hash of the live config, and if the files were updated
+ */
+func (r *AppReleaseReconciler) syncLiveConfigFiles(ar *v1alpha1.AppRelease) (hash string, updated bool, err error) {
+	if ar.Spec.ConfigReload != v1alpha1.ConfigReloadLive || ar.CurrentConfig() == "" {
+		return
+	}
+
+	revision := ar.CurrentConfig()
+	cm, err := resources.GetConfigMap(r.Client, ar.Namespace, revision)
+	if errors.IsNotFound(err) {
+		return "", false, nil
+	} else if err != nil {
+		return
+	}
+
+	configFiles, err := resources.GetConfigFiles(cm)
+	if err != nil {
+		return
+	}
+	filesCM := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: ar.Namespace,
+			Name:      ar.ConfigFilesName(),
+			Labels:    labelsForAppRelease(ar),
+		},
+		Data: configFiles,
+	}
+	hash = cm.Labels[v1alpha1.ConfigHashLabel]
+	if hash == "" {
+		hash = revision
+	}
+	filesCM.Labels[v1alpha1.ConfigHashLabel] = hash
+	op, err := resources.UpdateResource(r.Client, filesCM, ar, r.Scheme)
+	if err != nil {
+		return
+	}
+	updated = op != controllerutil.OperationResultNone
+	return
+}
+
+/**
+ * Makes sure pods are using the live config. Once files are synced, each pod is notified with a signal (SIGHUP
+ * by default) or a request to the reload hook. Apps that only read config on start could have their pods
+ * restarted one at a time instead.
+ * returns a duration when it needs to be checked again
+ */
+func (r *AppReleaseReconciler) reconcileLiveConfig(ctx context.Context, ar *v1alpha1.AppRelease, status *v1alpha1.AppReleaseStatus, hash string, filesUpdated bool) (requeue time.Duration, err error) {
+	status.LiveConfig = ar.Status.LiveConfig
+	status.LiveConfigUpdatedAt = ar.Status.LiveConfigUpdatedAt
+	if hash == "" {
+		return
+	}
+	if filesUpdated {
+		now := metav1.Now()
+		status.LiveConfigUpdatedAt = &now
+	}
+
+	revision := ar.CurrentConfig()
+	if status.LiveConfig == revision {
+		return
+	}
+
+	if restartsOnConfigReload(ar) {
+		var done bool
+		requeue, done, err = r.restartStalePods(ctx, ar, status, hash)
+		if err != nil || !done {
+			return
+		}
+		r.Log.Info("Restarted pods with live config", "appRelease", ar.Name, "config", revision)
+		status.LiveConfig = revision
+		return
+	}
+
+	if status.LiveConfig == "" || status.NumAvailable == 0 {
+		// pods will start with the current files
+		status.LiveConfig = revision
+		return
+	}
+	if status.LiveConfigUpdatedAt != nil {
+		elapsed := time.Since(status.LiveConfigUpdatedAt.Time)
+		if elapsed < liveConfigSyncDelay {
+			return liveConfigSyncDelay - elapsed, nil
+		}
+	}
+
+	if err = r.notifyConfigReload(ar); err != nil {
+		return
+	}
+	r.Log.Info("Reloaded live config", "appRelease", ar.Name, "config", revision)
+	status.LiveConfig = revision
+	return
+}
+
+func restartsOnConfigReload(ar *v1alpha1.AppRelease) bool {
+	return ar.Spec.ConfigReloadHook != nil && ar.Spec.ConfigReloadHook.Restart
+}
+
+// deletes a pod that's started with a previous config, once the others are available.
+// returns true when all pods are using the current config
+func (r *AppReleaseReconciler) restartStalePods(ctx context.Context, ar *v1alpha1.AppRelease, status *v1alpha1.AppReleaseStatus, hash string) (requeue time.Duration, done bool, err error) {
+	pods, err := resources.GetPodsForAppRelease(r.Client, ar.Namespace, ar.Name)
+	if err != nil {
+		return
+	}
+
+	var stale []*corev1.Pod
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			// wait for the previous restart to finish
+			return liveConfigRestartInterval, false, nil
+		}
+		if pod.Annotations[liveConfigHashAnnotation] != hash {
+			stale = append(stale, pod)
+		}
+	}
+	if len(stale) == 0 {
+		return 0, true, nil
+	}
+	if status.NumAvailable < status.NumDesired {
+		return liveConfigRestartInterval, false, nil
+	}
+
+	err = client.IgnoreNotFound(r.Client.Delete(ctx, stale[0]))
+	return liveConfigRestartInterval, false, err
+}
+
+func (r *AppReleaseReconciler) notifyConfigReload(ar *v1alpha1.AppRelease) error {
+	pods, err := resources.GetPodsForAppRelease(r.Client, ar.Namespace, ar.Name)
+	if err != nil {
+		return err
+	}
+
+	hook := ar.Spec.ConfigReloadHook
+	if hook == nil {
+		hook = &v1alpha1.ConfigReloadHook{}
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		if hook.Path != "" {
+			err = requestConfigReload(ar, pod, hook)
+		} else {
+			err = r.signalConfigReload(ar, pod, hook)
+		}
+		if err != nil {
+			return fmt.Errorf("could not reload config for pod %s: %v", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+func requestConfigReload(ar *v1alpha1.AppRelease, pod *corev1.Pod, hook *v1alpha1.ConfigReloadHook) error {
+	var port int32
+	for _, p := range ar.Spec.Ports {
+		if hook.Port == "" || p.Name == hook.Port {
+			port = p.Port
+			break
+		}
+	}
+	if port == 0 {
+		return fmt.Errorf("could not find port for config reload hook")
+	}
+
+	path := hook.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	resp, err := reloadHTTPClient.Post(fmt.Sprintf("http://%s:%d%s", pod.Status.PodIP, port, path), "", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("config reload hook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sends a signal to the main process of the app container
+func (r *AppReleaseReconciler) signalConfigReload(ar *v1alpha1.AppRelease, pod *corev1.Pod, hook *v1alpha1.ConfigReloadHook) error {
+	req := r.KubeClient.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: ar.Spec.App,
+			Command:   reloadSignalCommand(hook),
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(r.KubeConfig, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+
+	output := bytes.NewBuffer(nil)
+	err = executor.Stream(remotecommand.StreamOptions{
+		Stdout: output,
+		Stderr: output,
+	})
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, output.String())
+	}
+	return nil
+}
+
+// command that signals the main process, accepts signals with or without the SIG prefix
+func reloadSignalCommand(hook *v1alpha1.ConfigReloadHook) []string {
+	signal := strings.TrimPrefix(strings.ToUpper(hook.Signal), "SIG")
+	if signal == "" {
+		signal = defaultReloadSignal
+	}
+	return []string{"kill", "-" + signal, "1"}
+}
+
+func configVolumeForAR(ar *v1alpha1.AppRelease) (corev1.Volume, corev1.VolumeMount) {
+	optional := true
+	volume := corev1.Volume{
+		Name: configVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: ar.ConfigFilesName(),
+				},
+				Optional: &optional,
+			},
+		},
+	}
+	mount := corev1.VolumeMount{
+		Name:      configVolumeName,
+		MountPath: v1alpha1.LiveConfigPath,
+		ReadOnly:  true,
+	}
+	return volume, mount
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+func newLiveRelease(hook *v1alpha1.ConfigReloadHook) *v1alpha1.AppRelease {
+	ar := &v1alpha1.AppRelease{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "myapp",
+			Name:      "myapp-release",
+		},
+		Spec: v1alpha1.AppReleaseSpec{
+			App:    "myapp",
+			Config: "config-old",
+		},
+		Status: v1alpha1.AppReleaseStatus{
+			LiveConfig: "config-old",
+		},
+	}
+	ar.Spec.LiveConfig = "config-new"
+	ar.Spec.ConfigReload = v1alpha1.ConfigReloadLive
+	ar.Spec.ConfigReloadHook = hook
+	return ar
+}
+
+func newReleasePod(name, hash string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "myapp",
+			Name:      name,
+			Labels: map[string]string{
+				resources.AppReleaseLabel: "myapp-release",
+			},
+			Annotations: map[string]string{
+				liveConfigHashAnnotation: hash,
+			},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			PodIP: "127.0.0.1",
+		},
+	}
+}
+
+func newReloadReconciler(objs ...runtime.Object) *AppReleaseReconciler {
+	return &AppReleaseReconciler{
+		Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme, objs...),
+		Log:    ctrl.Log.WithName("test"),
+		Scheme: clientgoscheme.Scheme,
+	}
+}
+
+func podNames(t *testing.T, r *AppReleaseReconciler) []string {
+	pods, err := resources.GetPodsForAppRelease(r.Client, "myapp", "myapp-release")
+	assert.NoError(t, err)
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestReconcileLiveConfig(t *testing.T) {
+	t.Run("not reloading live", func(t *testing.T) {
+		r := newReloadReconciler()
+		ar := newLiveRelease(nil)
+		status := v1alpha1.AppReleaseStatus{}
+		requeue, err := r.reconcileLiveConfig(context.TODO(), ar, &status, "", false)
+		assert.NoError(t, err)
+		assert.Zero(t, requeue)
+		assert.Equal(t, "config-old", status.LiveConfig)
+	})
+
+	t.Run("waits for files to sync", func(t *testing.T) {
+		r := newReloadReconciler(newReleasePod("pod1", "old"))
+		ar := newLiveRelease(nil)
+		status := v1alpha1.AppReleaseStatus{NumDesired: 1, NumAvailable: 1}
+		requeue, err := r.reconcileLiveConfig(context.TODO(), ar, &status, "new", true)
+		assert.NoError(t, err)
+		assert.True(t, requeue > 0 && requeue <= liveConfigSyncDelay)
+		assert.NotNil(t, status.LiveConfigUpdatedAt)
+		assert.Equal(t, "config-old", status.LiveConfig)
+		// pods are notified, not restarted
+		assert.Equal(t, []string{"pod1"}, podNames(t, r))
+	})
+
+	t.Run("calls reload hook", func(t *testing.T) {
+		var reloads int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodPost && req.URL.Path == "/-/reload" {
+				reloads++
+			}
+		}))
+		defer server.Close()
+		_, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+		assert.NoError(t, err)
+		port, err := strconv.Atoi(portStr)
+		assert.NoError(t, err)
+
+		r := newReloadReconciler(newReleasePod("pod1", "old"), newReleasePod("pod2", "old"))
+		ar := newLiveRelease(&v1alpha1.ConfigReloadHook{Path: "-/reload"})
+		ar.Spec.Ports = []v1alpha1.PortSpec{{Name: "http", Port: int32(port)}}
+		synced := metav1.NewTime(time.Now().Add(-2 * liveConfigSyncDelay))
+		ar.Status.LiveConfigUpdatedAt = &synced
+		status := v1alpha1.AppReleaseStatus{NumDesired: 2, NumAvailable: 2}
+
+		requeue, err := r.reconcileLiveConfig(context.TODO(), ar, &status, "new", false)
+		assert.NoError(t, err)
+		assert.Zero(t, requeue)
+		assert.Equal(t, 2, reloads)
+		assert.Equal(t, "config-new", status.LiveConfig)
+		assert.Equal(t, []string{"pod1", "pod2"}, podNames(t, r))
+	})
+
+	t.Run("restarts when requested", func(t *testing.T) {
+		r := newReloadReconciler(newReleasePod("pod1", "new"), newReleasePod("pod2", "old"))
+		ar := newLiveRelease(&v1alpha1.ConfigReloadHook{Restart: true})
+		status := v1alpha1.AppReleaseStatus{NumDesired: 2, NumAvailable: 2}
+
+		requeue, err := r.reconcileLiveConfig(context.TODO(), ar, &status, "new", false)
+		assert.NoError(t, err)
+		assert.Equal(t, liveConfigRestartInterval, requeue)
+		assert.Equal(t, "config-old", status.LiveConfig)
+		assert.Equal(t, []string{"pod1"}, podNames(t, r))
+
+		// once pods are replaced, the new config is live
+		requeue, err = r.reconcileLiveConfig(context.TODO(), ar, &status, "new", false)
+		assert.NoError(t, err)
+		assert.Zero(t, requeue)
+		assert.Equal(t, "config-new", status.LiveConfig)
+	})
+}
+
+func TestRestartStalePods(t *testing.T) {
+	ar := newLiveRelease(&v1alpha1.ConfigReloadHook{Restart: true})
+
+	t.Run("waits for pods to become available", func(t *testing.T) {
+		r := newReloadReconciler(newReleasePod("pod1", "old"), newReleasePod("pod2", "old"))
+		status := &v1alpha1.AppReleaseStatus{NumDesired: 2, NumAvailable: 1}
+		requeue, done, err := r.restartStalePods(context.TODO(), ar, status, "new")
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.Equal(t, liveConfigRestartInterval, requeue)
+		assert.Len(t, podNames(t, r), 2)
+	})
+
+	t.Run("waits for terminating pods", func(t *testing.T) {
+		terminating := newReleasePod("pod1", "old")
+		now := metav1.Now()
+		terminating.DeletionTimestamp = &now
+		r := newReloadReconciler(terminating, newReleasePod("pod2", "old"))
+		status := &v1alpha1.AppReleaseStatus{NumDesired: 2, NumAvailable: 2}
+		_, done, err := r.restartStalePods(context.TODO(), ar, status, "new")
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.Len(t, podNames(t, r), 2)
+	})
+
+	t.Run("restarts one pod at a time", func(t *testing.T) {
+		r := newReloadReconciler(newReleasePod("pod1", "old"), newReleasePod("pod2", "old"))
+		status := &v1alpha1.AppReleaseStatus{NumDesired: 2, NumAvailable: 2}
+		_, done, err := r.restartStalePods(context.TODO(), ar, status, "new")
+		assert.NoError(t, err)
+		assert.False(t, done)
+		assert.Len(t, podNames(t, r), 1)
+	})
+
+	t.Run("done when pods are current", func(t *testing.T) {
+		r := newReloadReconciler(newReleasePod("pod1", "new"))
+		status := &v1alpha1.AppReleaseStatus{NumDesired: 1, NumAvailable: 1}
+		requeue, done, err := r.restartStalePods(context.TODO(), ar, status, "new")
+		assert.NoError(t, err)
+		assert.True(t, done)
+		assert.Zero(t, requeue)
+	})
+}
+
+func TestReloadSignalCommand(t *testing.T) {
+	assert.Equal(t, []string{"kill", "-HUP", "1"}, reloadSignalCommand(&v1alpha1.ConfigReloadHook{}))
+	assert.Equal(t, []string{"kill", "-USR1", "1"}, reloadSignalCommand(&v1alpha1.ConfigReloadHook{Signal: "sigusr1"}))
+	assert.Equal(t, []string{"kill", "-TERM", "1"}, reloadSignalCommand(&v1alpha1.ConfigReloadHook{Signal: "TERM"}))
+}
@@ -0,0 +1,336 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud/acme"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	// orders that haven't been validated by then are abandoned
+	certificateIssueTimeout = 10 * time.Minute
+	// limits each request to the CA, including waiting for the certificate after finalizing
+	certificateFinalizeTimeout    = time.Minute
+	certificateOrderCheckInterval = 10 * time.Second
+	// CAs limit failed validations, so wait before trying again
+	certificateRetryInterval = 15 * time.Minute
+	acmeAccountKeyField      = "key.pem"
+	operatorSelectorLabel    = "control-plane"
+	operatorSelectorValue    = "konstellation-manager"
+)
+
+// CertificateRefReconciler tracks certificate expiration, and issues and renews certificates with ACME
+type CertificateRefReconciler struct {
+	client.Client
+	Log        logr.Logger
+	Scheme     *runtime.Scheme
+	Recorder   record.EventRecorder
+	Challenges *acme.HTTPChallenges
+}
+
+// +kubebuilder:rbac:groups=k11n.dev,resources=certificaterefs,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=k11n.dev,resources=certificaterefs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets;services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *CertificateRefReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
+	ctx := context.Background()
+	res := ctrl.Result{}
+
+	cert := &v1alpha1.CertificateRef{}
+	err := r.Client.Get(ctx, req.NamespacedName, cert)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			removeCertificateMetrics(req.Name)
+			return res, nil
+		}
+		return res, err
+	}
+
+	cc, err := resources.GetClusterConfig(r.Client)
+	if err != nil {
+		return res, err
+	}
+
+	if err = r.reconcileMonitoring(cc); err != nil {
+		return res, err
+	}
+
+	// certificates from the cloud provider are issued and renewed by the provider
+	if cert.GetSource() == v1alpha1.CertificateSourceACME && ingress.ACMEEnabled(cc) {
+		res.RequeueAfter, err = r.reconcileACME(ctx, cc, cert)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	expiryRequeue, err := r.reconcileExpiry(ctx, cc, cert)
+	if err != nil {
+		return res, err
+	}
+	if expiryRequeue != 0 && (res.RequeueAfter == 0 || expiryRequeue < res.RequeueAfter) {
+		res.RequeueAfter = expiryRequeue
+	}
+
+	return res, nil
+}
+
+// issues certificates one step at a time: ordering, accepting challenges once responses are in place,
+// and saving the certificate once the CA has validated the domain
+func (r *CertificateRefReconciler) reconcileACME(ctx context.Context, cc *v1alpha1.ClusterConfig, cert *v1alpha1.CertificateRef) (requeue time.Duration, err error) {
+	log := r.Log.WithValues("certificateref", cert.Name)
+	if err = r.reconcileSolverServices(); err != nil {
+		return
+	}
+
+	// ensure the secret is still around before trusting the renewal time
+	secretExists := true
+	if _, err = resources.GetSecret(r.Client, resources.IstioNamespace, cert.Spec.SecretName); errors.IsNotFound(err) {
+		secretExists = false
+	} else if err != nil {
+		return
+	}
+	if cert.Spec.ACMEOrder == nil {
+		if cert.IsReady() && secretExists && cert.Spec.RenewAt != nil && time.Now().Before(cert.Spec.RenewAt.Time) {
+			return time.Until(cert.Spec.RenewAt.Time), nil
+		}
+		if cert.Spec.RetryAt != nil && time.Now().Before(cert.Spec.RetryAt.Time) {
+			return time.Until(cert.Spec.RetryAt.Time), nil
+		}
+	}
+
+	service, err := r.acmeService(ctx, cc)
+	if err != nil {
+		return
+	}
+
+	specCopy := cert.Spec.DeepCopy()
+	var issued *acme.IssuedCertificate
+	var issueErr error
+	if cert.Spec.ACMEOrder == nil {
+		log.Info("Ordering certificate", "domain", cert.Spec.Domain)
+		cert.Spec.ACMEOrder, issueErr = service.BeginOrder(ctx, cert.Spec.Domain, ingress.ACMEChallengeType(cc))
+	} else if time.Since(cert.Spec.ACMEOrder.CreatedAt.Time) > certificateIssueTimeout {
+		issueErr = fmt.Errorf("timed out waiting for %s to be validated", cert.Spec.Domain)
+	} else {
+		orderCtx, cancel := context.WithTimeout(ctx, certificateFinalizeTimeout)
+		issued, issueErr = service.ProgressOrder(orderCtx, cert.Spec.ACMEOrder, cert.Spec.Domain)
+		cancel()
+	}
+
+	if (issueErr != nil || issued != nil) && cert.Spec.ACMEOrder != nil {
+		if err := service.CleanupOrder(ctx, cert.Spec.ACMEOrder); err != nil {
+			log.Error(err, "Could not clean up challenges", "domain", cert.Spec.Domain)
+		}
+		cert.Spec.ACMEOrder = nil
+	}
+
+	if issueErr != nil {
+		log.Error(issueErr, "Could not issue certificate", "domain", cert.Spec.Domain)
+		r.Recorder.Eventf(cert, corev1.EventTypeWarning, "IssueFailed", "Could not issue certificate for %s: %v",
+			cert.Spec.Domain, issueErr)
+		// certificates being renewed could continue to be used until they expire
+		if !cert.IsReady() || !secretExists {
+			cert.Spec.Status = "ERROR"
+		}
+		cert.Spec.Message = issueErr.Error()
+		retryAt := metav1.NewTime(time.Now().Add(certificateRetryInterval))
+		cert.Spec.RetryAt = &retryAt
+		requeue = certificateRetryInterval
+	} else if issued != nil {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: resources.IstioNamespace,
+				Name:      cert.Spec.SecretName,
+				Labels: map[string]string{
+					resources.Konstellation: "1",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       issued.CertificatePEM,
+				corev1.TLSPrivateKeyKey: issued.PrivateKeyPEM,
+			},
+		}
+		op, err := resources.UpdateResource(r.Client, secret, cert, r.Scheme)
+		if err != nil {
+			return 0, err
+		}
+		resources.LogUpdates(log, op, "Updated certificate secret", "secret", secret.Name)
+
+		renewAt := metav1.NewTime(acme.RenewalTime(issued.Certificate, time.Now()))
+		cert.Spec.Status = issued.Certificate.Status.String()
+		cert.Spec.Issuer = issued.Certificate.Issuer
+		cert.Spec.ExpiresAt = metav1.NewTime(*issued.Certificate.ExpiresAt)
+		cert.Spec.KeyAlgorithm = issued.Certificate.KeyAlgorithm
+		cert.Spec.SignatureAlgorithm = issued.Certificate.SignatureAlgorithm
+		cert.Spec.RenewAt = &renewAt
+		cert.Spec.RetryAt = nil
+		cert.Spec.Message = ""
+		requeue = time.Until(renewAt.Time)
+		r.Recorder.Eventf(cert, corev1.EventTypeNormal, "Issued", "Issued certificate for %s, expires at %s",
+			cert.Spec.Domain, cert.Spec.ExpiresAt.Format(time.RFC3339))
+	} else {
+		// waiting on the CA
+		requeue = certificateOrderCheckInterval
+	}
+
+	if !apiequality.Semantic.DeepEqual(specCopy, &cert.Spec) {
+		if err = r.Client.Update(ctx, cert); err != nil {
+			return
+		}
+		log.Info("Updated certificate", "domain", cert.Spec.Domain, "status", cert.Spec.Status)
+	}
+	return
+}
+
+// returns a client for the configured CA, registering a new account key when needed
+func (r *CertificateRefReconciler) acmeService(ctx context.Context, cc *v1alpha1.ClusterConfig) (*acme.ACMEService, error) {
+	conf := cc.Spec.ComponentConfig[ingress.ComponentName]
+	server := conf[ingress.ACMEServerKey]
+	if server == "" {
+		server = acme.LetsEncryptURL
+	}
+	// TXT records for dns-01 challenges are created with the provider that manages ingress records
+	dnsProvider, err := ingress.NewDNSProviderForCluster(cc)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := resources.GetSecret(r.Client, resources.KonSystemNamespace, resources.ACMEAccountSecretName)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		key, err := acme.ParsePrivateKey(secret.Data[acmeAccountKeyField])
+		if err != nil {
+			return nil, err
+		}
+		return acme.NewACMEService(server, key, conf[ingress.ACMEEmailKey], r.Challenges, dnsProvider), nil
+	}
+
+	key, err := acme.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := acme.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      resources.ACMEAccountSecretName,
+		},
+		Data: map[string][]byte{
+			acmeAccountKeyField: keyData,
+		},
+	}
+	if err = r.Client.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return acme.NewACMEService(server, key, conf[ingress.ACMEEmailKey], r.Challenges, dnsProvider), nil
+}
+
+// challenges are served by the operator, the ingress routes to it through a service in the ingress namespace
+func (r *CertificateRefReconciler) reconcileSolverServices() error {
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      resources.ACMESolverServiceName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				operatorSelectorLabel: operatorSelectorValue,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       80,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(acme.ChallengePort),
+				},
+			},
+		},
+	}
+	if err := r.createServiceIfMissing(svc); err != nil {
+		return err
+	}
+
+	ingressSvc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      resources.ACMESolverServiceName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: resources.ServiceHostname(svc.Namespace, svc.Name),
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "http",
+					Port:     80,
+					Protocol: corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	return r.createServiceIfMissing(ingressSvc)
+}
+
+// services don't change, so they only need to be created
+func (r *CertificateRefReconciler) createServiceIfMissing(svc *corev1.Service) error {
+	_, err := resources.GetService(r.Client, svc.Namespace, svc.Name)
+	if !errors.IsNotFound(err) {
+		return err
+	}
+	if err = r.Client.Create(context.TODO(), svc); err != nil {
+		return err
+	}
+	r.Log.Info("Created service", "service", svc.Name, "namespace", svc.Namespace)
+	return nil
+}
+
+func (r *CertificateRefReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.CertificateRef{}).
+		Owns(&corev1.Secret{}).
+		Complete(r)
+}
@@ -0,0 +1,240 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	kprometheus "github.com/k11n/konstellation/pkg/components/prometheus"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	certificateExpiryMetric = "konstellation_certificate_expiry_timestamp_seconds"
+	operatorMetricsName     = "konstellation-metrics"
+	operatorMetricsPort     = 8080
+	operatorMetricsPortName = "http-metrics"
+	day                     = 24 * time.Hour
+)
+
+var (
+	certificateExpiry = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: certificateExpiryMetric,
+			Help: "Time that the certificate expires, in seconds since epoch",
+		},
+		[]string{"certificate", "domain"},
+	)
+	// domains of certificates with metrics, to clean up series after they are deleted
+	certificateDomains sync.Map
+)
+
+func init() {
+	metrics.Registry.MustRegister(certificateExpiry)
+}
+
+// sets expiry conditions on the certificate and returns the time until they would change next
+func (r *CertificateRefReconciler) reconcileExpiry(ctx context.Context, cc *v1alpha1.ClusterConfig, cert *v1alpha1.CertificateRef) (time.Duration, error) {
+	if cert.Spec.ExpiresAt.IsZero() {
+		return 0, nil
+	}
+	setCertificateMetrics(cert)
+
+	within := certExpiryAlertPeriod(cc)
+	now := time.Now()
+	expiresIn := cert.Spec.ExpiresAt.Sub(now)
+	statusCopy := cert.Status.DeepCopy()
+
+	expired := cert.IsExpired(now)
+	if setCertificateCondition(cert, v1alpha1.CertificateExpired, expired, "Expired",
+		fmt.Sprintf("Certificate expired at %s", cert.Spec.ExpiresAt.Format(time.RFC3339))) {
+		r.Recorder.Eventf(cert, corev1.EventTypeWarning, "Expired", "Certificate for %s has expired", cert.Spec.Domain)
+	}
+
+	expiringSoon := cert.IsExpiringSoon(now, within)
+	daysLeft := int(expiresIn / day)
+	if setCertificateCondition(cert, v1alpha1.CertificateExpiringSoon, expiringSoon, "ExpiringSoon",
+		fmt.Sprintf("Certificate expires in %d days", daysLeft)) {
+		r.Recorder.Eventf(cert, corev1.EventTypeWarning, "ExpiringSoon", "Certificate for %s expires in %d days",
+			cert.Spec.Domain, daysLeft)
+	}
+
+	if !apiequality.Semantic.DeepEqual(statusCopy, &cert.Status) {
+		if err := r.Client.Status().Update(ctx, cert); err != nil {
+			return 0, err
+		}
+		r.Log.Info("Updated certificate status", "certificateref", cert.Name, "expired", expired,
+			"expiringSoon", expiringSoon)
+	}
+
+	// check again when crossing into the next state
+	switch {
+	case expired:
+		return 0, nil
+	case expiringSoon:
+		return expiresIn, nil
+	default:
+		return expiresIn - within, nil
+	}
+}
+
+// returns true when the condition has transitioned into true
+func setCertificateCondition(cert *v1alpha1.CertificateRef, condType v1alpha1.CertificateConditionType, active bool, reason, message string) bool {
+	status := corev1.ConditionFalse
+	if active {
+		status = corev1.ConditionTrue
+	} else {
+		reason = "Valid"
+		message = ""
+	}
+	return cert.Status.SetCondition(condType, status, reason, message) && active
+}
+
+func certExpiryAlertPeriod(cc *v1alpha1.ClusterConfig) time.Duration {
+	days := kprometheus.DefaultCertExpiryAlertDays
+	if val := cc.Spec.ComponentConfig[kprometheus.ComponentName][kprometheus.CertExpiryAlertDaysKey]; val != "" {
+		if parsed, err := strconv.Atoi(val); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * day
+}
+
+func setCertificateMetrics(cert *v1alpha1.CertificateRef) {
+	if prev, ok := certificateDomains.Load(cert.Name); ok && prev.(string) != cert.Spec.Domain {
+		certificateExpiry.DeleteLabelValues(cert.Name, prev.(string))
+	}
+	certificateDomains.Store(cert.Name, cert.Spec.Domain)
+	certificateExpiry.WithLabelValues(cert.Name, cert.Spec.Domain).Set(float64(cert.Spec.ExpiresAt.Unix()))
+}
+
+func removeCertificateMetrics(name string) {
+	if domain, ok := certificateDomains.Load(name); ok {
+		certificateExpiry.DeleteLabelValues(name, domain.(string))
+		certificateDomains.Delete(name)
+	}
+}
+
+// when prometheus is installed, have it scrape the operator and alert on expiring certificates
+func (r *CertificateRefReconciler) reconcileMonitoring(cc *v1alpha1.ClusterConfig) error {
+	if cc.GetComponentConfig(kprometheus.ComponentName) == nil {
+		return nil
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      operatorMetricsName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+				operatorSelectorLabel:   operatorSelectorValue,
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				operatorSelectorLabel: operatorSelectorValue,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       operatorMetricsPortName,
+					Port:       operatorMetricsPort,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(operatorMetricsPort),
+				},
+			},
+		},
+	}
+	if err := r.createServiceIfMissing(svc); err != nil {
+		return err
+	}
+
+	sm := &promv1.ServiceMonitor{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      operatorMetricsName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: promv1.ServiceMonitorSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					operatorSelectorLabel: operatorSelectorValue,
+				},
+			},
+			Endpoints: []promv1.Endpoint{
+				{
+					Port: operatorMetricsPortName,
+				},
+			},
+		},
+	}
+	op, err := resources.UpdateResource(r.Client, sm, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated operator ServiceMonitor")
+
+	rule := newCertificateExpiryRule(int(certExpiryAlertPeriod(cc) / day))
+	op, err = resources.UpdateResource(r.Client, rule, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated certificate PrometheusRule")
+	return nil
+}
+
+func newCertificateExpiryRule(days int) *promv1.PrometheusRule {
+	return &promv1.PrometheusRule{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      "konstellation-certificates",
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+				prometheusName:          k8sName,
+				"role":                  "alert-rules",
+			},
+		},
+		Spec: promv1.PrometheusRuleSpec{
+			Groups: []promv1.RuleGroup{
+				{
+					Name: "certificates",
+					Rules: []promv1.Rule{
+						{
+							Alert: "CertificateExpiringSoon",
+							Expr: intstr.FromString(fmt.Sprintf("%s - time() < 86400 * %d > 0",
+								certificateExpiryMetric, days)),
+							Labels: map[string]string{
+								"severity": "warning",
+							},
+							Annotations: map[string]string{
+								"message": fmt.Sprintf("Certificate {{ $labels.certificate }} for {{ $labels.domain }} expires in less than %d days.", days),
+							},
+						},
+						{
+							Alert: "CertificateExpired",
+							Expr:  intstr.FromString(fmt.Sprintf("%s - time() <= 0", certificateExpiryMetric)),
+							Labels: map[string]string{
+								"severity": "critical",
+							},
+							Annotations: map[string]string{
+								"message": "Certificate {{ $labels.certificate }} for {{ $labels.domain }} has expired.",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
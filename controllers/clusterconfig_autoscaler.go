@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/autoscaler"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update
+
+// re-applies autoscaler settings from ComponentConfig when they or the nodepools change
+func (r *ClusterConfigReconciler) reconcileAutoscaler(cc *v1alpha1.ClusterConfig) error {
+	ctx := context.Background()
+	compConf := cc.GetComponentConfig(autoscaler.ComponentName)
+	if compConf == nil {
+		// component not yet installed
+		return nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: autoscaler.Namespace, Name: autoscaler.DeploymentName}, deployment)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	nodepools, err := resources.GetNodepools(r.Client)
+	if err != nil {
+		return err
+	}
+
+	if autoscaler.Expander(compConf) == autoscaler.ExpanderPriority {
+		op, err := resources.UpdateResource(r.Client, autoscaler.PriorityConfigMap(cc, nodepools), nil, nil)
+		if err != nil {
+			return err
+		}
+		resources.LogUpdates(r.Log, op, "Updated autoscaler priorities")
+	} else if err = autoscaler.DeletePriorityConfigMap(r.Client); err != nil {
+		return err
+	}
+
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return nil
+	}
+	command := autoscaler.Command(cc, nodepools)
+	if apiequality.Semantic.DeepEqual(containers[0].Command, command) {
+		return nil
+	}
+	containers[0].Command = command
+	r.Log.Info("Updating cluster autoscaler", "command", command)
+	return r.Client.Update(ctx, deployment)
+}
@@ -28,10 +28,13 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
 	"github.com/k11n/konstellation/pkg/components/prometheus"
@@ -89,13 +92,36 @@ func (r *ClusterConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, erro
 		return ctrl.Result{}, err
 	}
 
+	if err := r.reconcileAutoscaler(cc); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if err := r.reconcilePrometheusAdapter(cc); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	return ctrl.Result{}, nil
 }
 
 func (r *ClusterConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	// autoscaler settings depend on nodepools
+	nodepoolWatcher := &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(mapObj handler.MapObject) []ctrl.Request {
+			var reqs []ctrl.Request
+			_ = resources.ForEach(r.Client, &v1alpha1.ClusterConfigList{}, func(item interface{}) error {
+				cc := item.(v1alpha1.ClusterConfig)
+				reqs = append(reqs, ctrl.Request{
+					NamespacedName: types.NamespacedName{Name: cc.Name},
+				})
+				return nil
+			})
+			return reqs
+		}),
+	}
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.ClusterConfig{}).
 		Owns(&promv1.Prometheus{}).
+		Watches(&source.Kind{Type: &v1alpha1.Nodepool{}}, nodepoolWatcher).
 		Complete(r)
 }
 
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/prometheusadapter"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+// re-generates the adapter's rules from ComponentConfig, restarting the adapter when they change
+func (r *ClusterConfigReconciler) reconcilePrometheusAdapter(cc *v1alpha1.ClusterConfig) error {
+	ctx := context.Background()
+	compConf := cc.GetComponentConfig(prometheusadapter.ComponentName)
+	if compConf == nil {
+		// component not yet installed
+		return nil
+	}
+
+	deployment := &appsv1.Deployment{}
+	err := r.Client.Get(ctx, client.ObjectKey{Namespace: prometheusadapter.Namespace, Name: prometheusadapter.DeploymentName}, deployment)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	cm := prometheusadapter.ConfigMap(compConf)
+	op, err := resources.UpdateResource(r.Client, cm, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated prometheus adapter rules")
+
+	hash := prometheusadapter.ConfigHash(cm)
+	if deployment.Spec.Template.Annotations[v1alpha1.ConfigHashLabel] == hash {
+		return nil
+	}
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = make(map[string]string)
+	}
+	deployment.Spec.Template.Annotations[v1alpha1.ConfigHashLabel] = hash
+	r.Log.Info("Restarting prometheus adapter with updated rules")
+	return r.Client.Update(ctx, deployment)
+}
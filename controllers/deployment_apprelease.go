@@ -49,6 +49,8 @@ func (r *DeploymentReconciler) reconcileAppReleases(ctx context.Context, at *v1a
 
 	// do we already have a release for this appTargetHash and configmap combination?
 	// if not we'd want to create a new release
+	// when reloading config live, config changes are applied to the existing release
+	liveReload := at.Spec.ConfigReload == v1alpha1.ConfigReloadLive
 	var existingRelease *v1alpha1.AppRelease
 	var releaseIdx int
 	for idx, ar := range releases {
@@ -57,7 +59,7 @@ func (r *DeploymentReconciler) reconcileAppReleases(ctx context.Context, at *v1a
 			continue
 		}
 
-		if configMap == nil || configMap.Name == ar.Spec.Config {
+		if configMap == nil || configMap.Name == ar.Spec.Config || liveReload {
 			existingRelease = ar
 			releaseIdx = idx
 			break
@@ -93,6 +95,19 @@ func (r *DeploymentReconciler) reconcileAppReleases(ctx context.Context, at *v1a
 		releasesCopy = append(releasesCopy, ar.DeepCopy())
 	}
 
+	// point the existing release to the new config, it'll be saved with the rest of the releases
+	if existingRelease != nil && liveReload && configMap != nil {
+		liveConfig := ""
+		if configMap.Name != existingRelease.Spec.Config {
+			liveConfig = configMap.Name
+		}
+		if existingRelease.Spec.LiveConfig != liveConfig {
+			r.Log.Info("config changed, reloading live", "appRelease", existingRelease.Name,
+				"configMap", configMap.Name)
+			existingRelease.Spec.LiveConfig = liveConfig
+		}
+	}
+
 	// determine target release and traffic split
 	res, err = r.deployReleases(ctx, at, releases)
 	if err != nil {
@@ -204,6 +219,15 @@ func (r *DeploymentReconciler) deployReleases(ctx context.Context, at *v1alpha1.
 	// TODO: don't deploy additional builds when outside of schedule
 	// TODO: when there are canaries, compute remaining percentage here
 	desiredInstances := at.DesiredInstances()
+	if desiredInstances > 0 && activeRelease != targetRelease && at.NeedsAutoscaler() {
+		// the active release is autoscaled on its share of traffic during the deploy. follow it so that
+		// the target release is ramped up to handle increases in traffic
+		instances := at.InstancesForTraffic(activeRelease.Status.NumDesired, activeRelease.Spec.TrafficPercentage)
+		if instances > desiredInstances {
+			logger.Info("Following autoscaled instances", "release", activeRelease.Name, "instances", instances)
+			desiredInstances = instances
+		}
+	}
 	targetTrafficPercentage := targetRelease.Spec.TrafficPercentage
 
 	increment := float32(rampIncrement)
@@ -361,12 +385,18 @@ func (r *DeploymentReconciler) deployReleases(ctx context.Context, at *v1alpha1.
 		at.Status.NumReady = activeRelease.Status.NumReady
 		at.Status.NumAvailable = activeRelease.Status.NumAvailable
 	}
+	if at.Status.NumAvailable == 0 {
+		at.Status.AvailableSince = nil
+	} else if at.Status.AvailableSince == nil {
+		now := metav1.Now()
+		at.Status.AvailableSince = &now
+	}
 	return
 }
 
 /**
- * Configure autoscaler for the active release. If active release has changed, then delete and recreate scaler
- * when active release and target release aren't the same, we will want to pause the scaler
+ * Configure autoscalers for releases receiving traffic. In the middle of a deploy, both the active and target
+ * releases are autoscaled within their share of traffic, and the target is kept at the instances it's ramped up to.
  * reconciler auto updates appTarget status with current number desired by scaler
  */
 func (r *DeploymentReconciler) reconcileAutoScaler(ctx context.Context, at *v1alpha1.AppTarget, releases []*v1alpha1.AppRelease) error {
@@ -380,8 +410,16 @@ func (r *DeploymentReconciler) reconcileAutoScaler(ctx context.Context, at *v1al
 		}
 	}
 
-	// only autoscale when not in the middle of a deploy
-	needsScaler := at.NeedsAutoscaler() && activeRelease != nil && targetRelease == nil
+	// scalers that should exist, by name
+	scalers := make(map[string]*autoscale.HorizontalPodAutoscaler)
+	if at.NeedsAutoscaler() && activeRelease != nil {
+		scaler := newAutoscalerForAppTarget(at, activeRelease)
+		scalers[scaler.Name] = scaler
+		if targetRelease != nil {
+			scaler = newAutoscalerForAppTarget(at, targetRelease)
+			scalers[scaler.Name] = scaler
+		}
+	}
 
 	// find all existing scalers
 	scalerList := autoscale.HorizontalPodAutoscalerList{}
@@ -390,39 +428,32 @@ func (r *DeploymentReconciler) reconcileAutoScaler(ctx context.Context, at *v1al
 		return err
 	}
 
-	// remove all the existing scalers and exit
-	if !needsScaler {
-		for _, scaler := range scalerList.Items {
-			r.Log.Info("Deleting unused autoscaler", "appTarget", at.Name)
-			if err = r.Client.Delete(ctx, &scaler); err != nil {
-				return err
-			}
+	// delete the other scalers on older releases
+	for _, s := range scalerList.Items {
+		if scalers[s.Name] != nil {
+			continue
+		}
+		r.Log.Info("Deleting unused autoscaler", "appTarget", at.Name, "release", s.Labels[resources.AppReleaseLabel])
+		if err := r.Client.Delete(ctx, &s); err != nil {
+			return err
 		}
-		return nil
 	}
 
-	scaler := newAutoscalerForAppTarget(at, activeRelease)
-	// see if any of the existing scalers match the current template
-	for _, s := range scalerList.Items {
-		if s.Labels[resources.AppReleaseLabel] != scaler.Labels[resources.AppReleaseLabel] {
-			// delete the other scalers on older releases
-			r.Log.Info("Deleting autoscaler for old releases", "appTarget", at.Name)
-			if err := r.Client.Delete(ctx, &s); err != nil {
-				return err
-			}
+	for _, scaler := range scalers {
+		op, err := resources.UpdateResource(r.Client, scaler, at, r.Scheme)
+		if err != nil {
+			return err
 		}
-	}
+		resources.LogUpdates(r.Log, op, "Updated autoscaler", "appTarget", at.Name,
+			"release", scaler.Labels[resources.AppReleaseLabel])
 
-	op, err := resources.UpdateResourceWithMerge(r.Client, scaler, at, r.Scheme)
-	if err != nil {
-		return err
+		// update status
+		if scaler.Labels[resources.AppReleaseLabel] == activeRelease.Name {
+			at.Status.LastScaledAt = scaler.Status.LastScaleTime
+		}
 	}
-	resources.LogUpdates(r.Log, op, "Updated autoscaler", "appTarget", at.Name)
 
-	// update status
-	at.Status.LastScaledAt = scaler.Status.LastScaleTime
-
-	return err
+	return nil
 }
 
 func appReleaseForTarget(at *v1alpha1.AppTarget, build *v1alpha1.Build, configMap *corev1.ConfigMap) *v1alpha1.AppRelease {
@@ -456,33 +487,63 @@ func appReleaseForTarget(at *v1alpha1.AppTarget, build *v1alpha1.Build, configMa
 }
 
 func newAutoscalerForAppTarget(at *v1alpha1.AppTarget, ar *v1alpha1.AppRelease) *autoscale.HorizontalPodAutoscaler {
-	minReplicas := at.Spec.Scale.Min
-	maxReplicas := at.Spec.Scale.Max
-	if minReplicas == 0 {
-		minReplicas = 1
-	}
-	if maxReplicas < minReplicas {
-		maxReplicas = minReplicas
+	minReplicas, maxReplicas := at.ScaleBoundsForTraffic(ar.Spec.TrafficPercentage)
+	if ar.Spec.Role == v1alpha1.ReleaseRoleTarget && ar.Spec.NumDesired > minReplicas {
+		// keep instances that the target release has been ramped up to
+		minReplicas = ar.Spec.NumDesired
+		if maxReplicas < minReplicas {
+			maxReplicas = minReplicas
+		}
 	}
 	var metrics []autoscale.MetricSpec
-	if at.Spec.Scale.TargetCPUUtilization > 0 {
-		metrics = append(metrics, autoscale.MetricSpec{
-			Type: autoscale.ResourceMetricSourceType,
-			Resource: &autoscale.ResourceMetricSource{
-				Name: corev1.ResourceCPU,
-				Target: autoscale.MetricTarget{
-					Type:               autoscale.UtilizationMetricType,
-					AverageUtilization: &at.Spec.Scale.TargetCPUUtilization,
+	if at.ScalesOnCPU() {
+		metrics = append(metrics, resourceMetricSpec(corev1.ResourceCPU, at.Spec.Scale.TargetCPUUtilization))
+	}
+	if at.ScalesOnMemory() {
+		metrics = append(metrics, resourceMetricSpec(corev1.ResourceMemory, at.Spec.Scale.TargetMemoryUtilization))
+	}
+	for _, m := range at.Spec.Scale.Metrics {
+		target := m.Target.DeepCopy()
+		// custom and external metrics are served by the prometheus adapter
+		if m.GetType() == v1alpha1.ScaleMetricExternal {
+			var selector *metav1.LabelSelector
+			if len(m.Selector) != 0 {
+				selector = &metav1.LabelSelector{MatchLabels: m.Selector}
+			}
+			metrics = append(metrics, autoscale.MetricSpec{
+				Type: autoscale.ExternalMetricSourceType,
+				External: &autoscale.ExternalMetricSource{
+					Metric: autoscale.MetricIdentifier{
+						Name:     m.Name,
+						Selector: selector,
+					},
+					Target: autoscale.MetricTarget{
+						Type:         autoscale.AverageValueMetricType,
+						AverageValue: &target,
+					},
 				},
-			},
-		})
+			})
+		} else {
+			metrics = append(metrics, autoscale.MetricSpec{
+				Type: autoscale.PodsMetricSourceType,
+				Pods: &autoscale.PodsMetricSource{
+					Metric: autoscale.MetricIdentifier{
+						Name: m.Name,
+					},
+					Target: autoscale.MetricTarget{
+						Type:         autoscale.AverageValueMetricType,
+						AverageValue: &target,
+					},
+				},
+			})
+		}
 	}
 
 	labels := labelsForAppTarget(at)
 	labels[resources.AppReleaseLabel] = ar.Name
 	autoscaler := autoscale.HorizontalPodAutoscaler{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-scaler", at.Spec.App),
+			Name:      autoscalerName(ar),
 			Namespace: at.TargetNamespace(),
 			Labels:    labels,
 		},
@@ -495,7 +556,26 @@ func newAutoscalerForAppTarget(at *v1alpha1.AppTarget, ar *v1alpha1.AppRelease)
 			MinReplicas: &minReplicas,
 			MaxReplicas: maxReplicas,
 			Metrics:     metrics,
+			Behavior:    at.Spec.Scale.ToHPABehavior(),
 		},
 	}
 	return &autoscaler
 }
+
+// each release has its own scaler, named after it
+func autoscalerName(ar *v1alpha1.AppRelease) string {
+	return fmt.Sprintf("%s-scaler", ar.Name)
+}
+
+func resourceMetricSpec(name corev1.ResourceName, utilization int32) autoscale.MetricSpec {
+	return autoscale.MetricSpec{
+		Type: autoscale.ResourceMetricSourceType,
+		Resource: &autoscale.ResourceMetricSource{
+			Name: name,
+			Target: autoscale.MetricTarget{
+				Type:               autoscale.UtilizationMetricType,
+				AverageUtilization: &utilization,
+			},
+		},
+	}
+}
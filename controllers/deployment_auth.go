@@ -0,0 +1,170 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	securityapi "istio.io/api/security/v1beta1"
+	istiotype "istio.io/api/type/v1beta1"
+	istiosecurity "istio.io/client-go/pkg/apis/security/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	authorizationHeader = "Authorization"
+)
+
+// enforces OIDC in the mesh, so that requests to the ingress hosts can't bypass the ingress controller with
+// tokens. traffic from within the cluster uses service hostnames, and is allowed through.
+// basic auth is checked only by the ingress controller
+func (r *DeploymentReconciler) reconcileIngressAuth(ctx context.Context, at *v1alpha1.AppTarget) error {
+	cc, err := resources.GetClusterConfig(r.Client)
+	if err != nil {
+		return err
+	}
+	controller := ingress.ControllerForCluster(cc)
+	oidc := meshOIDCForTarget(at, controller)
+
+	ra := &istiosecurity.RequestAuthentication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      at.Spec.App,
+		},
+	}
+	if oidc != nil {
+		ra = newRequestAuthentication(at, oidc, controller == ingress.ControllerALB)
+		op, err := resources.UpdateResource(r.Client, ra, at, r.Scheme)
+		if err != nil {
+			return err
+		}
+		resources.LogUpdates(r.Log, op, "Updated RequestAuthentication", "appTarget", at.Name)
+	} else if err = r.deleteIfExists(ctx, ra); err != nil {
+		return err
+	}
+
+	ap := &istiosecurity.AuthorizationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      at.Spec.App,
+		},
+	}
+	if oidc == nil {
+		return r.deleteIfExists(ctx, ap)
+	}
+
+	ap = newAuthorizationPolicy(at, oidc)
+	op, err := resources.UpdateResource(r.Client, ap, at, r.Scheme)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated AuthorizationPolicy", "appTarget", at.Name)
+	return nil
+}
+
+func (r *DeploymentReconciler) deleteIfExists(ctx context.Context, obj runtime.Object) error {
+	key, err := client.ObjectKeyFromObject(obj)
+	if err != nil {
+		return err
+	}
+	if err = r.Client.Get(ctx, key, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	r.Log.Info("Deleting unneeded resource", "name", key.Name)
+	return r.Client.Delete(ctx, obj)
+}
+
+// returns the OIDC config that's enforced in the mesh. ALB logs users in itself, and passes on the access token
+// from the issuer instead of a bearer token. many issuers' access tokens are opaque, so they could be validated
+// only when the issuer is known to issue JWTs. otherwise requests are authenticated by ALB alone
+func meshOIDCForTarget(at *v1alpha1.AppTarget, controller string) *v1alpha1.OIDCAuth {
+	if !at.NeedsIngress() || at.Spec.Ingress.Auth == nil || at.Spec.Ingress.Auth.OIDC == nil {
+		return nil
+	}
+	oidc := at.Spec.Ingress.Auth.OIDC
+	if controller == ingress.ControllerALB && !oidc.JWTAccessTokens {
+		return nil
+	}
+	return oidc
+}
+
+// tokens are read from the Authorization header, and from the header that ALB passes the access token in
+func newRequestAuthentication(at *v1alpha1.AppTarget, oidc *v1alpha1.OIDCAuth, alb bool) *istiosecurity.RequestAuthentication {
+	headers := []*securityapi.JWTHeader{
+		{
+			Name:   authorizationHeader,
+			Prefix: "Bearer ",
+		},
+	}
+	if alb {
+		headers = append(headers, &securityapi.JWTHeader{
+			Name: ingress.ALBAccessTokenHeader,
+		})
+	}
+	return &istiosecurity.RequestAuthentication{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      at.Spec.App,
+			Labels:    labelsForAppTarget(at),
+		},
+		Spec: securityapi.RequestAuthentication{
+			Selector: &istiotype.WorkloadSelector{
+				MatchLabels: selectorsForAppTarget(at),
+			},
+			JwtRules: []*securityapi.JWTRule{
+				{
+					Issuer:               oidc.Issuer,
+					FromHeaders:          headers,
+					ForwardOriginalToken: true,
+				},
+			},
+		},
+	}
+}
+
+// requests to the ingress hosts need a token from the issuer
+func newAuthorizationPolicy(at *v1alpha1.AppTarget, oidc *v1alpha1.OIDCAuth) *istiosecurity.AuthorizationPolicy {
+	hosts := at.Spec.Ingress.Hosts
+	spec := securityapi.AuthorizationPolicy{
+		Selector: &istiotype.WorkloadSelector{
+			MatchLabels: selectorsForAppTarget(at),
+		},
+		Action: securityapi.AuthorizationPolicy_ALLOW,
+		Rules: []*securityapi.Rule{
+			// requests to other hosts come from within the cluster
+			{
+				To: []*securityapi.Rule_To{
+					{Operation: &securityapi.Operation{NotHosts: hosts}},
+				},
+			},
+			{
+				From: []*securityapi.Rule_From{
+					{Source: &securityapi.Source{
+						RequestPrincipals: []string{strings.TrimSuffix(oidc.Issuer, "/") + "/*"},
+					}},
+				},
+				To: []*securityapi.Rule_To{
+					{Operation: &securityapi.Operation{Hosts: hosts}},
+				},
+			},
+		},
+	}
+
+	return &istiosecurity.AuthorizationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      at.Spec.App,
+			Labels:    labelsForAppTarget(at),
+		},
+		Spec: spec,
+	}
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	securityapi "istio.io/api/security/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+)
+
+func newAuthTarget(oidc *v1alpha1.OIDCAuth) *v1alpha1.AppTarget {
+	at := &v1alpha1.AppTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "admin-production",
+		},
+		Spec: v1alpha1.AppTargetSpec{
+			App:    "admin",
+			Target: "production",
+		},
+	}
+	at.Spec.Ingress = &v1alpha1.IngressConfig{
+		Hosts: []string{"admin.example.com"},
+		Auth: &v1alpha1.IngressAuth{
+			OIDC: oidc,
+		},
+	}
+	return at
+}
+
+func TestMeshOIDCForTarget(t *testing.T) {
+	oidc := &v1alpha1.OIDCAuth{
+		Issuer:           "https://accounts.example.com/",
+		ClientID:         "client",
+		ClientSecretName: "admin-oidc",
+	}
+	at := newAuthTarget(oidc)
+
+	// bearer tokens are required with NGINX
+	assert.Equal(t, oidc, meshOIDCForTarget(at, ingress.ControllerNginx))
+
+	// ALB access tokens could be opaque, requests carrying only the ALB header are let through
+	assert.Nil(t, meshOIDCForTarget(at, ingress.ControllerALB))
+
+	oidc.JWTAccessTokens = true
+	assert.Equal(t, oidc, meshOIDCForTarget(at, ingress.ControllerALB))
+
+	// basic auth is checked by the ingress controller only
+	at.Spec.Ingress.Auth = &v1alpha1.IngressAuth{BasicAuthSecret: "credentials"}
+	assert.Nil(t, meshOIDCForTarget(at, ingress.ControllerNginx))
+}
+
+func TestALBAccessTokenPolicies(t *testing.T) {
+	oidc := &v1alpha1.OIDCAuth{
+		Issuer:           "https://accounts.example.com/",
+		ClientID:         "client",
+		ClientSecretName: "admin-oidc",
+		JWTAccessTokens:  true,
+	}
+	at := newAuthTarget(oidc)
+
+	// with ALB, the access token header is enough to authenticate the request
+	ra := newRequestAuthentication(at, oidc, true)
+	assert.Len(t, ra.Spec.JwtRules, 1)
+	assert.Equal(t, oidc.Issuer, ra.Spec.JwtRules[0].Issuer)
+	assert.Equal(t, []*securityapi.JWTHeader{
+		{Name: authorizationHeader, Prefix: "Bearer "},
+		{Name: ingress.ALBAccessTokenHeader},
+	}, ra.Spec.JwtRules[0].FromHeaders)
+
+	ap := newAuthorizationPolicy(at, oidc)
+	assert.Equal(t, securityapi.AuthorizationPolicy_ALLOW, ap.Spec.Action)
+	assert.Len(t, ap.Spec.Rules, 2)
+	assert.Equal(t, []string{"admin.example.com"}, ap.Spec.Rules[0].To[0].Operation.NotHosts)
+	tokenRule := ap.Spec.Rules[1]
+	assert.Equal(t, []string{"https://accounts.example.com/*"}, tokenRule.From[0].Source.RequestPrincipals)
+	assert.Equal(t, []string{"admin.example.com"}, tokenRule.To[0].Operation.Hosts)
+	// the principal is all that's required, not a particular header
+	assert.Empty(t, tokenRule.When)
+
+	// the header isn't a source of tokens with other ingress controllers
+	ra = newRequestAuthentication(at, oidc, false)
+	assert.Equal(t, []*securityapi.JWTHeader{
+		{Name: authorizationHeader, Prefix: "Bearer "},
+	}, ra.Spec.JwtRules[0].FromHeaders)
+}
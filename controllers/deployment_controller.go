@@ -18,11 +18,15 @@ package controllers
 
 import (
 	"context"
+	"time"
 
 	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/go-logr/logr"
+	promapi "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/thoas/go-funk"
+	istiov1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	istiosecurity "istio.io/client-go/pkg/apis/security/v1beta1"
 	autoscale "k8s.io/api/autoscaling/v2beta2"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -44,12 +48,20 @@ type DeploymentReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// queries requests to targets that scale to zero, and usage for recommendations
+	Prometheus promapi.API
+	// keeps resource recommendations up to date on AppTarget status
+	RecommendResources bool
+	// set on requests routed to the activator, which rejects requests without it
+	ActivatorToken string
 }
 
 // +kubebuilder:rbac:groups=k11n.dev,resources=appconfigs;apptargets;appreleases;builds;ingressrequests,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k11n.dev,resources=apptargets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=configmaps;services,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=networking.istio.io,resources=destinationrules;gateways;virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=destinationrules;envoyfilters;gateways;virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=security.istio.io,resources=authorizationpolicies;requestauthentications,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 // +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=prometheusrules;servicemonitors;podmonitors,verbs=get;list;watch;create;update;patch;delete
 
@@ -71,6 +83,20 @@ func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err
 	// copy status to detect changes
 	atStatus := at.Status.DeepCopy()
 
+	// schedules are evaluated once, so the releases and autoscalers agree on the bounds
+	now := time.Now()
+	at.ResolveScaleBounds(now)
+
+	idleRes, err := r.reconcileIdle(ctx, at)
+	if err != nil {
+		return
+	}
+	res.RequeueAfter = idleRes
+
+	if wait := r.reconcileRecommendation(ctx, at); wait != 0 && (res.RequeueAfter == 0 || wait < res.RequeueAfter) {
+		res.RequeueAfter = wait
+	}
+
 	// figure out configs
 	configMap, err := r.reconcileConfigMap(ctx, at)
 	if err != nil {
@@ -86,7 +112,7 @@ func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err
 		if arRes.Requeue {
 			res.Requeue = arRes.Requeue
 		}
-		if arRes.RequeueAfter != 0 {
+		if arRes.RequeueAfter != 0 && (res.RequeueAfter == 0 || arRes.RequeueAfter < res.RequeueAfter) {
 			res.RequeueAfter = arRes.RequeueAfter
 		}
 	}
@@ -97,12 +123,24 @@ func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err
 		return
 	}
 
+	// check again when the scale schedule changes
+	if len(at.Spec.Scale.Schedules) != 0 {
+		wait := at.Spec.Scale.NextScheduleChange(now, time.Hour).Sub(now) + time.Second
+		if res.RequeueAfter == 0 || wait < res.RequeueAfter {
+			res.RequeueAfter = wait
+		}
+	}
+
 	// reconcile Service
 	service, err := r.reconcileService(ctx, at)
 	if err != nil {
 		return
 	}
 
+	if err = r.reconcileExposedService(ctx, at); err != nil {
+		return
+	}
+
 	// reconcile prometheus setup
 	if err = r.reconcilePrometheusServiceMonitor(ctx, at); err != nil {
 		return
@@ -125,6 +163,31 @@ func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err
 		return
 	}
 
+	err = r.reconcileMaintenancePages(at)
+	if err != nil {
+		return
+	}
+
+	err = r.reconcileIngressVirtualService(ctx, at, service, activeReleases)
+	if err != nil {
+		return
+	}
+
+	err = r.reconcileIngressAuth(ctx, at)
+	if err != nil {
+		return
+	}
+
+	err = r.reconcileRateLimit(ctx, at)
+	if err != nil {
+		return
+	}
+
+	err = r.reconcileErrorPages(ctx, at)
+	if err != nil {
+		return
+	}
+
 	err = r.reconcileIngressRequest(ctx, at)
 	if err != nil {
 		return
@@ -138,6 +201,11 @@ func (r *DeploymentReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err
 		if err != nil {
 			return
 		}
+		// the activator could have woken up the target in the meantime
+		if activatedAt := at.Status.ActivatedAt; activatedAt != nil &&
+			(status.ActivatedAt == nil || activatedAt.After(status.ActivatedAt.Time)) {
+			status.ActivatedAt = activatedAt
+		}
 		at.Status = status
 		err = r.Client.Status().Update(ctx, at)
 	}
@@ -198,6 +266,9 @@ func (r *DeploymentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&v1alpha1.AppRelease{}).
 		Owns(&corev1.Service{}).
 		Owns(&istio.VirtualService{}).
+		Owns(&istiosecurity.AuthorizationPolicy{}).
+		Owns(&istiosecurity.RequestAuthentication{}).
+		Owns(&istiov1alpha3.EnvoyFilter{}).
 		Owns(&autoscale.HorizontalPodAutoscaler{}).
 		Owns(&v1alpha1.IngressRequest{}).
 		Watches(&source.Kind{Type: &v1alpha1.AppConfig{}}, configWatcher).
@@ -210,6 +281,11 @@ func (r *DeploymentReconciler) reconcileConfigMap(ctx context.Context, at *v1alp
 	if err != nil {
 		return
 	}
+	if ac != nil {
+		if err = resources.RenderConfig(r.Client, ac, at.Spec.App, at.Spec.Target); err != nil {
+			return
+		}
+	}
 
 	// find other configmaps
 	sharedConfigs := make([]*v1alpha1.AppConfig, 0, len(at.Spec.Configs))
@@ -221,6 +297,12 @@ func (r *DeploymentReconciler) reconcileConfigMap(ctx context.Context, at *v1alp
 				"target", at.Spec.Target, "config", config)
 			continue
 		}
+		if sc == nil {
+			continue
+		}
+		if err = resources.RenderConfig(r.Client, sc, at.Spec.App, at.Spec.Target); err != nil {
+			return
+		}
 		sharedConfigs = append(sharedConfigs, sc)
 	}
 
@@ -229,7 +311,10 @@ func (r *DeploymentReconciler) reconcileConfigMap(ctx context.Context, at *v1alp
 		// no config maps needed
 		return
 	}
-	configMap = resources.CreateConfigMap(at.Spec.App, ac, sharedConfigs)
+	configMap, err = resources.CreateConfigMap(at.Spec.App, ac, sharedConfigs, at.Spec.ConfigEnv)
+	if err != nil {
+		return
+	}
 	for key, val := range labelsForAppTarget(at) {
 		configMap.Labels[key] = val
 	}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	awsLoadBalancerTypeAnnotation = "service.beta.kubernetes.io/aws-load-balancer-type"
+)
+
+// exposes ports through a LoadBalancer Service, for protocols that can't go through the HTTP ingress
+func (r *DeploymentReconciler) reconcileExposedService(ctx context.Context, at *v1alpha1.AppTarget) error {
+	if !at.NeedsExpose() {
+		at.Status.ExposedAddress = ""
+		existing, err := resources.GetService(r.Client, at.TargetNamespace(), exposedServiceName(at))
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		// could be the Service of another app with a similar name
+		if !metav1.IsControlledBy(existing, at) {
+			return nil
+		}
+		r.Log.Info("Deleting unneeded exposed Service", "appTarget", at.Name)
+		return r.Client.Delete(ctx, existing)
+	}
+
+	cc, err := resources.GetClusterConfig(r.Client)
+	if err != nil {
+		return err
+	}
+	svc := newExposedService(at, cc.Spec.Cloud)
+	op, err := resources.UpdateResourceWithMerge(r.Client, svc, at, r.Scheme)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated exposed Service", "appTarget", at.Name)
+
+	// address is reported in status once the load balancer is provisioned
+	existing, err := resources.GetService(r.Client, svc.Namespace, svc.Name)
+	if err != nil {
+		return err
+	}
+	at.Status.ExposedAddress = ""
+	for _, lb := range existing.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			at.Status.ExposedAddress = lb.Hostname
+			break
+		} else if lb.IP != "" {
+			at.Status.ExposedAddress = lb.IP
+			break
+		}
+	}
+	// without an ingress, the load balancer is how the target is reached
+	if !at.NeedsIngress() {
+		at.Status.Hostname = at.Status.ExposedAddress
+	}
+	return nil
+}
+
+func exposedServiceName(at *v1alpha1.AppTarget) string {
+	return at.Spec.App + "-external"
+}
+
+func newExposedService(at *v1alpha1.AppTarget, cloud string) *corev1.Service {
+	annotations := map[string]string{}
+	if cloud == "aws" {
+		// NLBs handle both TCP and UDP, and keep connections open for long-lived clients
+		annotations[awsLoadBalancerTypeAnnotation] = "nlb"
+	}
+	for key, val := range at.Spec.Expose.Annotations {
+		annotations[key] = val
+	}
+
+	var ports []corev1.ServicePort
+	for _, p := range at.Spec.Expose.GetPorts(at.Spec.Ports) {
+		ports = append(ports, corev1.ServicePort{
+			Name:       p.Name,
+			Protocol:   p.GetProtocol(),
+			Port:       p.Port,
+			TargetPort: intstr.FromInt(int(p.Port)),
+		})
+	}
+
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   at.TargetNamespace(),
+			Name:        exposedServiceName(at),
+			Labels:      labelsForAppTarget(at),
+			Annotations: annotations,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:     corev1.ServiceTypeLoadBalancer,
+			Ports:    ports,
+			Selector: selectorsForAppTarget(at),
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestReconcileExposedServiceHostname(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, clientgoscheme.AddToScheme(scheme))
+	assert.NoError(t, v1alpha1.AddToScheme(scheme))
+
+	at := &v1alpha1.AppTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "broker-production",
+		},
+		Spec: v1alpha1.AppTargetSpec{
+			App:    "broker",
+			Target: "production",
+		},
+	}
+	at.Spec.Ports = []v1alpha1.PortSpec{{Name: "mqtt", Port: 1883, Protocol: corev1.ProtocolTCP}}
+	at.Spec.Expose = &v1alpha1.ExposeConfig{Ports: []string{"mqtt"}}
+
+	svc := newExposedService(at, "aws")
+	svc.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "broker.elb.amazonaws.com"}}
+	cc := &v1alpha1.ClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+	}
+	cc.Spec.Cloud = "aws"
+	r := &DeploymentReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, cc, svc),
+		Log:    ctrl.Log.WithName("test"),
+		Scheme: scheme,
+	}
+
+	// without an ingress, the load balancer is the target's hostname
+	assert.NoError(t, r.reconcileExposedService(context.TODO(), at))
+	assert.Equal(t, "broker.elb.amazonaws.com", at.Status.ExposedAddress)
+	assert.Equal(t, "broker.elb.amazonaws.com", at.Status.Hostname)
+
+	// the ingress address is kept otherwise
+	at.Spec.Ingress = &v1alpha1.IngressConfig{Hosts: []string{"broker.example.com"}}
+	at.Status.Hostname = "ingress.elb.amazonaws.com"
+	assert.NoError(t, r.reconcileExposedService(context.TODO(), at))
+	assert.Equal(t, "broker.elb.amazonaws.com", at.Status.ExposedAddress)
+	assert.Equal(t, "ingress.elb.amazonaws.com", at.Status.Hostname)
+}
@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	istionetworking "istio.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/components/prometheus"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	idleCheckInterval = time.Minute
+	// Prometheus is queried while reconciling, so a slow query shouldn't hold up the target
+	prometheusQueryTimeout = 30 * time.Second
+)
+
+// scales targets to zero when they haven't received requests within the idle timeout, and back up once requests
+// arrive at the activator. returns how long to wait before checking again
+func (r *DeploymentReconciler) reconcileIdle(ctx context.Context, at *v1alpha1.AppTarget) (time.Duration, error) {
+	if !at.ScalesToZero() {
+		at.Status.IdleSince = nil
+		return 0, nil
+	}
+
+	activatedAt := at.Status.ActivatedAt
+	if at.Status.IdleSince != nil {
+		if activatedAt != nil && activatedAt.After(at.Status.IdleSince.Time) {
+			r.Log.Info("Activating idle target", "appTarget", at.Name)
+			at.Status.IdleSince = nil
+		}
+		return 0, nil
+	}
+
+	// give the target time to receive requests after it's been deployed or activated, and instances are available
+	if at.Status.AvailableSince == nil {
+		return idleCheckInterval, nil
+	}
+	now := time.Now()
+	since := at.Status.DeployUpdatedAt.Time
+	if activatedAt != nil && activatedAt.After(since) {
+		since = activatedAt.Time
+	}
+	if at.Status.AvailableSince.After(since) {
+		since = at.Status.AvailableSince.Time
+	}
+	timeout := at.Spec.Scale.IdleTimeout.Duration
+	if wait := since.Add(timeout).Sub(now); wait > 0 {
+		return wait, nil
+	}
+
+	if r.Prometheus == nil {
+		return 0, nil
+	}
+	queryCtx, cancel := context.WithTimeout(ctx, prometheusQueryTimeout)
+	defer cancel()
+	query := prometheus.ServiceRequestsQuery(at.TargetNamespace(), at.Spec.App, timeout)
+	requests, err := prometheus.QueryScalar(queryCtx, r.Prometheus, query, now)
+	if err == prometheus.ErrNoSeries {
+		// Istio hasn't reported on the service, it can't be told whether it's idle
+		r.Log.Info("No request metrics for target, keeping it running", "appTarget", at.Name)
+		return idleCheckInterval, nil
+	}
+	if err != nil {
+		// keep the target running when it's unknown
+		r.Log.Error(err, "Could not check requests of target", "appTarget", at.Name)
+		return idleCheckInterval, nil
+	}
+	if requests > 0 {
+		return idleCheckInterval, nil
+	}
+
+	r.Log.Info("Scaling idle target to zero", "appTarget", at.Name, "idleTimeout", timeout)
+	at.Status.IdleSince = &metav1.Time{Time: now}
+	return 0, nil
+}
+
+// requests to idle targets are held by the activator in the operator, which wakes up the target
+// and proxies them to it once it's available
+func (r *DeploymentReconciler) routeToActivator(at *v1alpha1.AppTarget, route *istionetworking.HTTPRoute, port int32) {
+	route.Route = []*istionetworking.HTTPRouteDestination{
+		{
+			Destination: &istionetworking.Destination{
+				Host: resources.ServiceHostname(resources.KonSystemNamespace, resources.MaintenancePagesServiceName),
+				Port: &istionetworking.PortSelector{Number: 80},
+			},
+		},
+	}
+	route.Headers = &istionetworking.Headers{
+		Request: &istionetworking.Headers_HeaderOperations{
+			Set: map[string]string{
+				ingress.ActivateTargetHeader: at.Name,
+				ingress.ActivatePortHeader:   fmt.Sprintf("%d", port),
+				ingress.ActivateTokenHeader:  r.ActivatorToken,
+			},
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/prometheus"
+)
+
+func TestReconcileIdle(t *testing.T) {
+	var response string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+	qapi, err := prometheus.NewQueryAPI(server.URL)
+	assert.NoError(t, err)
+	r := &DeploymentReconciler{
+		Log:        ctrl.Log.WithName("test"),
+		Prometheus: qapi,
+	}
+
+	newIdleTarget := func() *v1alpha1.AppTarget {
+		availableSince := metav1.NewTime(time.Now().Add(-time.Hour))
+		at := &v1alpha1.AppTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-staging"},
+			Spec: v1alpha1.AppTargetSpec{
+				App:    "web",
+				Target: "staging",
+			},
+			Status: v1alpha1.AppTargetStatus{
+				DeployUpdatedAt: availableSince,
+				AvailableSince:  &availableSince,
+			},
+		}
+		at.Spec.Ports = []v1alpha1.PortSpec{{Name: "http", Port: 8080}}
+		at.Spec.Scale.IdleTimeout = &metav1.Duration{Duration: 15 * time.Minute}
+		return at
+	}
+
+	// without request metrics, it's unknown whether the target is idle
+	response = `{"status":"success","data":{"resultType":"vector","result":[]}}`
+	at := newIdleTarget()
+	wait, err := r.reconcileIdle(context.TODO(), at)
+	assert.NoError(t, err)
+	assert.Equal(t, idleCheckInterval, wait)
+	assert.Nil(t, at.Status.IdleSince)
+
+	response = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614556800,"3"]}]}}`
+	wait, err = r.reconcileIdle(context.TODO(), at)
+	assert.NoError(t, err)
+	assert.Equal(t, idleCheckInterval, wait)
+	assert.Nil(t, at.Status.IdleSince)
+
+	// scaled to zero once there haven't been any requests
+	response = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614556800,"0"]}]}}`
+	wait, err = r.reconcileIdle(context.TODO(), at)
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	assert.NotNil(t, at.Status.IdleSince)
+}
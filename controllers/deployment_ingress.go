@@ -2,12 +2,19 @@ package controllers
 
 import (
 	"context"
+	"regexp"
+	"strings"
 
+	"github.com/gogo/protobuf/types"
+	istionetworking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ingress"
 	"github.com/k11n/konstellation/pkg/resources"
 )
 
@@ -65,9 +72,11 @@ func (r *DeploymentReconciler) ingressRequestForAppTarget(at *v1alpha1.AppTarget
 	}
 	if at.Spec.Ingress != nil {
 		ir.Spec.Hosts = at.Spec.Ingress.Hosts
-		ir.Spec.Paths = at.Spec.Ingress.Paths
+		ir.Spec.Paths = at.Spec.Ingress.GetPaths()
 		ir.Spec.RequireHTTPS = at.Spec.Ingress.RequireHTTPS
 		ir.Spec.Annotations = at.Spec.Ingress.Annotations
+		ir.Spec.Auth = at.Spec.Ingress.Auth
+		ir.Spec.Visibility = at.Spec.Ingress.Visibility
 
 		if at.Spec.Ingress.Port == "grpc" {
 			ir.Spec.AppProtocol = "grpc"
@@ -75,3 +84,226 @@ func (r *DeploymentReconciler) ingressRequestForAppTarget(at *v1alpha1.AppTarget
 	}
 	return ir
 }
+
+// ingress traffic is routed through a VirtualService for each host, which the IngressRequest controller manages.
+// it delegates paths claimed by the app to this one
+func (r *DeploymentReconciler) reconcileIngressVirtualService(ctx context.Context, at *v1alpha1.AppTarget, service *corev1.Service, releases []*v1alpha1.AppRelease) error {
+	vs := r.newIngressVirtualService(at, service, releases)
+	needed := vs != nil
+
+	if vs == nil {
+		vs = &istio.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: at.TargetNamespace(),
+				Name:      at.ScopedName(),
+			},
+		}
+	}
+	existing := &istio.VirtualService{}
+	key, err := client.ObjectKeyFromObject(vs)
+	if err != nil {
+		return err
+	}
+	err = r.Client.Get(ctx, key, existing)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			if !needed {
+				return nil
+			}
+		} else {
+			return err
+		}
+	}
+
+	if !needed {
+		r.Log.Info("Deleting unneeded ingress virtual service", "appTarget", at.Name)
+		return r.Client.Delete(ctx, existing)
+	}
+
+	op, err := resources.UpdateResource(r.Client, vs, at, r.Scheme)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated ingress VirtualService", "appTarget", at.Name)
+	return nil
+}
+
+// returns a delegate VirtualService that routes ingress paths to the active releases, or nil when it's not needed
+func (r *DeploymentReconciler) newIngressVirtualService(at *v1alpha1.AppTarget, service *corev1.Service, releases []*v1alpha1.AppRelease) *istio.VirtualService {
+	if service == nil || !at.NeedsIngress() {
+		return nil
+	}
+
+	// map the desired port to 80
+	targetPort := ingressTargetPort(at)
+	if targetPort == 0 {
+		return nil
+	}
+
+	svcHost := resources.ServiceHostname(service.Namespace, service.Name)
+	var destinations []*istionetworking.HTTPRouteDestination
+	for _, ar := range releases {
+		destinations = append(destinations, &istionetworking.HTTPRouteDestination{
+			Destination: &istionetworking.Destination{
+				Host:   svcHost,
+				Port:   &istionetworking.PortSelector{Number: uint32(targetPort)},
+				Subset: ar.Name,
+			},
+			Weight: ar.Spec.TrafficPercentage,
+		})
+	}
+
+	corsPolicy := newCorsPolicy(at.Spec.Ingress.CORS)
+	var routes []*istionetworking.HTTPRoute
+	for _, ingressRoute := range at.Spec.Ingress.GetRoutes() {
+		path := ingressRoute.Path
+		rewrite := ingressRoute.GetRewrite()
+		if rewrite == "" || rewrite == path {
+			route := &istionetworking.HTTPRoute{
+				Route:      destinations,
+				CorsPolicy: corsPolicy,
+			}
+			// root is the default route, which doesn't need a match
+			if path != "/" {
+				route.Match = []*istionetworking.HTTPMatchRequest{
+					{
+						Uri: &istionetworking.StringMatch{
+							MatchType: &istionetworking.StringMatch_Prefix{Prefix: path},
+						},
+					},
+				}
+			}
+			routes = append(routes, route)
+			continue
+		}
+
+		// prefixes are replaced as is, match with the trailing slash to avoid a double slash in the rewritten path
+		prefix := strings.TrimSuffix(path, "/") + "/"
+		rewritePrefix := strings.TrimSuffix(rewrite, "/") + "/"
+		routes = append(routes, &istionetworking.HTTPRoute{
+			Match: []*istionetworking.HTTPMatchRequest{
+				{
+					Uri: &istionetworking.StringMatch{
+						MatchType: &istionetworking.StringMatch_Prefix{Prefix: prefix},
+					},
+				},
+			},
+			Rewrite:    &istionetworking.HTTPRewrite{Uri: rewritePrefix},
+			Route:      destinations,
+			CorsPolicy: corsPolicy,
+		})
+		if path != "/" {
+			routes = append(routes, &istionetworking.HTTPRoute{
+				Match: []*istionetworking.HTTPMatchRequest{
+					{
+						Uri: &istionetworking.StringMatch{
+							MatchType: &istionetworking.StringMatch_Exact{Exact: path},
+						},
+					},
+				},
+				Rewrite:    &istionetworking.HTTPRewrite{Uri: rewrite},
+				Route:      destinations,
+				CorsPolicy: corsPolicy,
+			})
+		}
+	}
+
+	// paths are still matched and rewritten, the activator proxies requests once the app is up
+	if at.IsIdle() {
+		for _, route := range routes {
+			r.routeToActivator(at, route, targetPort)
+		}
+	}
+
+	// the page is served in place of the app, for all of its paths
+	if at.InMaintenance() {
+		routes = []*istionetworking.HTTPRoute{newMaintenanceRoute(at)}
+	}
+
+	// delegates can't declare hosts or gateways, those are set on the VirtualService for each host
+	return &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      at.ScopedName(),
+			Labels:    labelsForAppTarget(at),
+		},
+		Spec: istionetworking.VirtualService{
+			Http: routes,
+		},
+	}
+}
+
+// routes requests to the operator, which serves the maintenance page of the target
+func newMaintenanceRoute(at *v1alpha1.AppTarget) *istionetworking.HTTPRoute {
+	return &istionetworking.HTTPRoute{
+		Route: []*istionetworking.HTTPRouteDestination{
+			{
+				Destination: &istionetworking.Destination{
+					Host: resources.ServiceHostname(resources.KonSystemNamespace, resources.MaintenancePagesServiceName),
+					Port: &istionetworking.PortSelector{Number: 80},
+				},
+			},
+		},
+		Headers: &istionetworking.Headers{
+			Request: &istionetworking.Headers_HeaderOperations{
+				Set: map[string]string{
+					ingress.PageTargetHeader: at.Name,
+				},
+			},
+		},
+	}
+}
+
+// returns the app port that ingress traffic is routed to, defaults to the first port
+func ingressTargetPort(at *v1alpha1.AppTarget) int32 {
+	var targetPort int32
+	for _, port := range at.Spec.Ports {
+		if targetPort == 0 {
+			targetPort = port.Port
+		}
+		if port.Name == at.Spec.Ingress.Port {
+			return port.Port
+		}
+	}
+	return targetPort
+}
+
+// preflight requests are answered by the sidecar, and CORS headers are added to responses from the app
+func newCorsPolicy(cors *v1alpha1.CORSPolicy) *istionetworking.CorsPolicy {
+	if cors == nil {
+		return nil
+	}
+	policy := &istionetworking.CorsPolicy{
+		AllowMethods:  cors.AllowMethods,
+		AllowHeaders:  cors.AllowHeaders,
+		ExposeHeaders: cors.ExposeHeaders,
+	}
+	for _, origin := range cors.AllowOrigins {
+		policy.AllowOrigins = append(policy.AllowOrigins, originMatch(origin))
+	}
+	if cors.MaxAge > 0 {
+		policy.MaxAge = &types.Duration{Seconds: int64(cors.MaxAge)}
+	}
+	if cors.AllowCredentials {
+		policy.AllowCredentials = &types.BoolValue{Value: true}
+	}
+	return policy
+}
+
+// origins with a wildcard match any characters other than a slash in its place, * alone matches all origins
+func originMatch(origin string) *istionetworking.StringMatch {
+	if origin == "*" {
+		return &istionetworking.StringMatch{
+			MatchType: &istionetworking.StringMatch_Regex{Regex: ".*"},
+		}
+	}
+	if !strings.Contains(origin, "*") {
+		return &istionetworking.StringMatch{
+			MatchType: &istionetworking.StringMatch_Exact{Exact: origin},
+		}
+	}
+	pattern := strings.Replace(regexp.QuoteMeta(origin), `\*`, `[^/]*`, 1)
+	return &istionetworking.StringMatch{
+		MatchType: &istionetworking.StringMatch_Regex{Regex: pattern},
+	}
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+
+	istio "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	luaFilter = "envoy.filters.http.lua"
+	luaType   = "type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua"
+)
+
+// maintenance pages are served by the operator, since the app may not be running.
+// requests to idle targets are held by the operator as well
+func (r *DeploymentReconciler) reconcileMaintenancePages(at *v1alpha1.AppTarget) error {
+	if !(at.InMaintenance() && at.NeedsIngress()) && !at.ScalesToZero() {
+		return nil
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.KonSystemNamespace,
+			Name:      resources.MaintenancePagesServiceName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{
+				operatorSelectorLabel: operatorSelectorValue,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http",
+					Port:       80,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(ingress.PagesPort),
+				},
+			},
+		},
+	}
+	op, err := resources.UpdateResourceWithMerge(r.Client, svc, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated maintenance pages Service")
+	return nil
+}
+
+// replaces error responses from the app with custom pages, in the app's sidecar
+func (r *DeploymentReconciler) reconcileErrorPages(ctx context.Context, at *v1alpha1.AppTarget) error {
+	var port int32
+	if at.NeedsIngress() && len(at.Spec.Ingress.ErrorPages) != 0 {
+		port = ingressTargetPort(at)
+	}
+
+	if port == 0 {
+		return r.deleteIfExists(ctx, &istio.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: at.TargetNamespace(),
+				Name:      errorPagesFilterName(at),
+			},
+		})
+	}
+
+	value, err := newStruct(map[string]interface{}{
+		"name": luaFilter,
+		"typed_config": map[string]interface{}{
+			"@type":       luaType,
+			"inline_code": ingress.ErrorPagesLua(at.Spec.Ingress.ErrorPages),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	filter := newInboundHTTPFilter(at, errorPagesFilterName(at), port, value)
+	op, err := resources.UpdateResource(r.Client, filter, at, r.Scheme)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated error pages EnvoyFilter", "appTarget", at.Name)
+	return nil
+}
+
+func errorPagesFilterName(at *v1alpha1.AppTarget) string {
+	return at.Spec.App + "-errorpages"
+}
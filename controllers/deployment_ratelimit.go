@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/types"
+	istionetworking "istio.io/api/networking/v1alpha3"
+	istio "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/ratelimit"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+// limits requests to the app's hosts at the ingress gateway, with the global rate limit service.
+// the gateway sends a descriptor for each client, so that each client IP or header value has its own limit.
+// requests over the limit are rejected with a 429
+func (r *DeploymentReconciler) reconcileRateLimit(ctx context.Context, at *v1alpha1.AppTarget) error {
+	if err := r.reconcileRateLimitConfig(); err != nil {
+		return err
+	}
+
+	if !at.NeedsIngress() || at.Spec.Ingress.RateLimit == nil {
+		return r.deleteIfExists(ctx, &istio.EnvoyFilter{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: resources.IstioNamespace,
+				Name:      rateLimitFilterName(at),
+			},
+		})
+	}
+
+	filter, err := newRateLimitFilter(at)
+	if err != nil {
+		return err
+	}
+	op, err := resources.UpdateResource(r.Client, filter, at, r.Scheme)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated rate limit EnvoyFilter", "appTarget", at.Name)
+	return nil
+}
+
+// limits of all targets are kept in the config of the rate limit service
+func (r *DeploymentReconciler) reconcileRateLimitConfig() error {
+	targets := v1alpha1.AppTargetList{}
+	if err := r.Client.List(context.TODO(), &targets); err != nil {
+		return err
+	}
+	config, err := ratelimit.NewConfig(targets.Items)
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      ratelimit.ConfigMapName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Data: map[string]string{
+			ratelimit.ConfigKey: config,
+		},
+	}
+	op, err := resources.UpdateResource(r.Client, cm, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated rate limit config")
+	return nil
+}
+
+func rateLimitFilterName(at *v1alpha1.AppTarget) string {
+	return at.Name + "-ratelimit"
+}
+
+// adds rate limit actions to the virtual hosts of the app at the gateway, on both the public and internal ports
+func newRateLimitFilter(at *v1alpha1.AppTarget) (*istio.EnvoyFilter, error) {
+	clientAction := map[string]interface{}{
+		ratelimit.RemoteAddressKey: map[string]interface{}{},
+	}
+	if header := at.Spec.Ingress.RateLimit.Header; header != "" {
+		// requests without the header aren't limited
+		clientAction = map[string]interface{}{
+			"request_headers": map[string]interface{}{
+				"header_name":    header,
+				"descriptor_key": ratelimit.HeaderKey,
+			},
+		}
+	}
+	value, err := newStruct(map[string]interface{}{
+		"rate_limits": []interface{}{
+			map[string]interface{}{
+				"actions": []interface{}{
+					map[string]interface{}{
+						ratelimit.TargetKey: map[string]interface{}{
+							"descriptor_value": at.Name,
+						},
+					},
+					clientAction,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var patches []*istionetworking.EnvoyFilter_EnvoyConfigObjectPatch
+	for _, host := range at.Spec.Ingress.Hosts {
+		for _, port := range []int{80, resources.InternalGatewayPort} {
+			patches = append(patches, &istionetworking.EnvoyFilter_EnvoyConfigObjectPatch{
+				ApplyTo: istionetworking.EnvoyFilter_VIRTUAL_HOST,
+				Match: &istionetworking.EnvoyFilter_EnvoyConfigObjectMatch{
+					Context: istionetworking.EnvoyFilter_GATEWAY,
+					ObjectTypes: &istionetworking.EnvoyFilter_EnvoyConfigObjectMatch_RouteConfiguration{
+						RouteConfiguration: &istionetworking.EnvoyFilter_RouteConfigurationMatch{
+							Vhost: &istionetworking.EnvoyFilter_RouteConfigurationMatch_VirtualHostMatch{
+								Name: fmt.Sprintf("%s:%d", host, port),
+							},
+						},
+					},
+				},
+				Patch: &istionetworking.EnvoyFilter_Patch{
+					Operation: istionetworking.EnvoyFilter_Patch_MERGE,
+					Value:     value,
+				},
+			})
+		}
+	}
+
+	return &istio.EnvoyFilter{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      rateLimitFilterName(at),
+			Labels:    labelsForAppTarget(at),
+		},
+		Spec: istionetworking.EnvoyFilter{
+			WorkloadSelector: &istionetworking.WorkloadSelector{
+				Labels: map[string]string{
+					"istio": resources.IngressGatewayName,
+				},
+			},
+			ConfigPatches: patches,
+		},
+	}, nil
+}
+
+// returns an EnvoyFilter that adds the HTTP filter to the app's sidecar, for requests on the port
+func newInboundHTTPFilter(at *v1alpha1.AppTarget, name string, port int32, value *types.Struct) *istio.EnvoyFilter {
+	return &istio.EnvoyFilter{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: at.TargetNamespace(),
+			Name:      name,
+			Labels:    labelsForAppTarget(at),
+		},
+		Spec: istionetworking.EnvoyFilter{
+			WorkloadSelector: &istionetworking.WorkloadSelector{
+				Labels: selectorsForAppTarget(at),
+			},
+			ConfigPatches: []*istionetworking.EnvoyFilter_EnvoyConfigObjectPatch{
+				{
+					ApplyTo: istionetworking.EnvoyFilter_HTTP_FILTER,
+					Match: &istionetworking.EnvoyFilter_EnvoyConfigObjectMatch{
+						Context: istionetworking.EnvoyFilter_SIDECAR_INBOUND,
+						ObjectTypes: &istionetworking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+							Listener: &istionetworking.EnvoyFilter_ListenerMatch{
+								PortNumber: uint32(port),
+								FilterChain: &istionetworking.EnvoyFilter_ListenerMatch_FilterChainMatch{
+									Filter: &istionetworking.EnvoyFilter_ListenerMatch_FilterMatch{
+										Name: "envoy.filters.network.http_connection_manager",
+										SubFilter: &istionetworking.EnvoyFilter_ListenerMatch_SubFilterMatch{
+											Name: "envoy.filters.http.router",
+										},
+									},
+								},
+							},
+						},
+					},
+					Patch: &istionetworking.EnvoyFilter_Patch{
+						Operation: istionetworking.EnvoyFilter_Patch_INSERT_BEFORE,
+						Value:     value,
+					},
+				},
+			},
+		},
+	}
+}
+
+// converts the value into a protobuf Struct, which EnvoyFilter patches are specified in
+func newStruct(value map[string]interface{}) (*types.Struct, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	st := &types.Struct{}
+	if err = jsonpb.UnmarshalString(string(data), st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
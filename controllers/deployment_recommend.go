@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components/prometheus"
+)
+
+const (
+	recommendationInterval      = 6 * time.Hour
+	recommendationRetryInterval = 15 * time.Minute
+)
+
+// periodically computes resource recommendations from the target's usage, when enabled.
+// returns how long to wait before computing them again
+func (r *DeploymentReconciler) reconcileRecommendation(ctx context.Context, at *v1alpha1.AppTarget) time.Duration {
+	if !r.RecommendResources || r.Prometheus == nil {
+		return 0
+	}
+
+	now := time.Now()
+	if rec := at.Status.Recommendation; rec != nil {
+		if wait := rec.UpdatedAt.Add(recommendationInterval).Sub(now); wait > 0 {
+			return wait
+		}
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, prometheusQueryTimeout)
+	defer cancel()
+	window := prometheus.DefaultRecommendationWindow
+	reqs, err := prometheus.RecommendResources(queryCtx, r.Prometheus, at.TargetNamespace(), at.Spec.App, window, now)
+	if err != nil {
+		// usually there isn't enough usage yet, or Prometheus is unavailable. try again later
+		r.Log.Info("Could not recommend resources", "appTarget", at.Name, "error", err.Error())
+		return recommendationRetryInterval
+	}
+	at.Status.Recommendation = &v1alpha1.ResourceRecommendation{
+		Resources: *reqs,
+		Window:    metav1.Duration{Duration: window},
+		UpdatedAt: metav1.Time{Time: now},
+	}
+	return recommendationInterval
+}
@@ -19,7 +19,7 @@ import (
 
 var (
 	ingressGateway = fmt.Sprintf("%s/%s", resources.IstioNamespace, resources.IngressGatewayName)
-	allGateways    = []string{resources.MeshGatewayName, ingressGateway}
+	tcpAppProtocol = "tcp"
 )
 
 func (r *DeploymentReconciler) reconcileService(ctx context.Context, at *v1alpha1.AppTarget) (svc *corev1.Service, err error) {
@@ -141,12 +141,17 @@ func newServiceForAppTarget(at *v1alpha1.AppTarget) *corev1.Service {
 
 	var ports []corev1.ServicePort
 	for _, p := range at.Spec.Ports {
-		ports = append(ports, corev1.ServicePort{
+		sp := corev1.ServicePort{
 			Name:       p.Name,
 			Protocol:   p.Protocol,
 			Port:       p.Port,
 			TargetPort: intstr.FromInt(int(p.Port)),
-		})
+		}
+		// exposed ports don't carry HTTP, skip protocol detection so that server-first protocols work
+		if at.IsPortExposed(p.Name) && p.GetProtocol() == corev1.ProtocolTCP {
+			sp.AppProtocol = &tcpAppProtocol
+		}
+		ports = append(ports, sp)
 	}
 
 	svc := corev1.Service{
@@ -213,16 +218,25 @@ func (r *DeploymentReconciler) newVirtualService(at *v1alpha1.AppTarget, service
 		svcHost = resources.ServiceHostname(service.Namespace, service.Name)
 		allHosts = append(allHosts, svcHost)
 	}
-	if at.Spec.Ingress != nil {
-		allHosts = append(allHosts, at.Spec.Ingress.Hosts...)
-	}
 
 	releasesByPort := map[int32][]*v1alpha1.AppRelease{}
 	ports := make([]int32, 0)
 	for _, ar := range releases {
 		for _, port := range ar.Spec.Ports {
+			if releasesByPort[port.Port] == nil {
+				ports = append(ports, port.Port)
+			}
 			releasesByPort[port.Port] = append(releasesByPort[port.Port], ar)
-			ports = append(ports, port.Port)
+		}
+	}
+
+	// exposed TCP ports are routed as plain TCP. other ports are treated as HTTP
+	portProtocols := map[int32]corev1.Protocol{}
+	for _, port := range at.Spec.Ports {
+		if port.GetProtocol() == corev1.ProtocolUDP {
+			portProtocols[port.Port] = corev1.ProtocolUDP
+		} else if at.IsPortExposed(port.Name) {
+			portProtocols[port.Port] = corev1.ProtocolTCP
 		}
 	}
 	sort.Slice(ports, func(i, j int) bool {
@@ -230,10 +244,40 @@ func (r *DeploymentReconciler) newVirtualService(at *v1alpha1.AppTarget, service
 	})
 
 	var routes []*istionetworking.HTTPRoute
+	var tcpRoutes []*istionetworking.TCPRoute
 
 	// create internal routes, map each port
 	for _, port := range ports {
 		portReleases := releasesByPort[port]
+		var destinations []*istionetworking.RouteDestination
+		for _, ar := range portReleases {
+			destinations = append(destinations, &istionetworking.RouteDestination{
+				Destination: &istionetworking.Destination{
+					Host:   svcHost,
+					Port:   &istionetworking.PortSelector{Number: uint32(port)},
+					Subset: ar.Name,
+				},
+				Weight: ar.Spec.TrafficPercentage,
+			})
+		}
+
+		switch portProtocols[port] {
+		case corev1.ProtocolUDP:
+			// UDP isn't proxied by the mesh
+			continue
+		case corev1.ProtocolTCP:
+			tcpRoutes = append(tcpRoutes, &istionetworking.TCPRoute{
+				Match: []*istionetworking.L4MatchAttributes{
+					{
+						Gateways: []string{resources.MeshGatewayName},
+						Port:     uint32(port),
+					},
+				},
+				Route: destinations,
+			})
+			continue
+		}
+
 		route := &istionetworking.HTTPRoute{
 			Match: []*istionetworking.HTTPMatchRequest{
 				{
@@ -253,68 +297,10 @@ func (r *DeploymentReconciler) newVirtualService(at *v1alpha1.AppTarget, service
 			}
 			route.Route = append(route.Route, rd)
 		}
-		routes = append(routes, route)
-	}
-
-	// create external route, map the desired port to 80
-	if at.Spec.Ingress != nil {
-		var targetPort int32
-		for _, port := range at.Spec.Ports {
-			if targetPort == 0 {
-				targetPort = port.Port
-			}
-			if port.Name == at.Spec.Ingress.Port {
-				targetPort = port.Port
-				break
-			}
-		}
-
-		// handle only desired paths
-		var matches []*istionetworking.HTTPMatchRequest
-		defaultRoute := len(at.Spec.Ingress.Paths) == 0
-		for _, path := range at.Spec.Ingress.Paths {
-			// skip root, because it should be a default path that istio handles differently
-			if path == "/" {
-				defaultRoute = true
-				continue
-			}
-			matches = append(matches, &istionetworking.HTTPMatchRequest{
-				Gateways: []string{ingressGateway},
-				Uri: &istionetworking.StringMatch{
-					MatchType: &istionetworking.StringMatch_Prefix{
-						Prefix: path,
-					},
-				},
-				Port: 80,
-			})
-		}
-
-		// by default match all requests
-		if defaultRoute {
-			matches = append(matches, &istionetworking.HTTPMatchRequest{
-				Gateways: []string{ingressGateway},
-				Port:     80,
-			})
-		}
-
-		if targetPort != 0 {
-			route := &istionetworking.HTTPRoute{
-				Match: matches,
-			}
-			// should always have a port in order for VS to be defined
-			for _, ar := range releases {
-				rd := &istionetworking.HTTPRouteDestination{
-					Destination: &istionetworking.Destination{
-						Host:   svcHost,
-						Port:   &istionetworking.PortSelector{Number: uint32(targetPort)},
-						Subset: ar.Name,
-					},
-					Weight: ar.Spec.TrafficPercentage,
-				}
-				route.Route = append(route.Route, rd)
-			}
-			routes = append(routes, route)
+		if at.IsIdle() {
+			r.routeToActivator(at, route, port)
 		}
+		routes = append(routes, route)
 	}
 
 	vs := &istio.VirtualService{
@@ -324,9 +310,10 @@ func (r *DeploymentReconciler) newVirtualService(at *v1alpha1.AppTarget, service
 			Labels:    ls,
 		},
 		Spec: istionetworking.VirtualService{
-			Gateways: allGateways,
+			Gateways: []string{resources.MeshGatewayName},
 			Hosts:    allHosts,
 			Http:     routes,
+			Tcp:      tcpRoutes,
 		},
 	}
 
@@ -19,6 +19,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-logr/logr"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
@@ -26,6 +27,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud"
 	"github.com/k11n/konstellation/pkg/components/ingress"
 	"github.com/k11n/konstellation/pkg/resources"
 
@@ -48,6 +50,8 @@ type IngressRequestReconciler struct {
 // +kubebuilder:rbac:groups=k11n.dev,resources=ingressrequests;certificaterefs,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k11n.dev,resources=ingressrequests/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.istio.io,resources=gateways;virtualservices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=secrets;services,verbs=get;list;watch;create;update;patch;delete
 
 func (r *IngressRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	ctx := context.Background()
@@ -72,17 +76,54 @@ func (r *IngressRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 		return res, err
 	}
 
-	// aggregate items by app protocol => []*ingressRequest
+	cc, err := resources.GetClusterConfig(r.Client)
+	if err != nil {
+		return res, err
+	}
+	dnsProvider, err := ingress.NewDNSProviderForCluster(cc)
+	if err != nil {
+		return res, err
+	}
+
+	// clean up DNS records before the request goes away, its ingress is updated once it's gone
+	if ir.DeletionTimestamp != nil {
+		return res, r.finalizeDNSRecords(ctx, dnsProvider, ir, irs)
+	}
+	irs = activeIngressRequests(irs)
+
+	// request certificates for hosts that aren't covered by one
+	if ingress.ACMEEnabled(cc) {
+		if err = r.requestCertificates(ctx, cc, irs); err != nil {
+			return res, err
+		}
+	}
+
+	// internal requests are routed through a gateway port of their own
+	if err = r.reconcileInternalGateway(ctx); err != nil {
+		return res, err
+	}
+
+	// route paths on each host to apps that requested them
+	if err = r.reconcileRoutes(ctx, req.Namespace, irs); err != nil {
+		return res, err
+	}
+
+	// aggregate items by ingress name => []*ingressRequest
 	itemsToReconcile := make(map[string][]*v1alpha1.IngressRequest)
+	var ingressNames []string
 	for _, r := range irs {
-		p := r.Spec.AppProtocol
-		if reconcileAll || p == ir.Spec.AppProtocol {
-			itemsToReconcile[p] = append(itemsToReconcile[p], r)
+		name := ingressNameForRequest(req.Namespace, r)
+		if reconcileAll || r.Spec.Auth != nil || r.IsInternal() || r.Spec.AppProtocol == ir.Spec.AppProtocol {
+			if itemsToReconcile[name] == nil {
+				ingressNames = append(ingressNames, name)
+			}
+			itemsToReconcile[name] = append(itemsToReconcile[name], r)
 		}
 	}
 
-	for protocol, irs := range itemsToReconcile {
-		err = r.reconcileForAppProtocol(req.Namespace, irs, protocol)
+	for _, name := range ingressNames {
+		irs := itemsToReconcile[name]
+		err = r.reconcileIngress(req.Namespace, name, irs, irs[0].Spec.AppProtocol, dnsProvider)
 		if err != nil {
 			return res, err
 		}
@@ -90,34 +131,37 @@ func (r *IngressRequestReconciler) Reconcile(req ctrl.Request) (ctrl.Result, err
 
 	// remove ingresses where name doesn't match
 	ingressList := &netv1.IngressList{}
-	err = r.Client.List(ctx, ingressList)
+	err = r.Client.List(ctx, ingressList, client.MatchingLabels{
+		resources.TargetLabel: req.Namespace,
+	})
 	if err != nil {
 		return res, err
 	}
 
-	// when in full reconcile mode, delete ingresses that don't match
-	if reconcileAll {
-		for _, in := range ingressList.Items {
-			protocol := in.Labels[resources.AppProtocolLabel]
-			if itemsToReconcile[protocol] == nil {
-				// no longer valid, delete
-				if err := r.Client.Delete(ctx, &in); err != nil {
-					return res, err
-				}
+	// when in full reconcile mode, delete ingresses that don't match.
+	// ingresses for auth and internal requests are always reconciled, so they are always checked
+	for _, in := range ingressList.Items {
+		if in.Labels[resources.Konstellation] == "" || itemsToReconcile[in.Name] != nil {
+			continue
+		}
+		if reconcileAll || in.Labels[resources.IngressAuthLabel] != "" || in.Labels[resources.IngressVisibilityLabel] != "" {
+			// no longer valid, delete
+			if err := r.Client.Delete(ctx, &in); err != nil {
+				return res, err
 			}
 		}
-
 	}
 
 	return res, nil
 }
 
-func (r *IngressRequestReconciler) reconcileForAppProtocol(namespace string, irs []*v1alpha1.IngressRequest, protocol string) error {
+func (r *IngressRequestReconciler) reconcileIngress(namespace string, name string, irs []*v1alpha1.IngressRequest, protocol string,
+	dnsProvider cloud.DNSProvider) error {
 	log := r.Log.WithValues("ingressrequest", namespace, "protocol", protocol)
 	ctx := context.Background()
 
 	// create ingress, one for all hosts sharing the same protocol
-	in, err := r.ingressForRequests(namespace, irs, protocol)
+	in, err := r.ingressForRequests(namespace, name, irs, protocol)
 	if err != nil {
 		return err
 	}
@@ -134,6 +178,21 @@ func (r *IngressRequestReconciler) reconcileForAppProtocol(namespace string, irs
 		return err
 	}
 
+	// credentials read by the ingress controller
+	for _, ir := range irs {
+		secrets, err := ingress.NewAuthSecrets(r.Client, in.Name, ir)
+		if err != nil {
+			return err
+		}
+		for _, secret := range secrets {
+			op, err := resources.UpdateResource(r.Client, secret, in, r.Scheme)
+			if err != nil {
+				return err
+			}
+			resources.LogUpdates(log, op, "Updated ingress auth secret", "secret", secret.Name)
+		}
+	}
+
 	var address string
 	for _, lb := range in.Status.LoadBalancer.Ingress {
 		if lb.Hostname != "" {
@@ -146,9 +205,26 @@ func (r *IngressRequestReconciler) reconcileForAppProtocol(namespace string, irs
 		}
 	}
 
+	// all requests in the namespace, records of hosts that are shared aren't removed
+	var allIrs []*v1alpha1.IngressRequest
+	if dnsProvider != nil {
+		allIrs, err = resources.GetIngressRequests(r.Client, namespace)
+		if err != nil {
+			return err
+		}
+	}
+
 	// update ingress url
+	var dnsErr error
 	for _, ir := range irs {
 		statusCopy := ir.Status.DeepCopy()
+		if dnsProvider != nil && address != "" {
+			// status is reported, and syncing retried when it fails
+			if err := r.syncDNSRecords(ctx, dnsProvider, ir, allIrs, address); err != nil {
+				log.Error(err, "Could not sync DNS records", "ingress", ir.Name)
+				dnsErr = err
+			}
+		}
 		ir.Status.Address = address
 
 		if !apiequality.Semantic.DeepEqual(statusCopy, &ir.Status) {
@@ -161,6 +237,58 @@ func (r *IngressRequestReconciler) reconcileForAppProtocol(namespace string, irs
 				"address", address)
 		}
 	}
+	return dnsErr
+}
+
+// requests that are being deleted no longer receive traffic
+func activeIngressRequests(irs []*v1alpha1.IngressRequest) []*v1alpha1.IngressRequest {
+	var active []*v1alpha1.IngressRequest
+	for _, ir := range irs {
+		if ir.DeletionTimestamp == nil {
+			active = append(active, ir)
+		}
+	}
+	return active
+}
+
+// creates ACME certificates for hosts without one. The CertificateRef controller handles issuing them
+func (r *IngressRequestReconciler) requestCertificates(ctx context.Context, cc *v1alpha1.ClusterConfig, irs []*v1alpha1.IngressRequest) error {
+	certs, err := resources.ListCertificates(r.Client)
+	if err != nil {
+		return err
+	}
+
+	for _, ir := range irs {
+		// http-01 challenges have to reach the host from the internet
+		if ir.IsInternal() {
+			continue
+		}
+		for _, host := range ir.Spec.Hosts {
+			if strings.HasPrefix(host, "*") && !ingress.ACMESupportsWildcards(cc) {
+				continue
+			}
+			covered := false
+			for _, cert := range certs {
+				if cert.GetSource() == v1alpha1.CertificateSourceACME && resources.CertificateCovers(cert.Spec.Domain, host) {
+					covered = true
+					break
+				}
+			}
+			if covered {
+				continue
+			}
+
+			cert := resources.NewACMECertificate(host)
+			if err := r.Client.Create(ctx, cert); err != nil {
+				if errors.IsAlreadyExists(err) {
+					continue
+				}
+				return err
+			}
+			r.Log.Info("Requested certificate", "domain", host, "certificate", cert.Name)
+			certs = append(certs, *cert)
+		}
+	}
 	return nil
 }
 
@@ -239,13 +367,33 @@ func (r *IngressRequestReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-func (r *IngressRequestReconciler) ingressForRequests(target string, requests []*v1alpha1.IngressRequest, protocol string) (*netv1.Ingress, error) {
+// requests share an Ingress for each protocol and visibility. requests with auth are given an Ingress of
+// their own, since ingress controllers apply auth to all of the hosts in an Ingress
+func ingressNameForRequest(target string, ir *v1alpha1.IngressRequest) string {
+	if ir.Spec.Auth != nil {
+		return fmt.Sprintf("%s-auth", ir.Name)
+	}
+	name := target
+	if ir.Spec.AppProtocol != "" {
+		name = fmt.Sprintf("%s-%s", name, ir.Spec.AppProtocol)
+	}
+	if ir.IsInternal() {
+		name = fmt.Sprintf("%s-%s", name, v1alpha1.IngressVisibilityInternal)
+	}
+	return name
+}
+
+func (r *IngressRequestReconciler) ingressForRequests(target string, ingressName string, requests []*v1alpha1.IngressRequest, protocol string) (*netv1.Ingress, error) {
 	cc, err := resources.GetClusterConfig(r.Client)
 	if err != nil {
 		return nil, err
 	}
-	ingressComponent := ingress.NewIngressForCluster(cc.Spec.Cloud, cc.Name)
+	ingressComponent, err := ingress.NewIngressForCluster(cc)
+	if err != nil {
+		return nil, err
+	}
 
+	internal := len(requests) != 0 && requests[0].IsInternal()
 	backend := netv1.IngressBackend{
 		Service: &netv1.IngressServiceBackend{
 			Name: resources.IngressBackendName,
@@ -254,11 +402,11 @@ func (r *IngressRequestReconciler) ingressForRequests(target string, requests []
 			},
 		},
 	}
-
-	ingressName := target
-	if protocol != "" {
-		ingressName = fmt.Sprintf("%s-%s", target, protocol)
+	if internal {
+		backend.Service.Name = resources.InternalIngressBackendName
+		backend.Service.Port.Number = resources.InternalGatewayPort
 	}
+
 	pathType := netv1.PathTypePrefix
 	in := netv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
@@ -290,6 +438,13 @@ func (r *IngressRequestReconciler) ingressForRequests(target string, requests []
 		},
 	}
 
+	if len(requests) != 0 && requests[0].Spec.Auth != nil {
+		in.Labels[resources.IngressAuthLabel] = "1"
+	}
+	if internal {
+		in.Labels[resources.IngressVisibilityLabel] = string(v1alpha1.IngressVisibilityInternal)
+	}
+
 	hostsUsed := map[string]bool{}
 	for _, req := range requests {
 		for _, host := range req.Spec.Hosts {
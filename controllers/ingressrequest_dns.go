@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/thoas/go-funk"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+)
+
+const (
+	// keeps requests around until their DNS records are removed
+	dnsRecordsFinalizer = "k11n.dev/dns-records"
+)
+
+// points the hosts of the request to the ingress address
+func (r *IngressRequestReconciler) syncDNSRecords(ctx context.Context, provider cloud.DNSProvider,
+	ir *v1alpha1.IngressRequest, irs []*v1alpha1.IngressRequest, address string) error {
+	if !funk.ContainsString(ir.Finalizers, dnsRecordsFinalizer) {
+		ir.Finalizers = append(ir.Finalizers, dnsRecordsFinalizer)
+		if err := r.Client.Update(ctx, ir); err != nil {
+			return err
+		}
+	}
+	return ingress.SyncDNSRecords(ctx, provider, ir, irs, address)
+}
+
+// removes records of a request that's being deleted. when the provider is no longer configured,
+// the records are left alone
+func (r *IngressRequestReconciler) finalizeDNSRecords(ctx context.Context, provider cloud.DNSProvider,
+	ir *v1alpha1.IngressRequest, irs []*v1alpha1.IngressRequest) error {
+	if !funk.ContainsString(ir.Finalizers, dnsRecordsFinalizer) {
+		return nil
+	}
+	if provider != nil {
+		if err := ingress.RemoveDNSRecords(ctx, provider, ir, irs); err != nil {
+			return err
+		}
+		r.Log.Info("Removed DNS records", "ingressrequest", ir.Name)
+	}
+	ir.Finalizers = funk.FilterString(ir.Finalizers, func(f string) bool {
+		return f != dnsRecordsFinalizer
+	})
+	return r.Client.Update(ctx, ir)
+}
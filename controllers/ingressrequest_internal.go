@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+
+	istionetworking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+var (
+	internalGateway = resources.IstioNamespace + "/" + resources.InternalGatewayName
+)
+
+// internal load balancers send traffic to a separate port on the ingress gateway. hosts of internal requests
+// are only routed on that port, the public load balancer can't reach them even when the host is spoofed.
+// the gateway is shared by all targets, and is removed once there are no internal requests left
+func (r *IngressRequestReconciler) reconcileInternalGateway(ctx context.Context) error {
+	// requests from all targets, not just the one being reconciled
+	irs, err := resources.GetIngressRequests(r.Client, "")
+	if err != nil {
+		return err
+	}
+	needed := false
+	for _, ir := range activeIngressRequests(irs) {
+		if ir.IsInternal() {
+			needed = true
+			break
+		}
+	}
+	if !needed {
+		return r.deleteInternalGateway(ctx)
+	}
+
+	gatewaySelector := map[string]string{
+		"istio": resources.IngressGatewayName,
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      resources.InternalIngressBackendName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			// ALB sends traffic to node ports
+			Type:     corev1.ServiceTypeNodePort,
+			Selector: gatewaySelector,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "http-internal",
+					Port:       resources.InternalGatewayPort,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromInt(resources.InternalGatewayPort),
+				},
+			},
+		},
+	}
+	op, err := resources.UpdateResourceWithMerge(r.Client, svc, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated internal gateway Service")
+
+	gateway := &istio.Gateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      resources.InternalGatewayName,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Spec: istionetworking.Gateway{
+			Selector: gatewaySelector,
+			Servers: []*istionetworking.Server{
+				{
+					Port: &istionetworking.Port{
+						Name:     "http-internal",
+						Number:   resources.InternalGatewayPort,
+						Protocol: "HTTP",
+					},
+					Hosts: []string{"*"},
+				},
+			},
+		},
+	}
+	op, err = resources.UpdateResource(r.Client, gateway, nil, nil)
+	if err != nil {
+		return err
+	}
+	resources.LogUpdates(r.Log, op, "Updated internal Gateway")
+	return nil
+}
+
+func (r *IngressRequestReconciler) deleteInternalGateway(ctx context.Context) error {
+	objs := []runtime.Object{
+		&istio.Gateway{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: resources.IstioNamespace,
+				Name:      resources.InternalGatewayName,
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: resources.IstioNamespace,
+				Name:      resources.InternalIngressBackendName,
+			},
+		},
+	}
+	for _, obj := range objs {
+		key, err := client.ObjectKeyFromObject(obj)
+		if err != nil {
+			return err
+		}
+		if err = r.Client.Get(ctx, key, obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		r.Log.Info("Deleting unneeded internal gateway resource", "name", key.Name)
+		if err = client.IgnoreNotFound(r.Client.Delete(ctx, obj)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	istionetworking "istio.io/api/networking/v1beta1"
+	istio "istio.io/client-go/pkg/apis/networking/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+// a path on a host, routed to the app of an IngressRequest
+type ingressClaim struct {
+	path     string
+	request  string
+	internal bool
+}
+
+// assigns each host and path to a single request. requests are sorted by creation time, so existing routes
+// are kept when a new app claims the same path. returns claims for each host, along with the conflicts
+// of each request
+func resolveIngressClaims(irs []*v1alpha1.IngressRequest) (map[string][]ingressClaim, map[string][]v1alpha1.IngressConflict) {
+	hostClaims := make(map[string][]ingressClaim)
+	conflicts := make(map[string][]v1alpha1.IngressConflict)
+	owners := make(map[string]string)
+	for _, ir := range irs {
+		for _, host := range ir.Spec.Hosts {
+			for _, path := range ir.GetPaths() {
+				key := host + path
+				if owner, ok := owners[key]; ok {
+					if owner != ir.Name {
+						conflicts[ir.Name] = append(conflicts[ir.Name], v1alpha1.IngressConflict{
+							Host:      host,
+							Path:      path,
+							ClaimedBy: owner,
+						})
+					}
+					continue
+				}
+				owners[key] = ir.Name
+				hostClaims[host] = append(hostClaims[host], ingressClaim{
+					path:     path,
+					request:  ir.Name,
+					internal: ir.IsInternal(),
+				})
+			}
+		}
+	}
+	return hostClaims, conflicts
+}
+
+// creates a VirtualService for each host, routing longer prefixes first so that apps on the same host
+// don't shadow each other. each route is delegated to the app's ingress VirtualService
+func (r *IngressRequestReconciler) reconcileRoutes(ctx context.Context, namespace string, irs []*v1alpha1.IngressRequest) error {
+	log := r.Log.WithValues("namespace", namespace)
+	hostClaims, conflicts := resolveIngressClaims(irs)
+
+	for _, ir := range irs {
+		statusCopy := ir.Status.DeepCopy()
+		ir.Status.Conflicts = conflicts[ir.Name]
+		if apiequality.Semantic.DeepEqual(statusCopy, &ir.Status) {
+			continue
+		}
+		if err := r.Client.Status().Update(ctx, ir); err != nil {
+			return err
+		}
+		for _, conflict := range ir.Status.Conflicts {
+			log.Info("Path is already routed to another app", "ingressrequest", ir.Name,
+				"host", conflict.Host, "path", conflict.Path, "claimedBy", conflict.ClaimedBy)
+		}
+	}
+
+	desired := make(map[string]bool)
+	for host, claims := range hostClaims {
+		vs := newHostVirtualService(namespace, host, claims)
+		desired[vs.Name] = true
+		op, err := resources.UpdateResource(r.Client, vs, nil, nil)
+		if err != nil {
+			return err
+		}
+		resources.LogUpdates(log, op, "Updated host VirtualService", "host", host)
+	}
+
+	// remove hosts that are no longer requested
+	vsList := &istio.VirtualServiceList{}
+	err := r.Client.List(ctx, vsList, client.InNamespace(namespace), client.MatchingLabels{
+		resources.IngressRoutesLabel: "1",
+	})
+	if err != nil {
+		return err
+	}
+	for _, vs := range vsList.Items {
+		if desired[vs.Name] {
+			continue
+		}
+		if err = r.Client.Delete(ctx, &vs); err != nil {
+			return err
+		}
+		log.Info("Deleted host VirtualService", "virtualService", vs.Name)
+	}
+	return nil
+}
+
+func newHostVirtualService(namespace string, host string, claims []ingressClaim) *istio.VirtualService {
+	sorted := make([]ingressClaim, len(claims))
+	copy(sorted, claims)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].path) > len(sorted[j].path)
+	})
+
+	var routes []*istionetworking.HTTPRoute
+	var hasPublic, hasInternal bool
+	for _, claim := range sorted {
+		// internal paths are only routed from the internal gateway port
+		match := &istionetworking.HTTPMatchRequest{
+			Port: 80,
+		}
+		if claim.internal {
+			match.Port = resources.InternalGatewayPort
+			hasInternal = true
+		} else {
+			hasPublic = true
+		}
+		if claim.path != "/" {
+			match.Uri = &istionetworking.StringMatch{
+				MatchType: &istionetworking.StringMatch_Prefix{Prefix: claim.path},
+			}
+		}
+		routes = append(routes, &istionetworking.HTTPRoute{
+			Match: []*istionetworking.HTTPMatchRequest{match},
+			Delegate: &istionetworking.Delegate{
+				Name:      claim.request,
+				Namespace: namespace,
+			},
+		})
+	}
+
+	var gateways []string
+	if hasPublic {
+		gateways = append(gateways, ingressGateway)
+	}
+	if hasInternal {
+		gateways = append(gateways, internalGateway)
+	}
+
+	return &istio.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      hostVirtualServiceName(host),
+			Labels: map[string]string{
+				resources.KubeManagedByLabel: resources.Konstellation,
+				resources.IngressRoutesLabel: "1",
+			},
+		},
+		Spec: istionetworking.VirtualService{
+			Hosts:    []string{host},
+			Gateways: gateways,
+			Http:     routes,
+		},
+	}
+}
+
+func hostVirtualServiceName(host string) string {
+	return "host-" + strings.Replace(host, "*", "wildcard", 1)
+}
@@ -18,13 +18,18 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
+	"github.com/thoas/go-funk"
 	corev1 "k8s.io/api/core/v1"
 	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -39,11 +44,15 @@ type NodepoolReconciler struct {
 	client.Client
 	Log    logr.Logger
 	Scheme *runtime.Scheme
+	// used to evict pods when nodes are replaced
+	KubeClient kubernetes.Interface
 }
 
 // +kubebuilder:rbac:groups=k11n.dev,resources=nodepools,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=k11n.dev,resources=nodepools/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
 
 func (r *NodepoolReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err error) {
 	ctx := context.Background()
@@ -65,6 +74,22 @@ func (r *NodepoolReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err e
 		return
 	}
 
+	// labels and taints are set on the node group, changes are applied to existing nodes here.
+	// while the nodepool is being replaced, its nodes keep the spec they are running with
+	spec := &np.Spec
+	if np.Status.AppliedSpec != nil {
+		spec = np.Status.AppliedSpec
+	}
+	for _, node := range nodes {
+		if !applyNodepoolToNode(spec, node) {
+			continue
+		}
+		log.Info("Updating node labels and taints", "node", node.Name)
+		if err = r.Client.Update(ctx, node); err != nil {
+			return
+		}
+	}
+
 	existingStatus := np.Status.DeepCopy()
 	np.Status.Nodes = make([]string, 0, len(nodes))
 	np.Status.NumReady = 0
@@ -83,15 +108,24 @@ func (r *NodepoolReconciler) Reconcile(req ctrl.Request) (res ctrl.Result, err e
 		}
 	}
 
+	res.RequeueAfter, err = r.reconcileSpec(ctx, np)
+	if err != nil {
+		return
+	}
+
 	if !apiequality.Semantic.DeepEqual(existingStatus, &np.Status) {
 		log.Info("Updating Nodepool status", "nodepool", np.Name, "numReady", np.Status.NumReady)
 		err = r.Client.Status().Update(context.Background(), np)
+		if errors.IsNotFound(err) {
+			// deleted after it's been replaced
+			err = nil
+		}
 		if err != nil {
 			return
 		}
 	}
 
-	return ctrl.Result{}, nil
+	return
 }
 
 func (r *NodepoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
@@ -118,3 +152,115 @@ func (r *NodepoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&source.Kind{Type: &corev1.Node{}}, nodeWatcher).
 		Complete(r)
 }
+
+// sets labels and taints of the nodepool on the node, removing ones that were dropped from the nodepool.
+// returns true if it's changed
+func applyNodepoolToNode(spec *v1alpha1.NodepoolSpec, node *corev1.Node) bool {
+	changed := false
+	for _, key := range splitAnnotation(node.Annotations[v1alpha1.NodepoolLabelsAnnotation]) {
+		if _, ok := spec.Labels[key]; ok {
+			continue
+		}
+		if _, ok := node.Labels[key]; ok {
+			delete(node.Labels, key)
+			changed = true
+		}
+	}
+
+	applied := splitAnnotation(node.Annotations[v1alpha1.NodepoolTaintsAnnotation])
+	var taints []corev1.Taint
+	for _, taint := range node.Spec.Taints {
+		if funk.ContainsString(applied, taintID(taint)) && !nodepoolHasTaint(spec, taint) {
+			changed = true
+			continue
+		}
+		taints = append(taints, taint)
+	}
+	node.Spec.Taints = taints
+
+	labelKeys := make([]string, 0, len(spec.Labels))
+	for key, val := range spec.Labels {
+		labelKeys = append(labelKeys, key)
+		if existing, ok := node.Labels[key]; ok && existing == val {
+			continue
+		}
+		if node.Labels == nil {
+			node.Labels = make(map[string]string)
+		}
+		node.Labels[key] = val
+		changed = true
+	}
+
+	taintIDs := make([]string, 0, len(spec.Taints))
+	for _, taint := range spec.Taints {
+		taintIDs = append(taintIDs, taintID(taint))
+		found := false
+		for i := range node.Spec.Taints {
+			existing := &node.Spec.Taints[i]
+			if existing.Key != taint.Key || existing.Effect != taint.Effect {
+				continue
+			}
+			found = true
+			if existing.Value != taint.Value {
+				existing.Value = taint.Value
+				changed = true
+			}
+			break
+		}
+		if !found {
+			node.Spec.Taints = append(node.Spec.Taints, taint)
+			changed = true
+		}
+	}
+
+	// keep track of what's applied
+	sort.Strings(labelKeys)
+	sort.Strings(taintIDs)
+	if setNodeAnnotation(node, v1alpha1.NodepoolLabelsAnnotation, strings.Join(labelKeys, ",")) {
+		changed = true
+	}
+	if setNodeAnnotation(node, v1alpha1.NodepoolTaintsAnnotation, strings.Join(taintIDs, ",")) {
+		changed = true
+	}
+	return changed
+}
+
+// taints are identified by their key and effect
+func taintID(taint corev1.Taint) string {
+	return fmt.Sprintf("%s:%s", taint.Key, taint.Effect)
+}
+
+func nodepoolHasTaint(spec *v1alpha1.NodepoolSpec, taint corev1.Taint) bool {
+	for _, t := range spec.Taints {
+		if t.Key == taint.Key && t.Effect == taint.Effect {
+			return true
+		}
+	}
+	return false
+}
+
+// sets or removes the annotation when it's empty, returns true if it's changed
+func setNodeAnnotation(node *corev1.Node, key, val string) bool {
+	existing, ok := node.Annotations[key]
+	if val == "" {
+		if ok {
+			delete(node.Annotations, key)
+		}
+		return ok
+	}
+	if ok && existing == val {
+		return false
+	}
+	if node.Annotations == nil {
+		node.Annotations = make(map[string]string)
+	}
+	node.Annotations[key] = val
+	return true
+}
+
+func splitAnnotation(val string) []string {
+	if val == "" {
+		return nil
+	}
+	return strings.Split(val, ",")
+}
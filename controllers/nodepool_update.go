@@ -0,0 +1,341 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud"
+	kaws "github.com/k11n/konstellation/pkg/cloud/aws"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	// time allowed for a new nodepool to become ready
+	nodepoolCreateTimeout = 30 * time.Minute
+	// draining that takes longer is reported as blocked, evictions are retried until it completes
+	nodepoolDrainTimeout = 10 * time.Minute
+	// changes to node groups are checked at this interval while in progress
+	nodepoolCheckInterval = 30 * time.Second
+)
+
+func newKubernetesProviderForCluster(cc *v1alpha1.ClusterConfig) (cloud.KubernetesProvider, error) {
+	if cc.Spec.Cloud != "aws" {
+		return nil, fmt.Errorf("nodepools are not supported on %s", cc.Spec.Cloud)
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(cc.Spec.Region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return kaws.NewEKSService(sess), nil
+}
+
+// applies changes of the spec to the node group. sizes, labels, and taints are updated in place, other changes
+// surge replace the nodepool with a new one. returns the interval to check again while changes are in progress
+func (r *NodepoolReconciler) reconcileSpec(ctx context.Context, np *v1alpha1.Nodepool) (time.Duration, error) {
+	if np.Status.AppliedSpec == nil {
+		// node group was created with the spec
+		np.Status.AppliedSpec = np.Spec.DeepCopy()
+		return 0, nil
+	}
+
+	replacement := np.Status.Replacement
+	isReplacing := replacement != nil && replacement.Phase != v1alpha1.NodepoolReplacementFailed
+	if !isReplacing {
+		if apiequality.Semantic.DeepEqual(&np.Spec, np.Status.AppliedSpec) {
+			np.Status.Replacement = nil
+			return 0, nil
+		}
+		if replacement != nil && apiequality.Semantic.DeepEqual(&np.Spec, &replacement.Spec) {
+			// replacement has been rolled back, wait for the spec to change before trying again
+			return 0, nil
+		}
+		if err := np.Spec.Validate(); err != nil {
+			r.Log.Error(err, "Invalid nodepool spec", "nodepool", np.Name)
+			return 0, nil
+		}
+		if np.Spec.MinSize > np.Spec.MaxSize {
+			r.Log.Info("Invalid nodepool spec, minSize is greater than maxSize", "nodepool", np.Name)
+			return 0, nil
+		}
+	}
+
+	cc, err := resources.GetClusterConfig(r.Client)
+	if err != nil {
+		return 0, err
+	}
+	provider, err := newKubernetesProviderForCluster(cc)
+	if err != nil {
+		return 0, err
+	}
+
+	if !isReplacing {
+		if !np.Spec.RequiresReplacement(np.Status.AppliedSpec) {
+			return r.updateNodepool(ctx, cc, provider, np)
+		}
+
+		// recorded before the new nodepool is created, so only one is created
+		np.Status.Replacement = &v1alpha1.NodepoolReplacement{
+			Name:      resources.NodepoolName(),
+			Phase:     v1alpha1.NodepoolReplacementCreating,
+			StartedAt: metav1.Now(),
+			Spec:      *np.Spec.DeepCopy(),
+		}
+		r.Log.Info("Replacing nodepool", "nodepool", np.Name, "replacement", np.Status.Replacement.Name)
+		return nodepoolCheckInterval, nil
+	}
+
+	switch replacement.Phase {
+	case v1alpha1.NodepoolReplacementCreating:
+		return r.createReplacement(ctx, cc, provider, np)
+	case v1alpha1.NodepoolReplacementDraining:
+		return r.drainNodepool(ctx, np)
+	case v1alpha1.NodepoolReplacementDeleting:
+		return r.deleteReplacedNodepool(ctx, cc, provider, np)
+	}
+	return 0, nil
+}
+
+func (r *NodepoolReconciler) updateNodepool(ctx context.Context, cc *v1alpha1.ClusterConfig, provider cloud.KubernetesProvider,
+	np *v1alpha1.Nodepool) (time.Duration, error) {
+	// node group can't be changed while a previous update is in progress
+	ready, err := provider.IsNodepoolReady(ctx, cc.Name, np.Name)
+	if err != nil {
+		return 0, err
+	}
+	if !ready {
+		return nodepoolCheckInterval, nil
+	}
+
+	r.Log.Info("Updating nodepool", "nodepool", np.Name)
+	if err = provider.UpdateNodepool(ctx, cc.Name, np); err != nil {
+		return 0, err
+	}
+	np.Status.AppliedSpec = np.Spec.DeepCopy()
+	np.Status.Replacement = nil
+	return 0, nil
+}
+
+// creates the new nodepool, and its resource once nodes are ready
+func (r *NodepoolReconciler) createReplacement(ctx context.Context, cc *v1alpha1.ClusterConfig, provider cloud.KubernetesProvider,
+	np *v1alpha1.Nodepool) (time.Duration, error) {
+	replacement := np.Status.Replacement
+	deleted, err := provider.IsNodepoolDeleted(ctx, cc.Name, replacement.Name)
+	if err != nil {
+		return 0, err
+	}
+	if deleted {
+		r.Log.Info("Creating nodepool", "nodepool", replacement.Name)
+		err = provider.CreateNodepool(ctx, cc, &v1alpha1.Nodepool{
+			ObjectMeta: metav1.ObjectMeta{Name: replacement.Name},
+			Spec:       replacement.Spec,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return nodepoolCheckInterval, nil
+	}
+
+	ready, err := provider.IsNodepoolReady(ctx, cc.Name, replacement.Name)
+	if err != nil {
+		return 0, err
+	}
+	if !ready {
+		if time.Since(replacement.StartedAt.Time) > nodepoolCreateTimeout {
+			r.Log.Info("Nodepool did not become ready, rolling back", "nodepool", replacement.Name)
+			return 0, r.rollbackReplacement(ctx, cc, provider, np)
+		}
+		return nodepoolCheckInterval, nil
+	}
+
+	asgID, err := provider.GetNodepoolASG(ctx, cc.Name, replacement.Name)
+	if err != nil {
+		return 0, err
+	}
+	newNp := &v1alpha1.Nodepool{}
+	err = r.Client.Get(ctx, client.ObjectKey{Name: replacement.Name}, newNp)
+	if errors.IsNotFound(err) {
+		newNp = &v1alpha1.Nodepool{
+			ObjectMeta: metav1.ObjectMeta{Name: replacement.Name},
+			Spec:       replacement.Spec,
+		}
+		err = r.Client.Create(ctx, newNp)
+	}
+	if err != nil {
+		return 0, err
+	}
+	newNp.Status.AppliedSpec = replacement.Spec.DeepCopy()
+	newNp.Status.AWS = &v1alpha1.AWSNodepoolStatus{
+		ASGID: asgID,
+	}
+	if err = r.Client.Status().Update(ctx, newNp); err != nil {
+		return 0, err
+	}
+
+	r.Log.Info("Draining nodepool", "nodepool", np.Name)
+	replacement.Phase = v1alpha1.NodepoolReplacementDraining
+	replacement.StartedAt = metav1.Now()
+	return nodepoolCheckInterval, nil
+}
+
+// cordons the nodes and evicts their pods, the way kubectl drain --ignore-daemonsets does.
+// it isn't rolled back once started, since the new nodepool is running pods that were evicted
+func (r *NodepoolReconciler) drainNodepool(ctx context.Context, np *v1alpha1.Nodepool) (time.Duration, error) {
+	replacement := np.Status.Replacement
+	nodes, err := resources.GetNodesForNodepool(r.Client, np.Name)
+	if err != nil {
+		return 0, err
+	}
+
+	// cordon all nodes first so evicted pods are not scheduled onto nodes that are going away
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		r.Log.Info("Cordoning node", "node", node.Name)
+		node.Spec.Unschedulable = true
+		if err = r.Client.Update(ctx, node); err != nil {
+			return 0, err
+		}
+	}
+
+	remaining := 0
+	for _, node := range nodes {
+		pods, err := r.KubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node.Name).String(),
+		})
+		if err != nil {
+			return 0, err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			if !isEvictable(pod) {
+				continue
+			}
+			remaining += 1
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			err = r.KubeClient.PolicyV1beta1().Evictions(pod.Namespace).Evict(ctx, &policyv1beta1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: pod.Namespace,
+					Name:      pod.Name,
+				},
+			})
+			// pods that would violate their disruption budget are evicted on a later check
+			if err != nil && !errors.IsNotFound(err) && !errors.IsTooManyRequests(err) {
+				return 0, err
+			}
+		}
+	}
+
+	if remaining == 0 {
+		r.Log.Info("Deleting drained nodepool", "nodepool", np.Name)
+		replacement.Phase = v1alpha1.NodepoolReplacementDeleting
+		replacement.StartedAt = metav1.Now()
+		return nodepoolCheckInterval, nil
+	}
+	if time.Since(replacement.StartedAt.Time) > nodepoolDrainTimeout {
+		// usually pods that a PodDisruptionBudget doesn't allow to be evicted
+		r.Log.Info("Nodepool drain is blocked, retrying evictions", "nodepool", np.Name, "remainingPods", remaining)
+	}
+	return nodepoolCheckInterval, nil
+}
+
+// deletes the node group, then the resource of the nodepool once it's gone
+func (r *NodepoolReconciler) deleteReplacedNodepool(ctx context.Context, cc *v1alpha1.ClusterConfig, provider cloud.KubernetesProvider,
+	np *v1alpha1.Nodepool) (time.Duration, error) {
+	deleted, err := provider.IsNodepoolDeleted(ctx, cc.Name, np.Name)
+	if err != nil {
+		return 0, err
+	}
+	if deleted {
+		r.Log.Info("Replaced nodepool", "nodepool", np.Name, "replacement", np.Status.Replacement.Name)
+		err = r.Client.Delete(ctx, np)
+		if err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+
+	// node group is no longer active once its deletion has started
+	ready, err := provider.IsNodepoolReady(ctx, cc.Name, np.Name)
+	if err != nil {
+		return 0, err
+	}
+	if ready {
+		if err = provider.DeleteNodepool(ctx, cc.Name, np.Name); err != nil {
+			return 0, err
+		}
+	}
+	return nodepoolCheckInterval, nil
+}
+
+// uncordons existing nodes and removes the new nodepool, the replacement isn't retried until the spec changes
+func (r *NodepoolReconciler) rollbackReplacement(ctx context.Context, cc *v1alpha1.ClusterConfig, provider cloud.KubernetesProvider,
+	np *v1alpha1.Nodepool) error {
+	replacement := np.Status.Replacement
+	nodes, err := resources.GetNodesForNodepool(r.Client, np.Name)
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		if !node.Spec.Unschedulable {
+			continue
+		}
+		r.Log.Info("Uncordoning node", "node", node.Name)
+		node.Spec.Unschedulable = false
+		if err = r.Client.Update(ctx, node); err != nil {
+			return err
+		}
+	}
+
+	deleted, err := provider.IsNodepoolDeleted(ctx, cc.Name, replacement.Name)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		if err = provider.DeleteNodepool(ctx, cc.Name, replacement.Name); err != nil {
+			return err
+		}
+	}
+	err = r.Client.Delete(ctx, &v1alpha1.Nodepool{
+		ObjectMeta: metav1.ObjectMeta{Name: replacement.Name},
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	replacement.Phase = v1alpha1.NodepoolReplacementFailed
+	replacement.StartedAt = metav1.Now()
+	return nil
+}
+
+// daemonset and mirror pods stay on the node, completed pods don't need to be moved
+func isEvictable(pod *corev1.Pod) bool {
+	if _, ok := pod.Annotations[corev1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestDrainNodepoolBlocked(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{"eks.amazonaws.com/nodegroup": "pool-old"},
+		},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "myapp",
+			Name:      "myapp-1",
+		},
+		Spec: corev1.PodSpec{
+			NodeName: node.Name,
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(pod)
+	evictions := 0
+	// PodDisruptionBudget doesn't allow the pod to be evicted
+	kubeClient.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		evictions++
+		return true, nil, errors.NewTooManyRequests("disruption budget", 10)
+	})
+	r := &NodepoolReconciler{
+		Client:     fake.NewFakeClientWithScheme(clientgoscheme.Scheme, node),
+		Log:        ctrl.Log.WithName("test"),
+		Scheme:     clientgoscheme.Scheme,
+		KubeClient: kubeClient,
+	}
+
+	np := &v1alpha1.Nodepool{
+		ObjectMeta: metav1.ObjectMeta{Name: "pool-old"},
+	}
+	startedAt := metav1.NewTime(time.Now().Add(-2 * nodepoolDrainTimeout))
+	np.Status.Replacement = &v1alpha1.NodepoolReplacement{
+		Name:      "pool-new",
+		Phase:     v1alpha1.NodepoolReplacementDraining,
+		StartedAt: startedAt,
+	}
+
+	// past the timeout, it keeps draining instead of rolling back
+	requeue, err := r.drainNodepool(context.TODO(), np)
+	assert.NoError(t, err)
+	assert.Equal(t, nodepoolCheckInterval, requeue)
+	assert.Equal(t, v1alpha1.NodepoolReplacementDraining, np.Status.Replacement.Phase)
+	assert.Equal(t, startedAt, np.Status.Replacement.StartedAt)
+	assert.Equal(t, 1, evictions)
+
+	// nodes stay cordoned so evicted pods aren't scheduled back onto them
+	updated := &corev1.Node{}
+	assert.NoError(t, r.Client.Get(context.TODO(), client.ObjectKey{Name: node.Name}, updated))
+	assert.True(t, updated.Spec.Unschedulable)
+
+	// eviction is retried on the next check
+	_, err = r.drainNodepool(context.TODO(), np)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, evictions)
+	assert.Equal(t, v1alpha1.NodepoolReplacementDraining, np.Status.Replacement.Phase)
+}
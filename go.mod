@@ -3,18 +3,21 @@ module github.com/k11n/konstellation
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/GeertJohan/go.rice v1.0.2
 	github.com/apparentlymart/go-cidr v1.1.0
-	github.com/aws/aws-sdk-go v1.33.7
+	github.com/aws/aws-sdk-go v1.38.45
 	github.com/coreos/prometheus-operator v0.40.0
 	github.com/daaku/go.zipexe v1.0.1 // indirect
 	github.com/gammazero/workerpool v1.0.0
 	github.com/go-logr/logr v0.4.0
 	github.com/go-logr/zapr v0.4.0 // indirect
+	github.com/gogo/protobuf v1.3.1
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/googleapis/gnostic v0.5.4 // indirect
 	github.com/hako/durafmt v0.0.0-20200710122514-c0fb7b4da026
 	github.com/imdario/mergo v0.3.10
+	github.com/joho/godotenv v1.3.0
 	github.com/manifoldco/promptui v0.7.0
 	github.com/mitchellh/hashstructure v1.0.0
 	github.com/olekukonko/tablewriter v0.0.4
@@ -22,12 +25,17 @@ require (
 	github.com/onsi/gomega v1.10.1
 	github.com/pkg/browser v0.0.0-20180916011732-0a3d74bf9ce4
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.6.0
+	github.com/prometheus/common v0.10.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/cast v1.3.0
 	github.com/stretchr/testify v1.6.1
 	github.com/thoas/go-funk v0.7.0
 	github.com/urfave/cli/v2 v2.2.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	go.uber.org/multierr v1.6.0 // indirect
 	go.uber.org/zap v1.16.0 // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 	golang.org/x/lint v0.0.0-20201208152925-83fdc39ff7b5 // indirect
 	golang.org/x/sys v0.0.0-20210608053332-aa57babbf139 // indirect
 	gopkg.in/ini.v1 v1.57.0
@@ -41,6 +49,7 @@ require (
 	k8s.io/metrics v0.18.2
 	k8s.io/utils v0.0.0-20200729134348-d5654de09c73
 	sigs.k8s.io/controller-runtime v0.6.2
+	sigs.k8s.io/yaml v1.2.0
 )
 
 replace k8s.io/client-go => k8s.io/client-go v0.19.9
@@ -17,25 +17,40 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"net/http"
 	"os"
 
 	promv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	istiov1alpha3 "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	istiov1beta1 "istio.io/client-go/pkg/apis/networking/v1beta1"
+	istiosecurityv1beta1 "istio.io/client-go/pkg/apis/security/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
 	k11nv1alpha1 "github.com/k11n/konstellation/api/v1alpha1"
 	"github.com/k11n/konstellation/controllers"
+	"github.com/k11n/konstellation/pkg/cloud/acme"
+	"github.com/k11n/konstellation/pkg/components/ingress"
+	"github.com/k11n/konstellation/pkg/components/prometheus"
+	"github.com/k11n/konstellation/pkg/resources"
 	// +kubebuilder:scaffold:imports
 )
 
+const (
+	webhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+)
+
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
@@ -51,6 +66,7 @@ func init() {
 	// Istio scheme
 	utilruntime.Must(istiov1alpha3.AddToScheme(scheme))
 	utilruntime.Must(istiov1beta1.AddToScheme(scheme))
+	utilruntime.Must(istiosecurityv1beta1.AddToScheme(scheme))
 
 	// Prometheus
 	utilruntime.Must(promv1.AddToScheme(scheme))
@@ -59,10 +75,16 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var enableWebhooks bool
+	var enableRecommendations bool
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable admission webhooks, a self-signed serving certificate is created for them.")
+	flag.BoolVar(&enableRecommendations, "enable-recommendations", false,
+		"Enable resource recommendations on AppTargets, computed from usage in Prometheus.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
@@ -71,6 +93,7 @@ func main() {
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
 		Port:               9443,
+		CertDir:            webhookCertDir,
 		LeaderElection:     enableLeaderElection,
 		LeaderElectionID:   "3509f031.k11n.dev",
 	})
@@ -79,6 +102,11 @@ func main() {
 		os.Exit(1)
 	}
 
+	kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create Kubernetes client")
+		os.Exit(1)
+	}
 	if err = (&controllers.ClusterConfigReconciler{
 		Client: mgr.GetClient(),
 		Log:    ctrl.Log.WithName("controllers").WithName("ClusterConfig"),
@@ -96,9 +124,11 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&controllers.AppReleaseReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("AppRelease"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("AppRelease"),
+		Scheme:     mgr.GetScheme(),
+		KubeClient: kubeClient,
+		KubeConfig: mgr.GetConfig(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "AppRelease")
 		os.Exit(1)
@@ -112,17 +142,37 @@ func main() {
 		os.Exit(1)
 	}
 	if err = (&controllers.NodepoolReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Nodepool"),
-		Scheme: mgr.GetScheme(),
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("Nodepool"),
+		Scheme:     mgr.GetScheme(),
+		KubeClient: kubeClient,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Nodepool")
 		os.Exit(1)
 	}
+	promAPI, err := prometheus.NewQueryAPI(prometheus.ClusterAddress)
+	if err != nil {
+		setupLog.Error(err, "unable to create Prometheus client")
+		os.Exit(1)
+	}
+	// manager's client isn't usable until it starts
+	setupClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	activatorToken, err := resources.EnsureActivatorToken(setupClient)
+	if err != nil {
+		setupLog.Error(err, "unable to set up activator token")
+		os.Exit(1)
+	}
 	if err = (&controllers.DeploymentReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Deployment"),
-		Scheme: mgr.GetScheme(),
+		Client:             mgr.GetClient(),
+		Log:                ctrl.Log.WithName("controllers").WithName("Deployment"),
+		Scheme:             mgr.GetScheme(),
+		Prometheus:         promAPI,
+		RecommendResources: enableRecommendations,
+		ActivatorToken:     activatorToken,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Deployment")
 		os.Exit(1)
@@ -135,6 +185,41 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "IngressRequest")
 		os.Exit(1)
 	}
+	challenges := acme.NewHTTPChallenges()
+	if err = (&controllers.CertificateRefReconciler{
+		Client:     mgr.GetClient(),
+		Log:        ctrl.Log.WithName("controllers").WithName("CertificateRef"),
+		Scheme:     mgr.GetScheme(),
+		Recorder:   mgr.GetEventRecorderFor("certificateref"),
+		Challenges: challenges,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CertificateRef")
+		os.Exit(1)
+	}
+	// serve ACME challenges for certificates being issued
+	if err = mgr.Add(newHTTPServer(acme.ChallengePort, challenges)); err != nil {
+		setupLog.Error(err, "unable to add ACME challenge server")
+		os.Exit(1)
+	}
+	// serve maintenance pages for apps under maintenance, and hold requests for idle apps
+	pages := ingress.NewActivator(mgr.GetClient(), activatorToken, ingress.NewMaintenancePages(mgr.GetClient()))
+	if err = mgr.Add(newHTTPServer(ingress.PagesPort, pages)); err != nil {
+		setupLog.Error(err, "unable to add maintenance page server")
+		os.Exit(1)
+	}
+	if enableWebhooks {
+		if err = resources.EnsureWebhookCertificate(setupClient, webhookCertDir); err != nil {
+			setupLog.Error(err, "unable to set up webhook certificate")
+			os.Exit(1)
+		}
+		if err = (&controllers.AppConfigValidator{
+			Client: mgr.GetClient(),
+			Log:    ctrl.Log.WithName("webhooks").WithName("AppConfig"),
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "AppConfig")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
 	setupLog.Info("starting manager")
@@ -143,3 +228,35 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// serves a handler while the manager is running, on every replica and not only the leader
+type httpServer struct {
+	server *http.Server
+}
+
+var _ manager.Runnable = &httpServer{}
+var _ manager.LeaderElectionRunnable = &httpServer{}
+
+func newHTTPServer(port int, handler http.Handler) *httpServer {
+	return &httpServer{
+		server: &http.Server{
+			Addr:    fmt.Sprintf(":%d", port),
+			Handler: handler,
+		},
+	}
+}
+
+func (s *httpServer) Start(stop <-chan struct{}) error {
+	go func() {
+		<-stop
+		s.server.Shutdown(context.Background())
+	}()
+	if err := s.server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func (s *httpServer) NeedLeaderElection() bool {
+	return false
+}
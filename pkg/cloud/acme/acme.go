@@ -0,0 +1,342 @@
+package acme
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud/types"
+)
+
+const (
+	LetsEncryptURL = acme.LetsEncryptURL
+	ProviderName   = "acme"
+
+	ChallengeHTTP01 = "http-01"
+	ChallengeDNS01  = "dns-01"
+
+	// TXT records take time to propagate to all name servers, CAs could query any of them
+	DefaultDNSPropagationDelay = time.Minute
+)
+
+// IssuedCertificate contains the certificate chain and private key, both PEM encoded
+type IssuedCertificate struct {
+	Certificate    *types.Certificate
+	CertificatePEM []byte
+	PrivateKeyPEM  []byte
+}
+
+// DNSChallengeProvider creates the TXT records used to respond to dns-01 challenges
+type DNSChallengeProvider interface {
+	UpsertTXTRecord(ctx context.Context, name, value string) error
+	DeleteTXTRecord(ctx context.Context, name, value string) error
+}
+
+// ACMEService issues certificates from an ACME CA, validating domains with HTTP-01 or DNS-01 challenges.
+// Orders are advanced one step at a time, so that callers don't block while the CA validates them
+type ACMEService struct {
+	client     *acme.Client
+	email      string
+	challenges *HTTPChallenges
+	dns        DNSChallengeProvider
+
+	DNSPropagationDelay time.Duration
+}
+
+// dns is only needed for dns-01 challenges, and could be nil otherwise
+func NewACMEService(directoryURL string, accountKey crypto.Signer, email string, challenges *HTTPChallenges, dns DNSChallengeProvider) *ACMEService {
+	return &ACMEService{
+		client: &acme.Client{
+			Key:          accountKey,
+			DirectoryURL: directoryURL,
+		},
+		email:               email,
+		challenges:          challenges,
+		dns:                 dns,
+		DNSPropagationDelay: DefaultDNSPropagationDelay,
+	}
+}
+
+// Register creates an account with the CA, or looks up the existing account for the key
+func (a *ACMEService) Register(ctx context.Context) error {
+	account := &acme.Account{}
+	if a.email != "" {
+		account.Contact = []string{"mailto:" + a.email}
+	}
+	_, err := a.client.Register(ctx, account, acme.AcceptTOS)
+	if err == acme.ErrAccountAlreadyExists {
+		err = nil
+	}
+	return err
+}
+
+// BeginOrder orders a certificate for the domain, and sets up responses to the challenges for it.
+// challengeType is used for regular domains, wildcards could only be validated with dns-01.
+// certificates for wildcards include the apex domain as well
+func (a *ACMEService) BeginOrder(ctx context.Context, domain string, challengeType string) (*v1alpha1.ACMEOrder, error) {
+	if err := a.Register(ctx); err != nil {
+		return nil, err
+	}
+
+	order, err := a.client.AuthorizeOrder(ctx, acme.DomainIDs(orderDomains(domain)...))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &v1alpha1.ACMEOrder{
+		URL:       order.URI,
+		CreatedAt: metav1.Now(),
+	}
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := a.client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return nil, err
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		chType := challengeType
+		if authz.Wildcard {
+			chType = ChallengeDNS01
+		}
+		var challenge *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == chType {
+				challenge = c
+				break
+			}
+		}
+		if challenge == nil {
+			a.CleanupOrder(ctx, result)
+			return nil, fmt.Errorf("CA did not offer a %s challenge for %s", chType, authz.Identifier.Value)
+		}
+
+		pending := v1alpha1.ACMEChallenge{
+			Domain: authz.Identifier.Value,
+			Type:   chType,
+			URL:    challenge.URI,
+			Token:  challenge.Token,
+		}
+		result.Challenges = append(result.Challenges, pending)
+		if err = a.presentChallenge(ctx, &pending); err != nil {
+			a.CleanupOrder(ctx, result)
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ProgressOrder accepts challenges once their responses are in place, and finalizes the order once
+// the domain is validated. returns the certificate when it's been issued, or nil when the order is still pending
+func (a *ACMEService) ProgressOrder(ctx context.Context, order *v1alpha1.ACMEOrder, domain string) (*IssuedCertificate, error) {
+	o, err := a.client.GetOrder(ctx, order.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Status {
+	case acme.StatusPending:
+		for i := range order.Challenges {
+			pending := &order.Challenges[i]
+			if pending.Type == ChallengeHTTP01 {
+				// responses are kept in memory, serve them again in case the operator has restarted
+				if err = a.presentChallenge(ctx, pending); err != nil {
+					return nil, err
+				}
+			} else if time.Since(order.CreatedAt.Time) < a.DNSPropagationDelay {
+				continue
+			}
+
+			challenge, err := a.client.GetChallenge(ctx, pending.URL)
+			if err != nil {
+				return nil, err
+			}
+			if challenge.Status == acme.StatusPending {
+				if _, err = a.client.Accept(ctx, challenge); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, nil
+	case acme.StatusProcessing:
+		return nil, nil
+	case acme.StatusReady:
+		return a.finalizeOrder(ctx, o, domain)
+	case acme.StatusInvalid:
+		return nil, a.orderError(ctx, o, domain)
+	}
+	// the key for a finalized order is gone, a new order is needed
+	return nil, fmt.Errorf("order for %s is %s", domain, o.Status)
+}
+
+// CleanupOrder removes responses to the order's challenges
+func (a *ACMEService) CleanupOrder(ctx context.Context, order *v1alpha1.ACMEOrder) error {
+	var errs []error
+	for _, pending := range order.Challenges {
+		switch pending.Type {
+		case ChallengeHTTP01:
+			a.challenges.Remove(pending.Token)
+		case ChallengeDNS01:
+			if a.dns == nil {
+				continue
+			}
+			value, err := a.client.DNS01ChallengeRecord(pending.Token)
+			if err == nil {
+				err = a.dns.DeleteTXTRecord(ctx, DNSChallengeRecordName(pending.Domain), value)
+			}
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (a *ACMEService) presentChallenge(ctx context.Context, pending *v1alpha1.ACMEChallenge) error {
+	switch pending.Type {
+	case ChallengeHTTP01:
+		response, err := a.client.HTTP01ChallengeResponse(pending.Token)
+		if err != nil {
+			return err
+		}
+		a.challenges.Set(pending.Token, response)
+		return nil
+	case ChallengeDNS01:
+		if a.dns == nil {
+			return fmt.Errorf("a DNS provider is needed to validate %s with dns-01", pending.Domain)
+		}
+		value, err := a.client.DNS01ChallengeRecord(pending.Token)
+		if err != nil {
+			return err
+		}
+		return a.dns.UpsertTXTRecord(ctx, DNSChallengeRecordName(pending.Domain), value)
+	}
+	return fmt.Errorf("unsupported challenge type: %s", pending.Type)
+}
+
+func (a *ACMEService) finalizeOrder(ctx context.Context, order *acme.Order, domain string) (issued *IssuedCertificate, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: domain},
+		DNSNames: orderDomains(domain),
+	}, key)
+	if err != nil {
+		return
+	}
+
+	der, _, err := a.client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return
+	}
+	if len(der) == 0 {
+		err = fmt.Errorf("CA did not return a certificate for %s", domain)
+		return
+	}
+
+	leaf, err := x509.ParseCertificate(der[0])
+	if err != nil {
+		return
+	}
+
+	issued = &IssuedCertificate{
+		Certificate: certificateFromX509(domain, leaf),
+	}
+	for _, b := range der {
+		issued.CertificatePEM = append(issued.CertificatePEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: b})...)
+	}
+	issued.PrivateKeyPEM, err = EncodePrivateKey(key)
+	return
+}
+
+// returns the error from the failed challenge
+func (a *ACMEService) orderError(ctx context.Context, order *acme.Order, domain string) error {
+	for _, authzURL := range order.AuthzURLs {
+		authz, err := a.client.GetAuthorization(ctx, authzURL)
+		if err != nil {
+			return err
+		}
+		for _, c := range authz.Challenges {
+			if c.Error != nil {
+				return fmt.Errorf("%s challenge for %s failed: %v", c.Type, authz.Identifier.Value, c.Error)
+			}
+		}
+	}
+	return fmt.Errorf("CA could not validate %s", domain)
+}
+
+// certificates for wildcards also include the apex domain, which the wildcard doesn't match
+func orderDomains(domain string) []string {
+	if strings.HasPrefix(domain, "*.") {
+		return []string{domain, strings.TrimPrefix(domain, "*.")}
+	}
+	return []string{domain}
+}
+
+// TXT record that the CA looks up to validate the domain
+func DNSChallengeRecordName(domain string) string {
+	return "_acme-challenge." + strings.TrimPrefix(domain, "*.")
+}
+
+// returns the time to renew the certificate, when two thirds of its lifetime has elapsed
+func RenewalTime(cert *types.Certificate, issuedAt time.Time) time.Time {
+	if cert.ExpiresAt == nil {
+		return issuedAt
+	}
+	lifetime := cert.ExpiresAt.Sub(issuedAt)
+	return issuedAt.Add(lifetime * 2 / 3)
+}
+
+func certificateFromX509(domain string, leaf *x509.Certificate) *types.Certificate {
+	expiresAt := leaf.NotAfter
+	cert := &types.Certificate{
+		Domain:             domain,
+		CloudProvider:      ProviderName,
+		ExpiresAt:          &expiresAt,
+		KeyAlgorithm:       leaf.PublicKeyAlgorithm.String(),
+		SignatureAlgorithm: leaf.SignatureAlgorithm.String(),
+		Status:             types.CertificateStatusReady,
+	}
+	if len(leaf.Issuer.Organization) != 0 {
+		cert.Issuer = leaf.Issuer.Organization[0]
+	} else {
+		cert.Issuer = leaf.Issuer.CommonName
+	}
+	return cert
+}
+
+func GeneratePrivateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+func EncodePrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	b, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: b}), nil
+}
+
+func ParsePrivateKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("could not decode private key")
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
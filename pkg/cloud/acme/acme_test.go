@@ -0,0 +1,483 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/acme"
+)
+
+func TestOrderCertificate(t *testing.T) {
+	accountKey, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+	challenges := NewHTTPChallenges()
+
+	ca := newFakeACMEServer(t, accountKey, challenges, nil)
+	defer ca.Close()
+
+	ctx := context.TODO()
+	service := NewACMEService(ca.URL+"/directory", accountKey, "admin@mydomain.com", challenges, nil)
+	order, err := service.BeginOrder(ctx, "app.mydomain.com", ChallengeHTTP01)
+	assert.NoError(t, err)
+	if !assert.NotNil(t, order) {
+		return
+	}
+	assert.Len(t, order.Challenges, 1)
+	assert.Equal(t, ChallengeHTTP01, order.Challenges[0].Type)
+	assert.Len(t, challenges.responses, 1)
+	assert.Equal(t, 0, ca.validations)
+
+	// responses are served again after a restart, and the challenge is accepted
+	challenges.Remove(order.Challenges[0].Token)
+	issued, err := service.ProgressOrder(ctx, order, "app.mydomain.com")
+	assert.NoError(t, err)
+	assert.Nil(t, issued)
+	assert.Equal(t, 1, ca.validations)
+
+	// certificate is issued once the order is ready
+	issued, err = service.ProgressOrder(ctx, order, "app.mydomain.com")
+	assert.NoError(t, err)
+	if !assert.NotNil(t, issued) {
+		return
+	}
+
+	cert := issued.Certificate
+	assert.Equal(t, "app.mydomain.com", cert.Domain)
+	assert.Equal(t, "Fake ACME CA", cert.Issuer)
+	assert.Equal(t, "READY", cert.Status.String())
+	assert.Equal(t, "ECDSA", cert.KeyAlgorithm)
+	assert.WithinDuration(t, time.Now().Add(90*24*time.Hour), *cert.ExpiresAt, time.Minute)
+
+	// chain includes the leaf and issuer
+	block, rest := pem.Decode(issued.CertificatePEM)
+	assert.NotNil(t, block)
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"app.mydomain.com"}, leaf.DNSNames)
+	block, _ = pem.Decode(rest)
+	assert.NotNil(t, block)
+
+	key, err := ParsePrivateKey(issued.PrivateKeyPEM)
+	assert.NoError(t, err)
+	assert.Equal(t, &key.PublicKey, leaf.PublicKey)
+
+	assert.NoError(t, service.CleanupOrder(ctx, order))
+	assert.Empty(t, challenges.responses)
+	assert.Equal(t, 1, ca.validations)
+}
+
+func TestOrderCertificateFailedChallenge(t *testing.T) {
+	accountKey, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+
+	// challenges aren't served by the handler the CA validates against
+	ca := newFakeACMEServer(t, accountKey, NewHTTPChallenges(), nil)
+	defer ca.Close()
+
+	ctx := context.TODO()
+	service := NewACMEService(ca.URL+"/directory", accountKey, "", NewHTTPChallenges(), nil)
+	order, err := service.BeginOrder(ctx, "app.mydomain.com", ChallengeHTTP01)
+	assert.NoError(t, err)
+	_, err = service.ProgressOrder(ctx, order, "app.mydomain.com")
+	assert.NoError(t, err)
+
+	_, err = service.ProgressOrder(ctx, order, "app.mydomain.com")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "http-01 challenge for app.mydomain.com failed")
+}
+
+func TestOrderWildcardCertificate(t *testing.T) {
+	accountKey, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+	dns := &fakeDNSProvider{records: make(map[string][]string)}
+
+	ca := newFakeACMEServer(t, accountKey, NewHTTPChallenges(), dns)
+	defer ca.Close()
+
+	ctx := context.TODO()
+	service := NewACMEService(ca.URL+"/directory", accountKey, "", NewHTTPChallenges(), dns)
+	service.DNSPropagationDelay = time.Hour
+
+	// wildcards are validated with dns-01, along with the apex domain that's included in the certificate
+	order, err := service.BeginOrder(ctx, "*.mydomain.com", ChallengeDNS01)
+	assert.NoError(t, err)
+	if !assert.Len(t, order.Challenges, 2) {
+		return
+	}
+	for _, challenge := range order.Challenges {
+		assert.Equal(t, ChallengeDNS01, challenge.Type)
+		assert.Equal(t, "mydomain.com", challenge.Domain)
+	}
+	assert.Len(t, dns.records["_acme-challenge.mydomain.com"], 2)
+
+	// challenges aren't accepted until records have propagated
+	issued, err := service.ProgressOrder(ctx, order, "*.mydomain.com")
+	assert.NoError(t, err)
+	assert.Nil(t, issued)
+	assert.Equal(t, 0, ca.validations)
+
+	service.DNSPropagationDelay = 0
+	_, err = service.ProgressOrder(ctx, order, "*.mydomain.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, ca.validations)
+
+	issued, err = service.ProgressOrder(ctx, order, "*.mydomain.com")
+	assert.NoError(t, err)
+	if assert.NotNil(t, issued) {
+		assert.Equal(t, "*.mydomain.com", issued.Certificate.Domain)
+		block, _ := pem.Decode(issued.CertificatePEM)
+		leaf, err := x509.ParseCertificate(block.Bytes)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"*.mydomain.com", "mydomain.com"}, leaf.DNSNames)
+	}
+
+	assert.NoError(t, service.CleanupOrder(ctx, order))
+	assert.Empty(t, dns.records)
+
+	// regular domains could still use http-01
+	ca.reset()
+	challenges := NewHTTPChallenges()
+	service = NewACMEService(ca.URL+"/directory", accountKey, "", challenges, dns)
+	order, err = service.BeginOrder(ctx, "*.mydomain.com", ChallengeHTTP01)
+	assert.NoError(t, err)
+	if assert.Len(t, order.Challenges, 2) {
+		assert.Equal(t, ChallengeDNS01, order.Challenges[0].Type)
+		assert.Equal(t, ChallengeHTTP01, order.Challenges[1].Type)
+	}
+	assert.NoError(t, service.CleanupOrder(ctx, order))
+
+	// dns-01 needs a provider
+	ca.reset()
+	service = NewACMEService(ca.URL+"/directory", accountKey, "", NewHTTPChallenges(), nil)
+	_, err = service.BeginOrder(ctx, "*.mydomain.com", ChallengeHTTP01)
+	assert.Error(t, err)
+}
+
+func TestHTTPChallenges(t *testing.T) {
+	challenges := NewHTTPChallenges()
+	challenges.Set("token", "token.thumbprint")
+
+	res := httptest.NewRecorder()
+	challenges.ServeHTTP(res, httptest.NewRequest("GET", ChallengePathPrefix+"token", nil))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "token.thumbprint", res.Body.String())
+
+	res = httptest.NewRecorder()
+	challenges.ServeHTTP(res, httptest.NewRequest("GET", ChallengePathPrefix+"other", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+
+	challenges.Remove("token")
+	res = httptest.NewRecorder()
+	challenges.ServeHTTP(res, httptest.NewRequest("GET", ChallengePathPrefix+"token", nil))
+	assert.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func TestDNSChallengeRecordName(t *testing.T) {
+	assert.Equal(t, "_acme-challenge.mydomain.com", DNSChallengeRecordName("mydomain.com"))
+	assert.Equal(t, "_acme-challenge.mydomain.com", DNSChallengeRecordName("*.mydomain.com"))
+}
+
+func TestRenewalTime(t *testing.T) {
+	issuedAt := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cert := certificateFromX509("mydomain.com", &x509.Certificate{
+		NotAfter: issuedAt.Add(90 * 24 * time.Hour),
+	})
+	assert.Equal(t, issuedAt.Add(60*24*time.Hour), RenewalTime(cert, issuedAt))
+}
+
+// keeps TXT values in memory, names could have multiple values
+type fakeDNSProvider struct {
+	records map[string][]string
+}
+
+func (p *fakeDNSProvider) UpsertTXTRecord(ctx context.Context, name, value string) error {
+	p.records[name] = append(p.records[name], value)
+	return nil
+}
+
+func (p *fakeDNSProvider) DeleteTXTRecord(ctx context.Context, name, value string) error {
+	var values []string
+	for _, v := range p.records[name] {
+		if v != value {
+			values = append(values, v)
+		}
+	}
+	if len(values) == 0 {
+		delete(p.records, name)
+	} else {
+		p.records[name] = values
+	}
+	return nil
+}
+
+// fakeACMEServer implements the parts of RFC 8555 needed to issue a single certificate,
+// validating http-01 challenges against the given handler, and dns-01 against the DNS provider
+type fakeACMEServer struct {
+	*httptest.Server
+	t         *testing.T
+	caKey     *ecdsa.PrivateKey
+	caCert    *x509.Certificate
+	account   *acme.Client
+	validator http.Handler
+	dns       *fakeDNSProvider
+
+	lock        sync.Mutex
+	domains     []string
+	authzStatus []string
+	certDER     []byte
+	validations int
+}
+
+func newFakeACMEServer(t *testing.T, accountKey *ecdsa.PrivateKey, validator http.Handler, dns *fakeDNSProvider) *fakeACMEServer {
+	caKey, err := GeneratePrivateKey()
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{Organization: []string{"Fake ACME CA"}},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &caKey.PublicKey, caKey)
+	assert.NoError(t, err)
+	caCert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+
+	s := &fakeACMEServer{
+		t:         t,
+		caKey:     caKey,
+		caCert:    caCert,
+		account:   &acme.Client{Key: accountKey},
+		validator: validator,
+		dns:       dns,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// starts over, as if a new order had been placed
+func (s *fakeACMEServer) reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.domains = nil
+	s.authzStatus = nil
+	s.certDER = nil
+}
+
+func (s *fakeACMEServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	w.Header().Set("Replay-Nonce", fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+	if r.Method == "HEAD" {
+		return
+	}
+
+	payload := s.readPayload(r)
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.URL.Path == "/directory":
+		s.writeJSON(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+	case r.URL.Path == "/account":
+		w.Header().Set("Location", s.URL+"/account/1")
+		s.writeJSON(w, http.StatusCreated, map[string]string{"status": acme.StatusValid})
+	case r.URL.Path == "/order":
+		var req struct {
+			Identifiers []struct{ Value string }
+		}
+		assert.NoError(s.t, json.Unmarshal(payload, &req))
+		if s.domains == nil {
+			for _, id := range req.Identifiers {
+				s.domains = append(s.domains, id.Value)
+				s.authzStatus = append(s.authzStatus, acme.StatusPending)
+			}
+		}
+		w.Header().Set("Location", s.URL+"/order/1")
+		s.writeJSON(w, http.StatusCreated, s.order())
+	case r.URL.Path == "/order/1":
+		w.Header().Set("Location", s.URL+"/order/1")
+		s.writeJSON(w, http.StatusOK, s.order())
+	case parts[0] == "authz":
+		s.writeJSON(w, http.StatusOK, s.authorization(s.index(parts[1])))
+	case parts[0] == "challenge":
+		idx := s.index(parts[1])
+		// challenges are accepted with a POST containing an empty object
+		if len(payload) != 0 {
+			s.validate(idx, parts[2])
+		}
+		for _, challenge := range s.challenges(idx) {
+			if challenge["type"] == parts[2] {
+				s.writeJSON(w, http.StatusOK, challenge)
+			}
+		}
+	case r.URL.Path == "/finalize/1":
+		var req struct{ CSR string }
+		assert.NoError(s.t, json.Unmarshal(payload, &req))
+		s.issue(req.CSR)
+		w.Header().Set("Location", s.URL+"/order/1")
+		s.writeJSON(w, http.StatusOK, s.order())
+	case r.URL.Path == "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		w.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.certDER}))
+		w.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw}))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *fakeACMEServer) index(val string) int {
+	idx, err := strconv.Atoi(val)
+	assert.NoError(s.t, err)
+	return idx
+}
+
+func (s *fakeACMEServer) readPayload(r *http.Request) []byte {
+	body, err := ioutil.ReadAll(r.Body)
+	assert.NoError(s.t, err)
+	if len(body) == 0 {
+		return nil
+	}
+	var jws struct{ Payload string }
+	assert.NoError(s.t, json.Unmarshal(body, &jws))
+	payload, err := base64.RawURLEncoding.DecodeString(jws.Payload)
+	assert.NoError(s.t, err)
+	return payload
+}
+
+func (s *fakeACMEServer) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	assert.NoError(s.t, json.NewEncoder(w).Encode(v))
+}
+
+func (s *fakeACMEServer) order() map[string]interface{} {
+	var identifiers []map[string]string
+	var authorizations []string
+	status := acme.StatusReady
+	for i, domain := range s.domains {
+		identifiers = append(identifiers, map[string]string{"type": "dns", "value": domain})
+		authorizations = append(authorizations, fmt.Sprintf("%s/authz/%d", s.URL, i))
+		switch s.authzStatus[i] {
+		case acme.StatusInvalid:
+			status = acme.StatusInvalid
+		case acme.StatusPending:
+			if status != acme.StatusInvalid {
+				status = acme.StatusPending
+			}
+		}
+	}
+	order := map[string]interface{}{
+		"status":         status,
+		"identifiers":    identifiers,
+		"authorizations": authorizations,
+		"finalize":       s.URL + "/finalize/1",
+	}
+	if s.certDER != nil {
+		order["status"] = acme.StatusValid
+		order["certificate"] = s.URL + "/cert/1"
+	}
+	return order
+}
+
+func (s *fakeACMEServer) authorization(idx int) map[string]interface{} {
+	domain := s.domains[idx]
+	return map[string]interface{}{
+		"status":     s.authzStatus[idx],
+		"identifier": map[string]string{"type": "dns", "value": strings.TrimPrefix(domain, "*.")},
+		"wildcard":   strings.HasPrefix(domain, "*."),
+		"challenges": s.challenges(idx),
+	}
+}
+
+// wildcards could only be validated with dns-01
+func (s *fakeACMEServer) challenges(idx int) []map[string]interface{} {
+	var challenges []map[string]interface{}
+	if !strings.HasPrefix(s.domains[idx], "*.") {
+		challenges = append(challenges, s.challenge(idx, "http-01"))
+	}
+	return append(challenges, s.challenge(idx, "dns-01"))
+}
+
+func (s *fakeACMEServer) challenge(idx int, chType string) map[string]interface{} {
+	challenge := map[string]interface{}{
+		"type":   chType,
+		"url":    fmt.Sprintf("%s/challenge/%d/%s", s.URL, idx, chType),
+		"token":  fmt.Sprintf("token-%d-%s", idx, chType),
+		"status": s.authzStatus[idx],
+	}
+	if s.authzStatus[idx] == acme.StatusInvalid {
+		challenge["error"] = map[string]interface{}{
+			"type":   "urn:ietf:params:acme:error:unauthorized",
+			"detail": "invalid response",
+		}
+	}
+	return challenge
+}
+
+func (s *fakeACMEServer) validate(idx int, chType string) {
+	s.validations += 1
+	domain := strings.TrimPrefix(s.domains[idx], "*.")
+	token := fmt.Sprintf("token-%d-%s", idx, chType)
+	valid := false
+	if chType == "dns-01" {
+		expected, err := s.account.DNS01ChallengeRecord(token)
+		assert.NoError(s.t, err)
+		if s.dns != nil {
+			for _, value := range s.dns.records["_acme-challenge."+domain] {
+				valid = valid || value == expected
+			}
+		}
+	} else {
+		expected, err := s.account.HTTP01ChallengeResponse(token)
+		assert.NoError(s.t, err)
+		res := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "http://"+domain+ChallengePathPrefix+token, nil)
+		s.validator.ServeHTTP(res, req)
+		valid = res.Code == http.StatusOK && res.Body.String() == expected
+	}
+	if valid {
+		s.authzStatus[idx] = acme.StatusValid
+	} else {
+		s.authzStatus[idx] = acme.StatusInvalid
+	}
+}
+
+func (s *fakeACMEServer) issue(encodedCSR string) {
+	der, err := base64.RawURLEncoding.DecodeString(encodedCSR)
+	assert.NoError(s.t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	assert.NoError(s.t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	s.certDER, err = x509.CreateCertificate(rand.Reader, template, s.caCert, csr.PublicKey, s.caKey)
+	assert.NoError(s.t, err)
+}
@@ -0,0 +1,56 @@
+package acme
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const (
+	// path that the CA requests when validating http-01 challenges
+	ChallengePathPrefix = "/.well-known/acme-challenge/"
+	ChallengePort       = 8089
+)
+
+// HTTPChallenges keeps track of pending http-01 challenges, and serves their responses
+type HTTPChallenges struct {
+	lock      sync.RWMutex
+	responses map[string]string
+}
+
+func NewHTTPChallenges() *HTTPChallenges {
+	return &HTTPChallenges{
+		responses: make(map[string]string),
+	}
+}
+
+func (h *HTTPChallenges) Set(token, response string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.responses[token] = response
+}
+
+func (h *HTTPChallenges) Remove(token string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	delete(h.responses, token)
+}
+
+func (h *HTTPChallenges) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, ChallengePathPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	token := strings.TrimPrefix(r.URL.Path, ChallengePathPrefix)
+
+	h.lock.RLock()
+	response, ok := h.responses[token]
+	h.lock.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(response))
+}
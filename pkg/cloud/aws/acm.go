@@ -3,7 +3,9 @@ package aws
 import (
 	"context"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/acm"
 
@@ -11,6 +13,8 @@ import (
 	"github.com/k11n/konstellation/pkg/utils/async"
 )
 
+const validationRecordAttempts = 10
+
 type ACMService struct {
 	session *session.Session
 	ACM     *acm.ACM
@@ -77,6 +81,33 @@ func (a *ACMService) ImportCertificate(ctx context.Context, cert []byte, pkey []
 	return
 }
 
+func (a *ACMService) RequestCertificate(ctx context.Context, domain string) (certificate *types.Certificate, err error) {
+	out, err := a.ACM.RequestCertificateWithContext(ctx, &acm.RequestCertificateInput{
+		DomainName:       &domain,
+		ValidationMethod: aws.String(acm.ValidationMethodDns),
+	})
+	if err != nil {
+		return
+	}
+
+	// validation records are populated asynchronously after the request
+	for i := 0; i < validationRecordAttempts; i++ {
+		var res *acm.DescribeCertificateOutput
+		res, err = a.ACM.DescribeCertificateWithContext(ctx, &acm.DescribeCertificateInput{
+			CertificateArn: out.CertificateArn,
+		})
+		if err != nil {
+			return
+		}
+		certificate = certificateFromDetails(res.Certificate)
+		if len(certificate.ValidationRecords) != 0 || certificate.Status != types.CertificateStatusNotReady {
+			return
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return
+}
+
 func certificateFromDetails(acmCert *acm.CertificateDetail) *types.Certificate {
 	cert := &types.Certificate{
 		Domain:        *acmCert.DomainName,
@@ -104,6 +135,17 @@ func certificateFromDetails(acmCert *acm.CertificateDetail) *types.Certificate {
 		cert.Status = types.CertificateStatusError
 	}
 
+	for _, option := range acmCert.DomainValidationOptions {
+		if option.ResourceRecord == nil {
+			continue
+		}
+		cert.ValidationRecords = append(cert.ValidationRecords, types.ValidationRecord{
+			Name:  aws.StringValue(option.ResourceRecord.Name),
+			Type:  aws.StringValue(option.ResourceRecord.Type),
+			Value: aws.StringValue(option.ResourceRecord.Value),
+		})
+	}
+
 	if acmCert.Issuer != nil {
 		cert.Issuer = *acmCert.Issuer
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/aws/aws-sdk-go/service/sts"
+	corev1 "k8s.io/api/core/v1"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
 	"github.com/k11n/konstellation/pkg/cloud/types"
@@ -205,6 +206,20 @@ func (s *EKSService) IsNodepoolDeleted(ctx context.Context, clusterName string,
 	return true, nil
 }
 
+func (s *EKSService) GetNodepoolASG(ctx context.Context, clusterName string, nodepoolName string) (string, error) {
+	res, err := s.EKS.DescribeNodegroupWithContext(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   &clusterName,
+		NodegroupName: &nodepoolName,
+	})
+	if err != nil {
+		return "", err
+	}
+	if res.Nodegroup.Resources == nil || len(res.Nodegroup.Resources.AutoScalingGroups) == 0 {
+		return "", fmt.Errorf("nodepool %s does not have an autoscaling group", nodepoolName)
+	}
+	return aws.StringValue(res.Nodegroup.Resources.AutoScalingGroups[0].Name), nil
+}
+
 func (s *EKSService) DeleteNodeGroupNetworkingResources(ctx context.Context, nodegroup string) error {
 	ec2Svc := ec2.New(s.session)
 
@@ -272,6 +287,26 @@ func (s *EKSService) CreateNodepool(ctx context.Context, cc *v1alpha1.ClusterCon
 	return err
 }
 
+// updates sizes, labels, and taints of an existing nodepool, other changes require a new nodepool
+func (s *EKSService) UpdateNodepool(ctx context.Context, clusterName string, np *v1alpha1.Nodepool) error {
+	res, err := s.EKS.DescribeNodegroupWithContext(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   &clusterName,
+		NodegroupName: &np.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	updateInput := nodepoolSpecToUpdateInput(res.Nodegroup, np)
+	if updateInput.ScalingConfig == nil && updateInput.Labels == nil && updateInput.Taints == nil {
+		// nothing to update
+		return nil
+	}
+	updateInput.SetClusterName(clusterName)
+	_, err = s.EKS.UpdateNodegroupConfigWithContext(ctx, updateInput)
+	return err
+}
+
 func (s *EKSService) DeleteNodepool(ctx context.Context, clusterName string, nodePool string) error {
 	_, err := s.EKS.DeleteNodegroupWithContext(ctx, &eks.DeleteNodegroupInput{
 		ClusterName:   &clusterName,
@@ -336,7 +371,18 @@ func nodepoolSpecToCreateInput(cc *v1alpha1.ClusterConfig, np *v1alpha1.Nodepool
 	cni.SetClusterName(cc.Name)
 	cni.SetAmiType(nps.AWS.AMIType)
 	cni.SetDiskSize(int64(nps.DiskSizeGiB))
-	cni.SetInstanceTypes([]*string{&nps.MachineType})
+	cni.SetInstanceTypes(aws.StringSlice(nps.GetMachineTypes()))
+	if nps.AWS.IsSpot() {
+		cni.SetCapacityType(eks.CapacityTypesSpot)
+	} else {
+		cni.SetCapacityType(eks.CapacityTypesOnDemand)
+	}
+	if len(nps.Labels) != 0 {
+		cni.SetLabels(aws.StringMap(nps.Labels))
+	}
+	if len(nps.Taints) != 0 {
+		cni.SetTaints(eksTaints(nps.Taints))
+	}
 	cni.SetNodeRole(awsStatus.NodeRoleArn)
 	cni.SetNodegroupName(np.ObjectMeta.Name)
 	cni.SetScalingConfig(&eks.NodegroupScalingConfig{
@@ -380,6 +426,100 @@ func nodepoolSpecToCreateInput(cc *v1alpha1.ClusterConfig, np *v1alpha1.Nodepool
 	return
 }
 
+// returns changes to the nodegroup's scaling config, labels, and taints, fields are left nil when unchanged
+func nodepoolSpecToUpdateInput(ng *eks.Nodegroup, np *v1alpha1.Nodepool) *eks.UpdateNodegroupConfigInput {
+	nps := np.Spec
+	uni := &eks.UpdateNodegroupConfigInput{}
+	uni.SetNodegroupName(np.Name)
+
+	sc := ng.ScalingConfig
+	if sc == nil || aws.Int64Value(sc.MinSize) != nps.MinSize || aws.Int64Value(sc.MaxSize) != nps.MaxSize {
+		// keep the current size when it's still within bounds
+		var desired int64
+		if sc != nil {
+			desired = aws.Int64Value(sc.DesiredSize)
+		}
+		if desired < nps.MinSize {
+			desired = nps.MinSize
+		} else if desired > nps.MaxSize {
+			desired = nps.MaxSize
+		}
+		uni.SetScalingConfig(&eks.NodegroupScalingConfig{
+			MinSize:     aws.Int64(nps.MinSize),
+			MaxSize:     aws.Int64(nps.MaxSize),
+			DesiredSize: aws.Int64(desired),
+		})
+	}
+
+	labels := &eks.UpdateLabelsPayload{}
+	for key, val := range nps.Labels {
+		if existing, ok := ng.Labels[key]; !ok || aws.StringValue(existing) != val {
+			if labels.AddOrUpdateLabels == nil {
+				labels.AddOrUpdateLabels = make(map[string]*string)
+			}
+			labels.AddOrUpdateLabels[key] = aws.String(val)
+		}
+	}
+	for key := range ng.Labels {
+		if _, ok := nps.Labels[key]; !ok {
+			labels.RemoveLabels = append(labels.RemoveLabels, aws.String(key))
+		}
+	}
+	if len(labels.AddOrUpdateLabels) != 0 || len(labels.RemoveLabels) != 0 {
+		uni.SetLabels(labels)
+	}
+
+	// taints are identified by their key and effect
+	taints := &eks.UpdateTaintsPayload{}
+	desired := eksTaints(nps.Taints)
+	for _, taint := range desired {
+		existing := findEKSTaint(ng.Taints, taint)
+		if existing == nil || aws.StringValue(existing.Value) != aws.StringValue(taint.Value) {
+			taints.AddOrUpdateTaints = append(taints.AddOrUpdateTaints, taint)
+		}
+	}
+	for _, taint := range ng.Taints {
+		if findEKSTaint(desired, taint) == nil {
+			taints.RemoveTaints = append(taints.RemoveTaints, taint)
+		}
+	}
+	if len(taints.AddOrUpdateTaints) != 0 || len(taints.RemoveTaints) != 0 {
+		uni.SetTaints(taints)
+	}
+
+	return uni
+}
+
+var eksTaintEffects = map[corev1.TaintEffect]string{
+	corev1.TaintEffectNoSchedule:       eks.TaintEffectNoSchedule,
+	corev1.TaintEffectPreferNoSchedule: eks.TaintEffectPreferNoSchedule,
+	corev1.TaintEffectNoExecute:        eks.TaintEffectNoExecute,
+}
+
+func eksTaints(taints []corev1.Taint) []*eks.Taint {
+	var converted []*eks.Taint
+	for _, taint := range taints {
+		t := &eks.Taint{
+			Key:    aws.String(taint.Key),
+			Effect: aws.String(eksTaintEffects[taint.Effect]),
+		}
+		if taint.Value != "" {
+			t.Value = aws.String(taint.Value)
+		}
+		converted = append(converted, t)
+	}
+	return converted
+}
+
+func findEKSTaint(taints []*eks.Taint, taint *eks.Taint) *eks.Taint {
+	for _, t := range taints {
+		if aws.StringValue(t.Key) == aws.StringValue(taint.Key) && aws.StringValue(t.Effect) == aws.StringValue(taint.Effect) {
+			return t
+		}
+	}
+	return nil
+}
+
 func clusterFromEksCluster(ec *eks.Cluster) *types.Cluster {
 	cluster := &types.Cluster{
 		ID:              *ec.Arn,
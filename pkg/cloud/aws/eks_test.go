@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestNodepoolSpecToUpdateInput(t *testing.T) {
+	ng := &eks.Nodegroup{
+		ScalingConfig: &eks.NodegroupScalingConfig{
+			MinSize:     aws.Int64(1),
+			MaxSize:     aws.Int64(5),
+			DesiredSize: aws.Int64(4),
+		},
+		Labels: aws.StringMap(map[string]string{
+			"workload": "batch",
+			"team":     "data",
+		}),
+	}
+	np := &v1alpha1.Nodepool{
+		ObjectMeta: metav1.ObjectMeta{Name: "kon-nodepool"},
+		Spec: v1alpha1.NodepoolSpec{
+			MinSize: 1,
+			MaxSize: 5,
+			Labels:  map[string]string{"workload": "batch", "team": "data"},
+		},
+	}
+
+	uni := nodepoolSpecToUpdateInput(ng, np)
+	assert.Nil(t, uni.ScalingConfig)
+	assert.Nil(t, uni.Labels)
+
+	np.Spec.MaxSize = 3
+	np.Spec.Labels = map[string]string{"workload": "web", "tier": "frontend"}
+	uni = nodepoolSpecToUpdateInput(ng, np)
+	assert.Equal(t, "kon-nodepool", *uni.NodegroupName)
+	assert.EqualValues(t, 1, *uni.ScalingConfig.MinSize)
+	assert.EqualValues(t, 3, *uni.ScalingConfig.MaxSize)
+	assert.EqualValues(t, 3, *uni.ScalingConfig.DesiredSize)
+	assert.Equal(t, map[string]string{"workload": "web", "tier": "frontend"},
+		aws.StringValueMap(uni.Labels.AddOrUpdateLabels))
+	assert.Equal(t, []string{"team"}, aws.StringValueSlice(uni.Labels.RemoveLabels))
+	assert.Nil(t, uni.Taints)
+}
+
+func TestNodepoolTaintsToUpdateInput(t *testing.T) {
+	ng := &eks.Nodegroup{
+		Taints: []*eks.Taint{
+			{Key: aws.String("dedicated"), Value: aws.String("batch"), Effect: aws.String(eks.TaintEffectNoSchedule)},
+			{Key: aws.String("spot"), Effect: aws.String(eks.TaintEffectPreferNoSchedule)},
+		},
+	}
+	np := &v1alpha1.Nodepool{
+		Spec: v1alpha1.NodepoolSpec{
+			Taints: []corev1.Taint{
+				{Key: "dedicated", Value: "batch", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+			},
+		},
+	}
+	assert.Nil(t, nodepoolSpecToUpdateInput(ng, np).Taints)
+
+	np.Spec.Taints = []corev1.Taint{
+		{Key: "dedicated", Value: "web", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "gpu", Effect: corev1.TaintEffectNoExecute},
+	}
+	uni := nodepoolSpecToUpdateInput(ng, np)
+	assert.Equal(t, []*eks.Taint{
+		{Key: aws.String("dedicated"), Value: aws.String("web"), Effect: aws.String(eks.TaintEffectNoSchedule)},
+		{Key: aws.String("gpu"), Effect: aws.String(eks.TaintEffectNoExecute)},
+	}, uni.Taints.AddOrUpdateTaints)
+	assert.Equal(t, []*eks.Taint{ng.Taints[1]}, uni.Taints.RemoveTaints)
+}
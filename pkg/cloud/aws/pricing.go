@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/pricing"
 	"github.com/spf13/cast"
 )
@@ -25,6 +27,8 @@ type EC2InstancePricing struct {
 	SupportsEnhancedNetworking bool
 	NetworkPerformance         string
 	OnDemandPriceUSD           float32
+	// set with SetSpotPrices, averaged across availability zones
+	SpotPriceUSD float32
 }
 
 var regionMapping = map[string]string{
@@ -110,6 +114,58 @@ func ListEC2Instances(svc *pricing.Pricing, region string, currentOnly bool) (in
 	return
 }
 
+// fills in current spot prices of the instances. spot prices aren't in the pricing API, they are from the
+// EC2 spot price history of the region
+func SetSpotPrices(svc *ec2.EC2, instances []*EC2InstancePricing) error {
+	if len(instances) == 0 {
+		return nil
+	}
+	instanceTypes := make([]*string, 0, len(instances))
+	for _, inst := range instances {
+		instanceTypes = append(instanceTypes, aws.String(inst.InstanceType))
+	}
+
+	// latest price in each zone
+	zonePrices := make(map[string]map[string]float32)
+	input := &ec2.DescribeSpotPriceHistoryInput{
+		InstanceTypes:       instanceTypes,
+		ProductDescriptions: []*string{aws.String("Linux/UNIX")},
+		StartTime:           aws.Time(time.Now()),
+	}
+	err := svc.DescribeSpotPriceHistoryPages(input, func(res *ec2.DescribeSpotPriceHistoryOutput, lastPage bool) bool {
+		for _, sp := range res.SpotPriceHistory {
+			instanceType := aws.StringValue(sp.InstanceType)
+			if zonePrices[instanceType] == nil {
+				zonePrices[instanceType] = make(map[string]float32)
+			}
+			zone := aws.StringValue(sp.AvailabilityZone)
+			if _, ok := zonePrices[instanceType][zone]; !ok {
+				zonePrices[instanceType][zone] = cast.ToFloat32(aws.StringValue(sp.SpotPrice))
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range instances {
+		inst.SpotPriceUSD = averagePrice(zonePrices[inst.InstanceType])
+	}
+	return nil
+}
+
+func averagePrice(prices map[string]float32) float32 {
+	if len(prices) == 0 {
+		return 0
+	}
+	var total float32
+	for _, p := range prices {
+		total += p
+	}
+	return total / float32(len(prices))
+}
+
 func createPricingFilter(field, value string) *pricing.Filter {
 	f := &pricing.Filter{}
 	f.SetType(pricing.FilterTypeTermMatch)
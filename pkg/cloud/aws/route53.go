@@ -0,0 +1,408 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/elb"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+
+	"github.com/k11n/konstellation/pkg/cloud/types"
+)
+
+const (
+	recordTTL = 300
+	// short lived, so that changes are seen quickly
+	txtRecordTTL = 60
+	// route53 escapes wildcards in record names
+	escapedWildcard = "\\052"
+	// TXT records that mark records as owned, like external-dns does. they can't share the name of a CNAME
+	ownerRecordPrefix         = "_konstellation."
+	ownerRecordWildcardPrefix = "_konstellation-wildcard."
+)
+
+type Route53Service struct {
+	session    *session.Session
+	Route53    *route53.Route53
+	ELBV2      *elbv2.ELBV2
+	ClassicELB *elb.ELB
+	// identifies records created by this cluster
+	ownerID string
+}
+
+func NewRoute53Service(s *session.Session, ownerID string) *Route53Service {
+	return &Route53Service{
+		session:    s,
+		Route53:    route53.New(s),
+		ELBV2:      elbv2.New(s),
+		ClassicELB: elb.New(s),
+		ownerID:    ownerID,
+	}
+}
+
+// creates a CNAME for the host, or an A record for IP addresses.
+// CNAMEs aren't allowed at the zone apex, it's aliased to the load balancer instead.
+// an ownership record is kept alongside it, existing records that aren't owned by the cluster are left alone
+func (r *Route53Service) UpsertRecord(ctx context.Context, record *types.DNSRecord) error {
+	name := normalizeName(record.Host)
+	zones, err := r.zonesForName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		return fmt.Errorf("could not find a hosted zone for %s", record.Host)
+	}
+
+	for _, zone := range zones {
+		owner, err := r.getOwnerRecordSet(ctx, zone, name)
+		if err != nil {
+			return err
+		}
+		if owner == nil {
+			existing, err := r.getAddressRecordSets(ctx, zone, name)
+			if err != nil {
+				return err
+			}
+			for _, rrs := range existing {
+				// records that already point to the target are taken over
+				if !recordPointsTo(rrs, record.Target) {
+					return fmt.Errorf("%s has a record that isn't managed by Konstellation, it won't be replaced", record.Host)
+				}
+			}
+		}
+
+		rrs := &route53.ResourceRecordSet{
+			Name: aws.String(name),
+		}
+		if net.ParseIP(record.Target) != nil {
+			rrs.Type = aws.String(route53.RRTypeA)
+			rrs.TTL = aws.Int64(recordTTL)
+			rrs.ResourceRecords = []*route53.ResourceRecord{
+				{Value: aws.String(record.Target)},
+			}
+		} else if name == normalizeName(*zone.Name) {
+			lbZoneId, err := r.loadBalancerZoneId(ctx, record.Target)
+			if err != nil {
+				return err
+			}
+			rrs.Type = aws.String(route53.RRTypeA)
+			rrs.AliasTarget = &route53.AliasTarget{
+				DNSName:              aws.String(record.Target),
+				HostedZoneId:         aws.String(lbZoneId),
+				EvaluateTargetHealth: aws.Bool(false),
+			}
+		} else {
+			rrs.Type = aws.String(route53.RRTypeCname)
+			rrs.TTL = aws.Int64(recordTTL)
+			rrs.ResourceRecords = []*route53.ResourceRecord{
+				{Value: aws.String(record.Target)},
+			}
+		}
+
+		err = r.changeRecords(ctx, zone,
+			newChange(route53.ChangeActionUpsert, rrs),
+			newChange(route53.ChangeActionUpsert, r.ownerRecordSet(name)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removes the record and its ownership record. records owned by others, or that were changed
+// to point elsewhere, are left alone
+func (r *Route53Service) DeleteRecord(ctx context.Context, record *types.DNSRecord) error {
+	name := normalizeName(record.Host)
+	zones, err := r.zonesForName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	for _, zone := range zones {
+		owner, err := r.getOwnerRecordSet(ctx, zone, name)
+		if err != nil {
+			return err
+		}
+		if owner == nil {
+			continue
+		}
+		existing, err := r.getAddressRecordSets(ctx, zone, name)
+		if err != nil {
+			return err
+		}
+		// deletions have to match the existing record exactly
+		changes := []*route53.Change{
+			newChange(route53.ChangeActionDelete, owner),
+		}
+		for _, rrs := range existing {
+			if recordPointsTo(rrs, record.Target) {
+				changes = append(changes, newChange(route53.ChangeActionDelete, rrs))
+			}
+		}
+		if err = r.changeRecords(ctx, zone, changes...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the ownership record of the name, nil when it's not owned by this cluster
+func (r *Route53Service) getOwnerRecordSet(ctx context.Context, zone *route53.HostedZone, name string) (*route53.ResourceRecordSet, error) {
+	owner, err := r.getTXTRecordSet(ctx, zone, ownerRecordName(name))
+	if err != nil || owner == nil {
+		return nil, err
+	}
+	if txtRecordIndex(owner, strconv.Quote(r.ownerValue())) == -1 {
+		return nil, nil
+	}
+	return owner, nil
+}
+
+// returns A, AAAA and CNAME records of the name
+func (r *Route53Service) getAddressRecordSets(ctx context.Context, zone *route53.HostedZone, name string) ([]*route53.ResourceRecordSet, error) {
+	out, err := r.Route53.ListResourceRecordSetsWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    zone.Id,
+		StartRecordName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var records []*route53.ResourceRecordSet
+	for _, rrs := range out.ResourceRecordSets {
+		if normalizeName(aws.StringValue(rrs.Name)) != name {
+			break
+		}
+		switch aws.StringValue(rrs.Type) {
+		case route53.RRTypeA, route53.RRTypeAaaa, route53.RRTypeCname:
+			records = append(records, rrs)
+		}
+	}
+	return records, nil
+}
+
+func (r *Route53Service) ownerValue() string {
+	return "heritage=konstellation,konstellation/owner=" + r.ownerID
+}
+
+func (r *Route53Service) ownerRecordSet(name string) *route53.ResourceRecordSet {
+	return &route53.ResourceRecordSet{
+		Name: aws.String(ownerRecordName(name)),
+		Type: aws.String(route53.RRTypeTxt),
+		TTL:  aws.Int64(recordTTL),
+		ResourceRecords: []*route53.ResourceRecord{
+			{Value: aws.String(strconv.Quote(r.ownerValue()))},
+		},
+	}
+}
+
+// wildcards have to be the leftmost label, so they are replaced in the name of the ownership record
+func ownerRecordName(name string) string {
+	if strings.HasPrefix(name, "*.") {
+		return ownerRecordWildcardPrefix + strings.TrimPrefix(name, "*.")
+	}
+	return ownerRecordPrefix + name
+}
+
+// adds the value to the TXT record. values are added alongside existing ones, since validating
+// a wildcard and its apex domain both use the same name
+func (r *Route53Service) UpsertTXTRecord(ctx context.Context, name, value string) error {
+	name = normalizeName(name)
+	zones, err := r.zonesForName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(zones) == 0 {
+		return fmt.Errorf("could not find a hosted zone for %s", name)
+	}
+
+	quoted := strconv.Quote(value)
+	for _, zone := range zones {
+		rrs, err := r.getTXTRecordSet(ctx, zone, name)
+		if err != nil {
+			return err
+		}
+		if rrs == nil {
+			rrs = &route53.ResourceRecordSet{
+				Name: aws.String(name),
+				Type: aws.String(route53.RRTypeTxt),
+			}
+		}
+		if txtRecordIndex(rrs, quoted) != -1 {
+			continue
+		}
+		rrs.TTL = aws.Int64(txtRecordTTL)
+		rrs.ResourceRecords = append(rrs.ResourceRecords, &route53.ResourceRecord{Value: aws.String(quoted)})
+		if err = r.changeRecord(ctx, zone, route53.ChangeActionUpsert, rrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removes the value from the TXT record, deleting the record when no other values are left
+func (r *Route53Service) DeleteTXTRecord(ctx context.Context, name, value string) error {
+	name = normalizeName(name)
+	zones, err := r.zonesForName(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	quoted := strconv.Quote(value)
+	for _, zone := range zones {
+		rrs, err := r.getTXTRecordSet(ctx, zone, name)
+		if err != nil {
+			return err
+		}
+		if rrs == nil {
+			continue
+		}
+		idx := txtRecordIndex(rrs, quoted)
+		if idx == -1 {
+			continue
+		}
+		if len(rrs.ResourceRecords) == 1 {
+			// deletions have to match the existing record exactly
+			err = r.changeRecord(ctx, zone, route53.ChangeActionDelete, rrs)
+		} else {
+			rrs.ResourceRecords = append(rrs.ResourceRecords[:idx], rrs.ResourceRecords[idx+1:]...)
+			err = r.changeRecord(ctx, zone, route53.ChangeActionUpsert, rrs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Route53Service) getTXTRecordSet(ctx context.Context, zone *route53.HostedZone, name string) (*route53.ResourceRecordSet, error) {
+	out, err := r.Route53.ListResourceRecordSetsWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    zone.Id,
+		StartRecordName: aws.String(name),
+		StartRecordType: aws.String(route53.RRTypeTxt),
+		MaxItems:        aws.String("1"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, rrs := range out.ResourceRecordSets {
+		if normalizeName(aws.StringValue(rrs.Name)) == name && aws.StringValue(rrs.Type) == route53.RRTypeTxt {
+			return rrs, nil
+		}
+	}
+	return nil, nil
+}
+
+func txtRecordIndex(rrs *route53.ResourceRecordSet, quoted string) int {
+	for i, rr := range rrs.ResourceRecords {
+		if aws.StringValue(rr.Value) == quoted {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Route53Service) changeRecord(ctx context.Context, zone *route53.HostedZone, action string, rrs *route53.ResourceRecordSet) error {
+	return r.changeRecords(ctx, zone, newChange(action, rrs))
+}
+
+// changes in a batch are applied together, or not at all
+func (r *Route53Service) changeRecords(ctx context.Context, zone *route53.HostedZone, changes ...*route53.Change) error {
+	_, err := r.Route53.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: zone.Id,
+		ChangeBatch: &route53.ChangeBatch{
+			Comment: aws.String("Managed by Konstellation"),
+			Changes: changes,
+		},
+	})
+	return err
+}
+
+func newChange(action string, rrs *route53.ResourceRecordSet) *route53.Change {
+	return &route53.Change{
+		Action:            aws.String(action),
+		ResourceRecordSet: rrs,
+	}
+}
+
+// finds the most specific zones containing the name. public and private zones could share a domain,
+// records are kept in both of them
+func (r *Route53Service) zonesForName(ctx context.Context, name string) (zones []*route53.HostedZone, err error) {
+	longest := 0
+	err = r.Route53.ListHostedZonesPagesWithContext(ctx, &route53.ListHostedZonesInput{},
+		func(out *route53.ListHostedZonesOutput, lastPage bool) bool {
+			for _, zone := range out.HostedZones {
+				zoneName := normalizeName(aws.StringValue(zone.Name))
+				if name != zoneName && !strings.HasSuffix(name, "."+zoneName) {
+					continue
+				}
+				if len(zoneName) > longest {
+					longest = len(zoneName)
+					zones = nil
+				}
+				if len(zoneName) == longest {
+					zones = append(zones, zone)
+				}
+			}
+			return true
+		})
+	return
+}
+
+// aliases need the hosted zone of the load balancer
+func (r *Route53Service) loadBalancerZoneId(ctx context.Context, dnsName string) (zoneId string, err error) {
+	err = r.ELBV2.DescribeLoadBalancersPagesWithContext(ctx, &elbv2.DescribeLoadBalancersInput{},
+		func(out *elbv2.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range out.LoadBalancers {
+				if strings.EqualFold(aws.StringValue(lb.DNSName), dnsName) {
+					zoneId = aws.StringValue(lb.CanonicalHostedZoneId)
+					return false
+				}
+			}
+			return true
+		})
+	if err != nil || zoneId != "" {
+		return
+	}
+
+	err = r.ClassicELB.DescribeLoadBalancersPagesWithContext(ctx, &elb.DescribeLoadBalancersInput{},
+		func(out *elb.DescribeLoadBalancersOutput, lastPage bool) bool {
+			for _, lb := range out.LoadBalancerDescriptions {
+				if strings.EqualFold(aws.StringValue(lb.DNSName), dnsName) {
+					zoneId = aws.StringValue(lb.CanonicalHostedZoneNameID)
+					return false
+				}
+			}
+			return true
+		})
+	if err == nil && zoneId == "" {
+		err = fmt.Errorf("could not find load balancer %s", dnsName)
+	}
+	return
+}
+
+func recordPointsTo(rrs *route53.ResourceRecordSet, target string) bool {
+	target = normalizeName(target)
+	if rrs.AliasTarget != nil {
+		alias := normalizeName(aws.StringValue(rrs.AliasTarget.DNSName))
+		return alias == target || alias == "dualstack."+target
+	}
+	for _, rr := range rrs.ResourceRecords {
+		if normalizeName(aws.StringValue(rr.Value)) == target {
+			return true
+		}
+	}
+	return false
+}
+
+// lowercase names without the trailing dot
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, escapedWildcard, "*"))
+	return strings.TrimSuffix(name, ".")
+}
@@ -0,0 +1,31 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerRecordName(t *testing.T) {
+	assert.Equal(t, "_konstellation.www.example.com", ownerRecordName("www.example.com"))
+	assert.Equal(t, "_konstellation-wildcard.example.com", ownerRecordName("*.example.com"))
+}
+
+func TestRecordPointsTo(t *testing.T) {
+	cname := &route53.ResourceRecordSet{
+		ResourceRecords: []*route53.ResourceRecord{
+			{Value: aws.String("lb-1234.us-west-2.elb.amazonaws.com.")},
+		},
+	}
+	assert.True(t, recordPointsTo(cname, "lb-1234.us-west-2.elb.amazonaws.com"))
+	assert.False(t, recordPointsTo(cname, "lb-5678.us-west-2.elb.amazonaws.com"))
+
+	alias := &route53.ResourceRecordSet{
+		AliasTarget: &route53.AliasTarget{
+			DNSName: aws.String("dualstack.lb-1234.us-west-2.elb.amazonaws.com."),
+		},
+	}
+	assert.True(t, recordPointsTo(alias, "lb-1234.us-west-2.elb.amazonaws.com"))
+}
@@ -15,11 +15,30 @@ type KubernetesProvider interface {
 	GetAuthToken(ctx context.Context, cluster string, status types.ClusterStatus) (*types.AuthToken, error)
 	IsNodepoolReady(ctx context.Context, clusterName string, nodepoolName string) (bool, error)
 	CreateNodepool(ctx context.Context, cc *v1alpha1.ClusterConfig, np *v1alpha1.Nodepool) error
+	// updates sizes, labels, and taints of an existing nodepool
+	UpdateNodepool(ctx context.Context, clusterName string, np *v1alpha1.Nodepool) error
+	DeleteNodepool(ctx context.Context, clusterName string, nodepoolName string) error
+	IsNodepoolDeleted(ctx context.Context, clusterName string, nodepoolName string) (bool, error)
+	// returns the autoscaling group that nodes of the nodepool are in
+	GetNodepoolASG(ctx context.Context, clusterName string, nodepoolName string) (string, error)
 }
 
 type CertificateProvider interface {
 	ListCertificates(context.Context) ([]*types.Certificate, error)
 	ImportCertificate(ctx context.Context, cert []byte, pkey []byte, chain []byte, existingID string) (*types.Certificate, error)
+	// requests the provider to issue a certificate, returned certificate includes records needed for DNS validation
+	RequestCertificate(ctx context.Context, domain string) (*types.Certificate, error)
+}
+
+// manages records that point hosts to load balancers
+type DNSProvider interface {
+	// creates the record, or updates an existing one for the host
+	UpsertRecord(ctx context.Context, record *types.DNSRecord) error
+	// removes the record when it still points to the target
+	DeleteRecord(ctx context.Context, record *types.DNSRecord) error
+	// adds and removes values of TXT records, used to validate domain ownership
+	UpsertTXTRecord(ctx context.Context, name, value string) error
+	DeleteTXTRecord(ctx context.Context, name, value string) error
 }
 
 type StorageProvider interface {
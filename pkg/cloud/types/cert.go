@@ -30,4 +30,12 @@ type Certificate struct {
 	KeyAlgorithm       string
 	SignatureAlgorithm string
 	Status             CertificateStatus
+	ValidationRecords  []ValidationRecord
+}
+
+// DNS record that proves ownership of a domain
+type ValidationRecord struct {
+	Name  string
+	Type  string
+	Value string
 }
@@ -0,0 +1,7 @@
+package types
+
+type DNSRecord struct {
+	Host string
+	// hostname or IP address that the host resolves to
+	Target string
+}
@@ -2,15 +2,50 @@ package autoscaler
 
 import (
 	"bytes"
-	"io/ioutil"
-	"text/template"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/k11n/konstellation/api/v1alpha1"
 	"github.com/k11n/konstellation/pkg/components"
 	"github.com/k11n/konstellation/pkg/resources"
-	"github.com/k11n/konstellation/pkg/utils/assets"
-	"github.com/k11n/konstellation/pkg/utils/cli"
+)
+
+const (
+	ComponentName  = "cluster-autoscaler"
+	Namespace      = "kube-system"
+	DeploymentName = "cluster-autoscaler"
+	// ConfigMap that the priority expander reads from
+	PriorityConfigMapName = "cluster-autoscaler-priority-expander"
+
+	// ComponentConfig keys
+	// time to wait after a scale up before scale down is evaluated, i.e. 10m
+	ScaleDownDelayKey = "scale-down-delay"
+	// nodes with utilization below the threshold are considered for removal, between 0 and 1
+	UtilizationThresholdKey = "scale-down-utilization-threshold"
+	// strategy to choose the nodepool to scale up, least-waste (default), priority, most-pods, or random
+	ExpanderKey = "expander"
+	// comma separated priorities used with the priority expander, nodepool=priority
+	NodepoolPrioritiesKey = "nodepool-priorities"
+	// comma separated nodepools that the autoscaler should not scale
+	DisabledNodepoolsKey = "disabled-nodepools"
+
+	ExpanderLeastWaste = "least-waste"
+	ExpanderPriority   = "priority"
+	ExpanderMostPods   = "most-pods"
+	ExpanderRandom     = "random"
+
+	// nodepools without an explicit priority, spot nodepools are preferred as they are cheaper
+	DefaultNodepoolPriority = 10
+	DefaultSpotPriority     = 20
 )
 
 func init() {
@@ -29,7 +64,7 @@ type ClusterAutoScaler struct {
 }
 
 func (s *ClusterAutoScaler) Name() string {
-	return "cluster-autoscaler"
+	return ComponentName
 }
 func (s *ClusterAutoScaler) VersionForKube(version string) string {
 	return versionMap[version]
@@ -38,6 +73,7 @@ func (s *ClusterAutoScaler) VersionForKube(version string) string {
 type autoScalerConfig struct {
 	ClusterName string
 	Version     string
+	Command     []string
 }
 
 func (s *ClusterAutoScaler) InstallComponent(kclient client.Client) error {
@@ -46,32 +82,162 @@ func (s *ClusterAutoScaler) InstallComponent(kclient client.Client) error {
 	if err != nil {
 		return err
 	}
-
-	box := assets.DeployResourcesBox()
-	f, err := box.Open("templates/cluster-autoscaler.yaml")
+	nodepools, err := resources.GetNodepools(kclient)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	content, err := ioutil.ReadAll(f)
-	if err != nil {
+
+	conf := autoScalerConfig{
+		ClusterName: cc.Name,
+		Version:     s.VersionForKube(cc.Spec.KubeVersion),
+		Command:     Command(cc, nodepools),
+	}
+	if err = components.ApplyTemplate("cluster-autoscaler", conf); err != nil {
 		return err
 	}
 
-	tmpl, err := template.New("autoscaler").Parse(string(content))
-	if err != nil {
+	if Expander(cc.Spec.ComponentConfig[ComponentName]) == ExpanderPriority {
+		_, err = resources.UpdateResource(kclient, PriorityConfigMap(cc, nodepools), nil, nil)
 		return err
 	}
+	return DeletePriorityConfigMap(kclient)
+}
 
-	conf := autoScalerConfig{
-		ClusterName: cc.Name,
-		Version:     s.VersionForKube(cc.Spec.KubeVersion),
+// returns the expander that's configured, invalid values are ignored
+func Expander(config v1alpha1.ComponentConfig) string {
+	switch val := config[ExpanderKey]; val {
+	case ExpanderPriority, ExpanderMostPods, ExpanderRandom:
+		return val
+	default:
+		return ExpanderLeastWaste
+	}
+}
+
+// returns the autoscaler command for the cluster's ComponentConfig.
+// nodepools are auto-discovered unless some are disabled, then the ones to scale are listed explicitly
+func Command(cc *v1alpha1.ClusterConfig, nodepools []*v1alpha1.Nodepool) []string {
+	config := cc.Spec.ComponentConfig[ComponentName]
+	command := []string{
+		"./cluster-autoscaler",
+		"--v=4",
+		"--stderrthreshold=info",
+		"--cloud-provider=aws",
+		"--skip-nodes-with-local-storage=false",
+		fmt.Sprintf("--expander=%s", Expander(config)),
+	}
+
+	if val := config[ScaleDownDelayKey]; val != "" {
+		if _, err := time.ParseDuration(val); err == nil {
+			command = append(command, fmt.Sprintf("--scale-down-delay-after-add=%s", val))
+		}
+	}
+	if val := config[UtilizationThresholdKey]; val != "" {
+		if threshold, err := strconv.ParseFloat(val, 64); err == nil && threshold > 0 && threshold <= 1 {
+			command = append(command, fmt.Sprintf("--scale-down-utilization-threshold=%s", val))
+		}
 	}
+
+	disabled := splitList(config[DisabledNodepoolsKey])
+	if len(disabled) == 0 {
+		return append(command,
+			fmt.Sprintf("--node-group-auto-discovery=asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/%s", cc.Name))
+	}
+
+	for _, np := range scalableNodepools(nodepools) {
+		isDisabled := false
+		for _, name := range disabled {
+			if name == np.Name {
+				isDisabled = true
+				break
+			}
+		}
+		if !isDisabled {
+			command = append(command, fmt.Sprintf("--nodes=%d:%d:%s", np.Spec.MinSize, np.Spec.MaxSize, np.Status.AWS.ASGID))
+		}
+	}
+	return command
+}
+
+// returns config for the priority expander, matching on autoscaling groups of the nodepools
+func PriorityConfigMap(cc *v1alpha1.ClusterConfig, nodepools []*v1alpha1.Nodepool) *corev1.ConfigMap {
+	configured := make(map[string]int)
+	for _, item := range splitList(cc.Spec.ComponentConfig[ComponentName][NodepoolPrioritiesKey]) {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if priority, err := strconv.Atoi(parts[1]); err == nil {
+			configured[parts[0]] = priority
+		}
+	}
+
+	groups := make(map[int][]string)
+	for _, np := range scalableNodepools(nodepools) {
+		priority, ok := configured[np.Name]
+		if !ok {
+			priority = DefaultNodepoolPriority
+			if np.Spec.AWS.IsSpot() {
+				priority = DefaultSpotPriority
+			}
+		}
+		groups[priority] = append(groups[priority], fmt.Sprintf("^%s$", regexp.QuoteMeta(np.Status.AWS.ASGID)))
+	}
+
+	priorities := make([]int, 0, len(groups))
+	for priority := range groups {
+		priorities = append(priorities, priority)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(priorities)))
+
 	buf := bytes.NewBuffer(nil)
-	err = tmpl.Execute(buf, conf)
-	if err != nil {
-		return err
+	for _, priority := range priorities {
+		fmt.Fprintf(buf, "%d:\n", priority)
+		for _, pattern := range groups[priority] {
+			fmt.Fprintf(buf, "  - '%s'\n", pattern)
+		}
 	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: Namespace,
+			Name:      PriorityConfigMapName,
+		},
+		Data: map[string]string{
+			"priorities": buf.String(),
+		},
+	}
+}
 
-	return cli.KubeApplyReader(buf)
+// removes config of the priority expander, when a different expander is configured
+func DeletePriorityConfigMap(kclient client.Client) error {
+	return client.IgnoreNotFound(kclient.Delete(context.Background(), &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: Namespace,
+			Name:      PriorityConfigMapName,
+		},
+	}))
+}
+
+// nodepools that have autoscale enabled and their autoscaling group known
+func scalableNodepools(nodepools []*v1alpha1.Nodepool) []*v1alpha1.Nodepool {
+	var scalable []*v1alpha1.Nodepool
+	for _, np := range nodepools {
+		if np.Spec.Autoscale && np.Status.AWS != nil && np.Status.AWS.ASGID != "" {
+			scalable = append(scalable, np)
+		}
+	}
+	sort.Slice(scalable, func(i, j int) bool {
+		return scalable[i].Name < scalable[j].Name
+	})
+	return scalable
+}
+
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
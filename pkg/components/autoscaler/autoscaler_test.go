@@ -0,0 +1,116 @@
+package autoscaler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func testNodepools() []*v1alpha1.Nodepool {
+	return []*v1alpha1.Nodepool{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kon-nodepool-b"},
+			Spec: v1alpha1.NodepoolSpec{
+				Autoscale: true,
+				MinSize:   1,
+				MaxSize:   10,
+				AWS:       &v1alpha1.AWSNodepoolSpec{CapacityType: v1alpha1.CapacityTypeSpot},
+			},
+			Status: v1alpha1.NodepoolStatus{AWS: &v1alpha1.AWSNodepoolStatus{ASGID: "eks-b"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "kon-nodepool-a"},
+			Spec:       v1alpha1.NodepoolSpec{Autoscale: true, MinSize: 2, MaxSize: 4},
+			Status:     v1alpha1.NodepoolStatus{AWS: &v1alpha1.AWSNodepoolStatus{ASGID: "eks-a"}},
+		},
+		{
+			// not created yet
+			ObjectMeta: metav1.ObjectMeta{Name: "kon-nodepool-c"},
+			Spec:       v1alpha1.NodepoolSpec{Autoscale: true, MinSize: 1, MaxSize: 2},
+		},
+	}
+}
+
+func TestCommand(t *testing.T) {
+	cc := &v1alpha1.ClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "staging"},
+	}
+	assert.Equal(t, []string{
+		"./cluster-autoscaler",
+		"--v=4",
+		"--stderrthreshold=info",
+		"--cloud-provider=aws",
+		"--skip-nodes-with-local-storage=false",
+		"--expander=least-waste",
+		"--node-group-auto-discovery=asg:tag=k8s.io/cluster-autoscaler/enabled,k8s.io/cluster-autoscaler/staging",
+	}, Command(cc, testNodepools()))
+
+	cc.Spec.ComponentConfig = map[string]v1alpha1.ComponentConfig{
+		ComponentName: {
+			ExpanderKey:             "priority",
+			ScaleDownDelayKey:       "20m",
+			UtilizationThresholdKey: "0.6",
+			DisabledNodepoolsKey:    "kon-nodepool-b",
+		},
+	}
+	assert.Equal(t, []string{
+		"./cluster-autoscaler",
+		"--v=4",
+		"--stderrthreshold=info",
+		"--cloud-provider=aws",
+		"--skip-nodes-with-local-storage=false",
+		"--expander=priority",
+		"--scale-down-delay-after-add=20m",
+		"--scale-down-utilization-threshold=0.6",
+		"--nodes=2:4:eks-a",
+	}, Command(cc, testNodepools()))
+
+	// invalid values are ignored
+	cc.Spec.ComponentConfig[ComponentName] = v1alpha1.ComponentConfig{
+		ExpanderKey:             "cheapest",
+		ScaleDownDelayKey:       "soon",
+		UtilizationThresholdKey: "2",
+	}
+	command := Command(cc, testNodepools())
+	assert.Contains(t, command, "--expander=least-waste")
+	assert.Len(t, command, 7)
+}
+
+func TestPriorityConfigMap(t *testing.T) {
+	cc := &v1alpha1.ClusterConfig{}
+	cm := PriorityConfigMap(cc, testNodepools())
+	assert.Equal(t, PriorityConfigMapName, cm.Name)
+	assert.Equal(t, Namespace, cm.Namespace)
+	assert.Equal(t, "20:\n  - '^eks-b$'\n10:\n  - '^eks-a$'\n", cm.Data["priorities"])
+
+	cc.Spec.ComponentConfig = map[string]v1alpha1.ComponentConfig{
+		ComponentName: {
+			NodepoolPrioritiesKey: "kon-nodepool-a=50, kon-nodepool-b=invalid",
+		},
+	}
+	cm = PriorityConfigMap(cc, testNodepools())
+	assert.Equal(t, "50:\n  - '^eks-a$'\n20:\n  - '^eks-b$'\n", cm.Data["priorities"])
+}
+
+func TestDeletePriorityConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	cm := PriorityConfigMap(&v1alpha1.ClusterConfig{}, testNodepools())
+	kclient := fake.NewFakeClientWithScheme(scheme, cm)
+
+	assert.NoError(t, DeletePriorityConfigMap(kclient))
+	err := kclient.Get(context.Background(), client.ObjectKey{Namespace: Namespace, Name: PriorityConfigMapName}, &corev1.ConfigMap{})
+	assert.True(t, errors.IsNotFound(err))
+
+	// already deleted
+	assert.NoError(t, DeletePriorityConfigMap(kclient))
+}
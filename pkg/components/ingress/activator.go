@@ -0,0 +1,131 @@
+package ingress
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	// set on requests routed to the activator, the name of the idle AppTarget and the port of the app
+	ActivateTargetHeader = "x-konstellation-activate"
+	ActivatePortHeader   = "x-konstellation-port"
+	// set by the route along with the others, so that requests sent to the activator directly are rejected
+	ActivateTokenHeader = "x-konstellation-activate-token"
+
+	// longest time that requests are held while the target starts up
+	ActivationTimeout      = 2 * time.Minute
+	activationPollInterval = time.Second
+)
+
+// Activator holds requests for targets that were scaled to zero when idle. it wakes up the target, and proxies
+// the requests to it once it's available. requests without the activation header are passed to next
+type Activator struct {
+	client client.Client
+	token  string
+	next   http.Handler
+}
+
+func NewActivator(kclient client.Client, token string, next http.Handler) *Activator {
+	return &Activator{
+		client: kclient,
+		token:  token,
+		next:   next,
+	}
+}
+
+func (a *Activator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(ActivateTargetHeader)
+	if name == "" {
+		a.next.ServeHTTP(w, r)
+		return
+	}
+	// only requests routed by Istio could wake up targets and be proxied to them
+	token := r.Header.Get(ActivateTokenHeader)
+	if a.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	at := &v1alpha1.AppTarget{}
+	if err := a.client.Get(r.Context(), client.ObjectKey{Name: name}, at); err != nil {
+		http.Error(w, "target not found", http.StatusNotFound)
+		return
+	}
+	// only ports of the app could be proxied to
+	port, _ := strconv.Atoi(r.Header.Get(ActivatePortHeader))
+	if !hasPort(at, int32(port)) {
+		http.Error(w, "invalid port", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ActivationTimeout)
+	defer cancel()
+	if err := a.activate(ctx, name); err != nil {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "target is starting up", http.StatusServiceUnavailable)
+		return
+	}
+
+	target := &url.URL{
+		Scheme: "http",
+		Host:   fmt.Sprintf("%s:%d", resources.ServiceHostname(at.TargetNamespace(), at.Spec.App), port),
+	}
+	r.Header.Del(ActivateTargetHeader)
+	r.Header.Del(ActivatePortHeader)
+	r.Header.Del(ActivateTokenHeader)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+}
+
+// marks the target as activated, and waits until it has instances available
+func (a *Activator) activate(ctx context.Context, name string) error {
+	ticker := time.NewTicker(activationPollInterval)
+	defer ticker.Stop()
+	for {
+		at := &v1alpha1.AppTarget{}
+		if err := a.client.Get(ctx, client.ObjectKey{Name: name}, at); err != nil {
+			return err
+		}
+		if at.Status.IdleSince == nil && at.Status.NumAvailable > 0 {
+			return nil
+		}
+
+		// set again if it's been lost, it's the only way the target wakes up
+		activatedAt := at.Status.ActivatedAt
+		if at.Status.IdleSince != nil && (activatedAt == nil || !activatedAt.After(at.Status.IdleSince.Time)) {
+			now := metav1.Now()
+			at.Status.ActivatedAt = &now
+			// conflicts are retried on the next check
+			if err := a.client.Status().Update(ctx, at); err != nil && !errors.IsConflict(err) {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func hasPort(at *v1alpha1.AppTarget, port int32) bool {
+	for _, p := range at.Spec.Ports {
+		if p.Port == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package ingress
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestActivator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	idleSince := metav1.NewTime(time.Now().Add(-time.Hour))
+	at := &v1alpha1.AppTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-staging"},
+		Spec: v1alpha1.AppTargetSpec{
+			App:    "web",
+			Target: "staging",
+			AppCommonSpec: v1alpha1.AppCommonSpec{
+				Ports: []v1alpha1.PortSpec{{Name: "http", Port: 8080}},
+			},
+		},
+		Status: v1alpha1.AppTargetStatus{
+			IdleSince: &idleSince,
+		},
+	}
+	kclient := fake.NewFakeClientWithScheme(scheme, at)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	activator := NewActivator(kclient, "secret", next)
+
+	serveWithToken := func(target, port, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/", nil)
+		if target != "" {
+			req.Header.Set(ActivateTargetHeader, target)
+			req.Header.Set(ActivatePortHeader, port)
+			req.Header.Set(ActivateTokenHeader, token)
+		}
+		rec := httptest.NewRecorder()
+		activator.ServeHTTP(rec, req)
+		return rec
+	}
+	serve := func(target, port string) *httptest.ResponseRecorder {
+		return serveWithToken(target, port, "secret")
+	}
+
+	// other requests are passed through
+	assert.Equal(t, http.StatusTeapot, serve("", "").Code)
+	assert.Equal(t, http.StatusNotFound, serve("unknown", "8080").Code)
+	// requests that weren't routed by Istio are rejected
+	assert.Equal(t, http.StatusForbidden, serveWithToken("web-staging", "8080", "").Code)
+	assert.Equal(t, http.StatusForbidden, serveWithToken("web-staging", "8080", "guess").Code)
+	// only ports of the app
+	assert.Equal(t, http.StatusBadRequest, serve("web-staging", "22").Code)
+
+	// requests mark the target as activated, and wait for it to start
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	assert.Error(t, activator.activate(ctx, at.Name))
+
+	updated := &v1alpha1.AppTarget{}
+	assert.NoError(t, kclient.Get(context.TODO(), client.ObjectKey{Name: at.Name}, updated))
+	activatedAt := updated.Status.ActivatedAt
+	if assert.NotNil(t, activatedAt) {
+		assert.True(t, activatedAt.After(idleSince.Time))
+	}
+
+	// done once it's available
+	updated.Status.IdleSince = nil
+	updated.Status.NumAvailable = 1
+	assert.NoError(t, kclient.Update(context.TODO(), updated))
+	assert.NoError(t, activator.activate(context.TODO(), at.Name))
+}
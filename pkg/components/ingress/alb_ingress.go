@@ -117,6 +117,20 @@ func (i *AWSALBIngress) ConfigureIngress(kclient client.Client, ingress *netv1.I
 		"alb.ingress.kubernetes.io/load-balancer-attributes": "routing.http2.enabled=true",
 	}
 
+	// internal load balancers are placed in private subnets, so they aren't reachable from the internet
+	if isInternal(irs) {
+		annotations["alb.ingress.kubernetes.io/scheme"] = "internal"
+		if cc.Status.AWS != nil {
+			var subnets []string
+			for _, subnet := range cc.Status.AWS.PrivateSubnets {
+				subnets = append(subnets, subnet.SubnetId)
+			}
+			if len(subnets) != 0 {
+				annotations["alb.ingress.kubernetes.io/subnets"] = strings.Join(subnets, ",")
+			}
+		}
+	}
+
 	// attach dualstack LB if we have IPV6 enabled on the subnet
 	if cc.Spec.EnableIpv6 && cc.Status.AWS.Ipv6Cidr != "" {
 		annotations["alb.ingress.kubernetes.io/ip-address-type"] = "dualstack"
@@ -164,13 +178,45 @@ func (i *AWSALBIngress) ConfigureIngress(kclient client.Client, ingress *netv1.I
 			requiresHttps = true
 		}
 		// use the first annotations that we could find
-		if len(ir.Spec.Annotations) != 0 && customAnnotations != nil {
+		if len(ir.Spec.Annotations) != 0 && customAnnotations == nil {
 			customAnnotations = ir.Spec.Annotations
 		}
 	}
 
+	// requests with auth are given an Ingress of their own, so it only applies to the app
+	if auth := authForRequests(irs); auth != nil {
+		if auth.BasicAuthSecret != "" {
+			return fmt.Errorf("basic auth isn't supported by ALB, use the nginx ingress controller instead")
+		}
+		if auth.OIDC != nil {
+			oidc, err := discoverOIDCEndpoints(context.TODO(), auth.OIDC)
+			if err != nil {
+				return err
+			}
+			idp, err := json.Marshal(map[string]string{
+				"issuer":                oidc.Issuer,
+				"authorizationEndpoint": oidc.AuthorizationEndpoint,
+				"tokenEndpoint":         oidc.TokenEndpoint,
+				"userInfoEndpoint":      oidc.UserInfoEndpoint,
+				"secretName":            OIDCSecretName(ingress.Name),
+			})
+			if err != nil {
+				return err
+			}
+			annotations["alb.ingress.kubernetes.io/auth-type"] = "oidc"
+			annotations["alb.ingress.kubernetes.io/auth-idp-oidc"] = string(idp)
+			annotations["alb.ingress.kubernetes.io/auth-scope"] = strings.Join(append([]string{"openid"}, oidc.Scopes...), " ")
+			annotations["alb.ingress.kubernetes.io/auth-on-unauthenticated-request"] = "authenticate"
+			// ALB authenticates only on HTTPS listeners
+			requiresHttps = true
+		}
+		if len(auth.AllowedCIDRs) != 0 {
+			annotations["alb.ingress.kubernetes.io/inbound-cidrs"] = strings.Join(auth.AllowedCIDRs, ",")
+		}
+	}
+
 	// get all certs and match against
-	certMap, err := resources.GetCertificatesForHosts(kclient, hosts)
+	certMap, err := resources.GetCertificatesForHosts(kclient, hosts, v1alpha1.CertificateSourceProvider)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,67 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+func TestALBConfigureIngressInternal(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	cc := &v1alpha1.ClusterConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+	}
+	cc.Spec.Cloud = "aws"
+	cc.Status.AWS = &v1alpha1.AWSClusterStatus{
+		PrivateSubnets: []*v1alpha1.AWSSubnet{
+			{SubnetId: "subnet-a"},
+			{SubnetId: "subnet-b"},
+		},
+	}
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      resources.IngressBackendName,
+		},
+	}
+	kclient := fake.NewFakeClientWithScheme(scheme, cc, svc)
+
+	irs := []*v1alpha1.IngressRequest{
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts: []string{"www.example.com"},
+			},
+		},
+	}
+	in := &netv1.Ingress{}
+	in.Name = "production"
+	err := (&AWSALBIngress{}).ConfigureIngress(kclient, in, irs)
+	assert.NoError(t, err)
+	assert.Equal(t, "internet-facing", in.Annotations["alb.ingress.kubernetes.io/scheme"])
+	assert.NotContains(t, in.Annotations, "alb.ingress.kubernetes.io/subnets")
+
+	irs[0].Spec.Visibility = v1alpha1.IngressVisibilityInternal
+	in = &netv1.Ingress{}
+	in.Name = "production-internal"
+	err = (&AWSALBIngress{}).ConfigureIngress(kclient, in, irs)
+	assert.NoError(t, err)
+	assert.Equal(t, "internal", in.Annotations["alb.ingress.kubernetes.io/scheme"])
+	assert.Equal(t, "subnet-a,subnet-b", in.Annotations["alb.ingress.kubernetes.io/subnets"])
+
+	// ALB can't check basic auth credentials
+	irs[0].Spec.Auth = &v1alpha1.IngressAuth{BasicAuthSecret: "credentials"}
+	err = (&AWSALBIngress{}).ConfigureIngress(kclient, &netv1.Ingress{}, irs)
+	assert.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package ingress
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+const (
+	// key in the secret referenced by OIDCAuth
+	OIDCClientSecretKey = "clientSecret"
+
+	// keys that the ALB controller reads client credentials from
+	albOIDCClientIDKey     = "clientID"
+	albOIDCClientSecretKey = "clientSecret"
+	// key that NGINX reads htpasswd entries from
+	nginxBasicAuthKey = "auth"
+
+	// header that ALB passes the access token in, after authenticating the user
+	ALBAccessTokenHeader = "x-amzn-oidc-accesstoken"
+
+	oidcDiscoveryPath = "/.well-known/openid-configuration"
+	saltSize          = 8
+)
+
+// an unresponsive issuer shouldn't hold up reconciling ingresses
+var oidcDiscoveryClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// returns auth config of the requests. requests with auth are given an Ingress of their own
+func authForRequests(irs []*v1alpha1.IngressRequest) *v1alpha1.IngressAuth {
+	for _, ir := range irs {
+		if ir.Spec.Auth != nil {
+			return ir.Spec.Auth
+		}
+	}
+	return nil
+}
+
+// requests are grouped by visibility, so the ingress is internal when its requests are
+func isInternal(irs []*v1alpha1.IngressRequest) bool {
+	return len(irs) != 0 && irs[0].IsInternal()
+}
+
+func OIDCSecretName(ingressName string) string {
+	return ingressName + "-oidc"
+}
+
+func BasicAuthSecretName(ingressName string) string {
+	return ingressName + "-basic-auth"
+}
+
+// returns secrets in the ingress namespace that the ingress controller reads credentials from.
+// credentials are copied from secrets in the request's namespace
+func NewAuthSecrets(kclient client.Client, ingressName string, ir *v1alpha1.IngressRequest) ([]*corev1.Secret, error) {
+	auth := ir.Spec.Auth
+	if auth == nil {
+		return nil, nil
+	}
+
+	var secrets []*corev1.Secret
+	if auth.OIDC != nil {
+		source, err := resources.GetSecret(kclient, ir.Namespace, auth.OIDC.ClientSecretName)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, newIngressSecret(OIDCSecretName(ingressName), map[string][]byte{
+			albOIDCClientIDKey:     []byte(auth.OIDC.ClientID),
+			albOIDCClientSecretKey: source.Data[OIDCClientSecretKey],
+		}))
+	}
+
+	if auth.BasicAuthSecret != "" {
+		source, err := resources.GetSecret(kclient, ir.Namespace, auth.BasicAuthSecret)
+		if err != nil {
+			return nil, err
+		}
+		username := string(source.Data[corev1.BasicAuthUsernameKey])
+		password := string(source.Data[corev1.BasicAuthPasswordKey])
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("secret %s should contain a username and password", auth.BasicAuthSecret)
+		}
+		// keep existing entries, since they are salted differently each time
+		name := BasicAuthSecretName(ingressName)
+		var entry string
+		existing, err := resources.GetSecret(kclient, resources.IstioNamespace, name)
+		if err == nil && htpasswdMatches(string(existing.Data[nginxBasicAuthKey]), username, password) {
+			entry = string(existing.Data[nginxBasicAuthKey])
+		} else if entry, err = htpasswdEntry(username, password); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, newIngressSecret(name, map[string][]byte{
+			nginxBasicAuthKey: []byte(entry),
+		}))
+	}
+	return secrets, nil
+}
+
+func newIngressSecret(name string, data map[string][]byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: resources.IstioNamespace,
+			Name:      name,
+			Labels: map[string]string{
+				resources.Konstellation: "1",
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+}
+
+// returns a copy of the config, with endpoints that aren't set discovered from the issuer
+func discoverOIDCEndpoints(ctx context.Context, oidc *v1alpha1.OIDCAuth) (*v1alpha1.OIDCAuth, error) {
+	oidc = oidc.DeepCopy()
+	if oidc.AuthorizationEndpoint != "" && oidc.TokenEndpoint != "" && oidc.UserInfoEndpoint != "" {
+		return oidc, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(oidc.Issuer, "/")+oidcDiscoveryPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := oidcDiscoveryClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not discover OIDC endpoints of %s, status: %d", oidc.Issuer, resp.StatusCode)
+	}
+
+	discovery := struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	}{}
+	if err = json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, err
+	}
+	if oidc.AuthorizationEndpoint == "" {
+		oidc.AuthorizationEndpoint = discovery.AuthorizationEndpoint
+	}
+	if oidc.TokenEndpoint == "" {
+		oidc.TokenEndpoint = discovery.TokenEndpoint
+	}
+	if oidc.UserInfoEndpoint == "" {
+		oidc.UserInfoEndpoint = discovery.UserInfoEndpoint
+	}
+	return oidc, nil
+}
+
+// returns a htpasswd line with a salted SHA-1 hash, which NGINX supports natively
+func htpasswdEntry(username, password string) (string, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:{SSHA}%s\n", username, sshaHash(password, salt)), nil
+}
+
+func htpasswdMatches(entry, username, password string) bool {
+	prefix := username + ":{SSHA}"
+	entry = strings.TrimSpace(entry)
+	if !strings.HasPrefix(entry, prefix) {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(entry, prefix))
+	if err != nil || len(decoded) <= sha1.Size {
+		return false
+	}
+	return strings.TrimPrefix(entry, prefix) == sshaHash(password, decoded[sha1.Size:])
+}
+
+func sshaHash(password string, salt []byte) string {
+	hash := sha1.Sum(append([]byte(password), salt...))
+	return base64.StdEncoding.EncodeToString(append(hash[:], salt...))
+}
@@ -0,0 +1,115 @@
+package ingress
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+func TestHtpasswd(t *testing.T) {
+	entry, err := htpasswdEntry("admin", "secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(entry, "admin:{SSHA}"))
+	assert.True(t, htpasswdMatches(entry, "admin", "secret"))
+	assert.False(t, htpasswdMatches(entry, "admin", "other"))
+	assert.False(t, htpasswdMatches(entry, "root", "secret"))
+	assert.False(t, htpasswdMatches("", "admin", "secret"))
+
+	// salted differently each time
+	other, err := htpasswdEntry("admin", "secret")
+	assert.NoError(t, err)
+	assert.NotEqual(t, entry, other)
+}
+
+func TestDiscoverOIDCEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, oidcDiscoveryPath, r.URL.Path)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": "https://accounts.example.com/authorize",
+			"token_endpoint":         "https://accounts.example.com/token",
+			"userinfo_endpoint":      "https://accounts.example.com/userinfo",
+		})
+	}))
+	defer server.Close()
+
+	oidc := &v1alpha1.OIDCAuth{
+		Issuer:        server.URL + "/",
+		TokenEndpoint: "https://accounts.example.com/oauth/token",
+	}
+	discovered, err := discoverOIDCEndpoints(context.TODO(), oidc)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://accounts.example.com/authorize", discovered.AuthorizationEndpoint)
+	assert.Equal(t, "https://accounts.example.com/userinfo", discovered.UserInfoEndpoint)
+	// explicit endpoints are kept
+	assert.Equal(t, "https://accounts.example.com/oauth/token", discovered.TokenEndpoint)
+	assert.Empty(t, oidc.AuthorizationEndpoint)
+}
+
+func TestNewAuthSecrets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	newSecret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Data:       data,
+		}
+	}
+	kclient := fake.NewFakeClientWithScheme(scheme,
+		newSecret("oidc", map[string][]byte{OIDCClientSecretKey: []byte("client-secret")}),
+		newSecret("credentials", map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("admin"),
+			corev1.BasicAuthPasswordKey: []byte("secret"),
+		}),
+	)
+
+	ir := &v1alpha1.IngressRequest{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "myapp-production"},
+	}
+	secrets, err := NewAuthSecrets(kclient, "myapp-production-auth", ir)
+	assert.NoError(t, err)
+	assert.Empty(t, secrets)
+
+	ir.Spec.Auth = &v1alpha1.IngressAuth{
+		OIDC: &v1alpha1.OIDCAuth{
+			Issuer:           "https://accounts.example.com",
+			ClientID:         "client",
+			ClientSecretName: "oidc",
+		},
+		BasicAuthSecret: "credentials",
+	}
+	secrets, err = NewAuthSecrets(kclient, "myapp-production-auth", ir)
+	assert.NoError(t, err)
+	assert.Len(t, secrets, 2)
+
+	assert.Equal(t, "myapp-production-auth-oidc", secrets[0].Name)
+	assert.Equal(t, resources.IstioNamespace, secrets[0].Namespace)
+	assert.Equal(t, "client", string(secrets[0].Data[albOIDCClientIDKey]))
+	assert.Equal(t, "client-secret", string(secrets[0].Data[albOIDCClientSecretKey]))
+
+	basicAuth := secrets[1]
+	assert.Equal(t, "myapp-production-auth-basic-auth", basicAuth.Name)
+	assert.True(t, htpasswdMatches(string(basicAuth.Data[nginxBasicAuthKey]), "admin", "secret"))
+
+	// existing entries are kept when credentials are unchanged
+	assert.NoError(t, kclient.Create(context.TODO(), basicAuth))
+	secrets, err = NewAuthSecrets(kclient, "myapp-production-auth", ir)
+	assert.NoError(t, err)
+	assert.Equal(t, basicAuth.Data, secrets[1].Data)
+
+	ir.Spec.Auth.BasicAuthSecret = "missing"
+	_, err = NewAuthSecrets(kclient, "myapp-production-auth", ir)
+	assert.Error(t, err)
+}
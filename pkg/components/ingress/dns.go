@@ -0,0 +1,133 @@
+package ingress
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/thoas/go-funk"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud"
+	kaws "github.com/k11n/konstellation/pkg/cloud/aws"
+	"github.com/k11n/konstellation/pkg/cloud/types"
+)
+
+const (
+	// ComponentConfig key for the provider that manages DNS records of ingress hosts
+	DNSProviderKey     = "dns-provider"
+	DNSProviderRoute53 = "route53"
+)
+
+// returns the DNS provider selected in the cluster's component config, nil when records aren't managed
+func NewDNSProviderForCluster(cc *v1alpha1.ClusterConfig) (cloud.DNSProvider, error) {
+	provider := cc.Spec.ComponentConfig[ComponentName][DNSProviderKey]
+	switch provider {
+	case "":
+		return nil, nil
+	case DNSProviderRoute53:
+		if cc.Spec.Cloud != "aws" {
+			return nil, fmt.Errorf("%s is only supported on aws", provider)
+		}
+		sess, err := session.NewSession(&aws.Config{
+			Region: aws.String(cc.Spec.Region),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return kaws.NewRoute53Service(sess, cc.Name), nil
+	}
+	return nil, fmt.Errorf("unsupported DNS provider: %s", provider)
+}
+
+// points each host of the request to the address, and removes records of hosts that are no longer requested.
+// irs are all requests in the namespace, records of hosts that other requests still use are left alone.
+// results are reported in the request's status
+func SyncDNSRecords(ctx context.Context, provider cloud.DNSProvider, ir *v1alpha1.IngressRequest,
+	irs []*v1alpha1.IngressRequest, address string) error {
+	status := ir.Status.DNS
+	if status == nil {
+		status = &v1alpha1.IngressDNSStatus{}
+	}
+	existing := make(map[string]v1alpha1.IngressDNSRecord)
+	for _, record := range status.Records {
+		existing[record.Host] = record
+	}
+
+	var records []v1alpha1.IngressDNSRecord
+	var errs []error
+	for _, record := range status.Records {
+		if funk.ContainsString(ir.Spec.Hosts, record.Host) || hostRequestedByOthers(record.Host, ir, irs) {
+			continue
+		}
+		err := provider.DeleteRecord(ctx, &types.DNSRecord{Host: record.Host, Target: record.Target})
+		if err != nil {
+			// keep track of it to try again
+			errs = append(errs, err)
+			records = append(records, record)
+		}
+	}
+
+	for _, host := range ir.Spec.Hosts {
+		record, ok := existing[host]
+		if ok && record.Target == address && status.Synced {
+			records = append(records, record)
+			continue
+		}
+		err := provider.UpsertRecord(ctx, &types.DNSRecord{Host: host, Target: address})
+		if err != nil {
+			errs = append(errs, err)
+			if ok {
+				records = append(records, record)
+			}
+			continue
+		}
+		records = append(records, v1alpha1.IngressDNSRecord{
+			Host:   host,
+			Target: address,
+		})
+	}
+
+	err := utilerrors.NewAggregate(errs)
+	ir.Status.DNS = &v1alpha1.IngressDNSStatus{
+		Records: records,
+		Synced:  err == nil,
+	}
+	if err != nil {
+		ir.Status.DNS.Message = err.Error()
+	}
+	return err
+}
+
+// removes records of the request, leaving ones for hosts that other requests still use
+func RemoveDNSRecords(ctx context.Context, provider cloud.DNSProvider, ir *v1alpha1.IngressRequest,
+	irs []*v1alpha1.IngressRequest) error {
+	if ir.Status.DNS == nil {
+		return nil
+	}
+	var errs []error
+	for _, record := range ir.Status.DNS.Records {
+		if hostRequestedByOthers(record.Host, ir, irs) {
+			continue
+		}
+		err := provider.DeleteRecord(ctx, &types.DNSRecord{Host: record.Host, Target: record.Target})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func hostRequestedByOthers(host string, ir *v1alpha1.IngressRequest, irs []*v1alpha1.IngressRequest) bool {
+	for _, other := range irs {
+		if other.Name == ir.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		if funk.ContainsString(other.Spec.Hosts, host) {
+			return true
+		}
+	}
+	return false
+}
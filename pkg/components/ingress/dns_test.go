@@ -0,0 +1,163 @@
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud/types"
+)
+
+// keeps records in memory, failing changes to hosts in failHosts
+type fakeDNSProvider struct {
+	records   map[string]string
+	failHosts []string
+	upserts   int
+}
+
+func newFakeDNSProvider() *fakeDNSProvider {
+	return &fakeDNSProvider{
+		records: make(map[string]string),
+	}
+}
+
+func (p *fakeDNSProvider) UpsertRecord(ctx context.Context, record *types.DNSRecord) error {
+	if err := p.check(record.Host); err != nil {
+		return err
+	}
+	p.upserts += 1
+	p.records[record.Host] = record.Target
+	return nil
+}
+
+func (p *fakeDNSProvider) DeleteRecord(ctx context.Context, record *types.DNSRecord) error {
+	if err := p.check(record.Host); err != nil {
+		return err
+	}
+	if p.records[record.Host] == record.Target {
+		delete(p.records, record.Host)
+	}
+	return nil
+}
+
+func (p *fakeDNSProvider) UpsertTXTRecord(ctx context.Context, name, value string) error {
+	return p.check(name)
+}
+
+func (p *fakeDNSProvider) DeleteTXTRecord(ctx context.Context, name, value string) error {
+	return p.check(name)
+}
+
+func (p *fakeDNSProvider) check(host string) error {
+	for _, h := range p.failHosts {
+		if h == host {
+			return fmt.Errorf("could not change record for %s", host)
+		}
+	}
+	return nil
+}
+
+func newDNSTestRequest(name string, hosts ...string) *v1alpha1.IngressRequest {
+	return &v1alpha1.IngressRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1alpha1.IngressRequestSpec{
+			Hosts: hosts,
+		},
+	}
+}
+
+func TestSyncDNSRecords(t *testing.T) {
+	ctx := context.TODO()
+	lb := "k8s-lb.us-west-2.elb.amazonaws.com"
+
+	t.Run("creates records for hosts", func(t *testing.T) {
+		provider := newFakeDNSProvider()
+		ir := newDNSTestRequest("web", "www.example.com", "example.com")
+		irs := []*v1alpha1.IngressRequest{ir}
+
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+		assert.Equal(t, map[string]string{
+			"www.example.com": lb,
+			"example.com":     lb,
+		}, provider.records)
+		assert.True(t, ir.Status.DNS.Synced)
+		assert.Len(t, ir.Status.DNS.Records, 2)
+
+		// records already in sync aren't changed again
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+		assert.Equal(t, 2, provider.upserts)
+
+		// until the address changes
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, "other-lb"))
+		assert.Equal(t, "other-lb", provider.records["www.example.com"])
+		assert.Equal(t, "other-lb", ir.Status.DNS.Records[0].Target)
+	})
+
+	t.Run("removes records of hosts no longer requested", func(t *testing.T) {
+		provider := newFakeDNSProvider()
+		ir := newDNSTestRequest("web", "www.example.com", "example.com", "shared.example.com")
+		other := newDNSTestRequest("api", "shared.example.com")
+		irs := []*v1alpha1.IngressRequest{ir, other}
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+
+		ir.Spec.Hosts = []string{"www.example.com"}
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+		assert.Equal(t, map[string]string{
+			"www.example.com":    lb,
+			"shared.example.com": lb,
+		}, provider.records)
+		assert.Equal(t, []v1alpha1.IngressDNSRecord{
+			{Host: "www.example.com", Target: lb},
+		}, ir.Status.DNS.Records)
+	})
+
+	t.Run("reports errors", func(t *testing.T) {
+		provider := newFakeDNSProvider()
+		provider.failHosts = []string{"www.example.org"}
+		ir := newDNSTestRequest("web", "www.example.com", "www.example.org")
+		irs := []*v1alpha1.IngressRequest{ir}
+
+		assert.Error(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+		assert.False(t, ir.Status.DNS.Synced)
+		assert.Contains(t, ir.Status.DNS.Message, "www.example.org")
+		assert.Equal(t, []v1alpha1.IngressDNSRecord{
+			{Host: "www.example.com", Target: lb},
+		}, ir.Status.DNS.Records)
+
+		// everything is tried again until synced
+		provider.failHosts = nil
+		assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+		assert.True(t, ir.Status.DNS.Synced)
+		assert.Empty(t, ir.Status.DNS.Message)
+		assert.Len(t, provider.records, 2)
+	})
+}
+
+func TestRemoveDNSRecords(t *testing.T) {
+	ctx := context.TODO()
+	lb := "k8s-lb.us-west-2.elb.amazonaws.com"
+	provider := newFakeDNSProvider()
+	ir := newDNSTestRequest("web", "www.example.com", "shared.example.com")
+	other := newDNSTestRequest("api", "shared.example.com")
+	irs := []*v1alpha1.IngressRequest{ir, other}
+	assert.NoError(t, SyncDNSRecords(ctx, provider, ir, irs, lb))
+
+	assert.NoError(t, RemoveDNSRecords(ctx, provider, ir, irs))
+	assert.Equal(t, map[string]string{
+		"shared.example.com": lb,
+	}, provider.records)
+
+	// records changed by someone else are left alone
+	provider.records["www.example.com"] = "elsewhere"
+	assert.NoError(t, RemoveDNSRecords(ctx, provider, ir, irs))
+	assert.Equal(t, "elsewhere", provider.records["www.example.com"])
+
+	// nothing to do without records
+	assert.NoError(t, RemoveDNSRecords(ctx, provider, newDNSTestRequest("empty"), irs))
+}
@@ -1,24 +1,82 @@
 package ingress
 
 import (
-	"log"
+	"fmt"
 
 	netv1 "k8s.io/api/networking/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud/acme"
 	"github.com/k11n/konstellation/pkg/components"
 )
 
+const (
+	// ComponentConfig used to select the ingress controller
+	ComponentName = "ingress"
+	ControllerKey = "controller"
+
+	ControllerALB   = "alb"
+	ControllerNginx = "nginx"
+
+	// ComponentConfig keys used to issue certificates for hosts that aren't covered by one
+	TLSIssuerKey  = "tls-issuer"
+	ACMEServerKey = "acme-server"
+	ACMEEmailKey  = "acme-email"
+
+	TLSIssuerACME = "acme"
+
+	// challenge used to validate domains, http-01 (default) or dns-01. wildcard domains are always validated
+	// with dns-01, which needs a dns-provider
+	ACMEChallengeKey = "acme-challenge"
+)
+
 type IngressComponent interface {
 	components.ComponentInstaller
 	ConfigureIngress(kclient client.Client, ingress *netv1.Ingress, irs []*v1alpha1.IngressRequest) error
 }
 
-func NewIngressForCluster(cloud, cluster string) IngressComponent {
-	if cloud == "aws" {
-		return &AWSALBIngress{}
+// returns the ingress controller selected in the cluster's component config.
+// defaults to ALB on AWS, and NGINX everywhere else
+func NewIngressForCluster(cc *v1alpha1.ClusterConfig) (IngressComponent, error) {
+	controller := ControllerForCluster(cc)
+	switch controller {
+	case ControllerALB:
+		return &AWSALBIngress{}, nil
+	case ControllerNginx:
+		return &NginxIngress{}, nil
+	}
+	return nil, fmt.Errorf("unsupported ingress controller: %s", controller)
+}
+
+func ControllerForCluster(cc *v1alpha1.ClusterConfig) string {
+	controller := cc.Spec.ComponentConfig[ComponentName][ControllerKey]
+	if controller == "" {
+		if cc.Spec.Cloud == "aws" {
+			controller = ControllerALB
+		} else {
+			controller = ControllerNginx
+		}
+	}
+	return controller
+}
+
+// returns true when certificates should be issued via ACME. certificates are stored as secrets,
+// and so could only be used with NGINX
+func ACMEEnabled(cc *v1alpha1.ClusterConfig) bool {
+	return cc.Spec.ComponentConfig[ComponentName][TLSIssuerKey] == TLSIssuerACME &&
+		ControllerForCluster(cc) == ControllerNginx
+}
+
+// returns the challenge type used to validate regular domains
+func ACMEChallengeType(cc *v1alpha1.ClusterConfig) string {
+	if cc.Spec.ComponentConfig[ComponentName][ACMEChallengeKey] == acme.ChallengeDNS01 {
+		return acme.ChallengeDNS01
 	}
-	log.Fatalf("Unsupported cloud: %s", cloud)
-	return nil
+	return acme.ChallengeHTTP01
+}
+
+// wildcard domains could only be validated with dns-01 challenges, which need a DNS provider
+func ACMESupportsWildcards(cc *v1alpha1.ClusterConfig) bool {
+	return cc.Spec.ComponentConfig[ComponentName][DNSProviderKey] != ""
 }
@@ -0,0 +1,241 @@
+package ingress
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/thoas/go-funk"
+	netv1 "k8s.io/api/networking/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/cloud/acme"
+	"github.com/k11n/konstellation/pkg/components"
+	"github.com/k11n/konstellation/pkg/resources"
+	"github.com/k11n/konstellation/pkg/utils/cli"
+)
+
+const (
+	nginxControllerVersion = "0.44.0"
+	// ComponentConfig key for the ingress-nginx deployment variant, i.e. cloud, aws, kind, or baremetal
+	NginxProviderKey     = "nginx-provider"
+	defaultNginxProvider = "cloud"
+
+	nginxAnnotationPrefix       = "nginx.ingress.kubernetes.io/"
+	certManagerAnnotationPrefix = "cert-manager.io/"
+)
+
+var (
+	// RFC 1918 ranges
+	privateNetworks = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"}
+)
+
+func init() {
+	components.RegisterComponent(&NginxIngress{})
+}
+
+type NginxIngress struct {
+}
+
+func (i *NginxIngress) Name() string {
+	return "ingress.nginx"
+}
+
+func (i *NginxIngress) VersionForKube(version string) string {
+	return nginxControllerVersion
+}
+
+func (i *NginxIngress) InstallComponent(kclient client.Client) error {
+	cc, err := resources.GetClusterConfig(kclient)
+	if err != nil {
+		return err
+	}
+
+	provider := cc.Spec.ComponentConfig[ComponentName][NginxProviderKey]
+	if provider == "" {
+		provider = defaultNginxProvider
+	}
+	url := fmt.Sprintf("https://raw.githubusercontent.com/kubernetes/ingress-nginx/controller-v%s/deploy/static/provider/%s/deploy.yaml",
+		nginxControllerVersion, provider)
+	return cli.KubeApply(url)
+}
+
+func (i *NginxIngress) ConfigureIngress(kclient client.Client, ingress *netv1.Ingress, irs []*v1alpha1.IngressRequest) error {
+	annotations := map[string]string{
+		"kubernetes.io/ingress.class": "nginx",
+	}
+
+	isGrpc := false
+	requiresHttps := false
+	var customAnnotations map[string]string
+
+	// unique hosts, with the paths requested for each
+	var hosts []string
+	hostPaths := make(map[string][]string)
+	for _, ir := range irs {
+		if ir.Spec.AppProtocol == "grpc" {
+			isGrpc = true
+		}
+		if ir.Spec.RequireHTTPS {
+			requiresHttps = true
+		}
+		// use the first annotations that we could find
+		if len(ir.Spec.Annotations) != 0 && customAnnotations == nil {
+			customAnnotations = ir.Spec.Annotations
+		}
+
+		paths := ir.Spec.Paths
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		for _, host := range ir.Spec.Hosts {
+			if _, ok := hostPaths[host]; !ok {
+				hosts = append(hosts, host)
+			}
+			for _, path := range paths {
+				if !funk.ContainsString(hostPaths[host], path) {
+					hostPaths[host] = append(hostPaths[host], path)
+				}
+			}
+		}
+	}
+
+	if isGrpc {
+		// istio gateway accepts h2c on the http port
+		annotations[nginxAnnotationPrefix+"backend-protocol"] = "GRPC"
+	}
+
+	cc, err := resources.GetClusterConfig(kclient)
+	if err != nil {
+		return err
+	}
+	acmeEnabled := ACMEEnabled(cc)
+
+	// route each host to the istio gateway, which handles routing to apps
+	backend := ingress.Spec.DefaultBackend
+	pathType := netv1.PathTypePrefix
+	var rules []netv1.IngressRule
+	for _, host := range hosts {
+		var paths []netv1.HTTPIngressPath
+		if acmeEnabled {
+			// operator responds to challenges when validating hosts
+			paths = append(paths, netv1.HTTPIngressPath{
+				Path:     acme.ChallengePathPrefix,
+				PathType: &pathType,
+				Backend: netv1.IngressBackend{
+					Service: &netv1.IngressServiceBackend{
+						Name: resources.ACMESolverServiceName,
+						Port: netv1.ServiceBackendPort{
+							Number: 80,
+						},
+					},
+				},
+			})
+		}
+		for _, path := range hostPaths[host] {
+			paths = append(paths, netv1.HTTPIngressPath{
+				Path:     path,
+				PathType: &pathType,
+				Backend:  *backend,
+			})
+		}
+		rules = append(rules, netv1.IngressRule{
+			Host: host,
+			IngressRuleValue: netv1.IngressRuleValue{
+				HTTP: &netv1.HTTPIngressRuleValue{
+					Paths: paths,
+				},
+			},
+		})
+	}
+	if len(rules) != 0 {
+		ingress.Spec.Rules = rules
+	}
+
+	// nginx terminates TLS with secrets, from certificates issued via ACME
+	certMap, err := resources.GetCertificatesForHosts(kclient, hosts, v1alpha1.CertificateSourceACME)
+	if err != nil {
+		return err
+	}
+	var secretNames []string
+	secretHosts := make(map[string][]string)
+	var uncoveredHosts []string
+	for _, host := range hosts {
+		cert := certMap[host]
+		if cert == nil || !cert.IsReady() || cert.Spec.SecretName == "" {
+			uncoveredHosts = append(uncoveredHosts, host)
+			continue
+		}
+		if _, ok := secretHosts[cert.Spec.SecretName]; !ok {
+			secretNames = append(secretNames, cert.Spec.SecretName)
+		}
+		secretHosts[cert.Spec.SecretName] = append(secretHosts[cert.Spec.SecretName], host)
+	}
+	var tls []netv1.IngressTLS
+	for _, secretName := range secretNames {
+		tls = append(tls, netv1.IngressTLS{
+			Hosts:      secretHosts[secretName],
+			SecretName: secretName,
+		})
+	}
+
+	// or ones that cert-manager issues when annotated
+	hasCertManager := false
+	for key := range customAnnotations {
+		if strings.HasPrefix(key, certManagerAnnotationPrefix) {
+			hasCertManager = true
+			break
+		}
+	}
+	if hasCertManager && len(uncoveredHosts) != 0 {
+		tls = append(tls, netv1.IngressTLS{
+			Hosts:      uncoveredHosts,
+			SecretName: ingress.Name + "-tls",
+		})
+	}
+	ingress.Spec.TLS = tls
+
+	// requests with auth are given an Ingress of their own, so it only applies to the app.
+	// OIDC tokens are verified by the mesh, since NGINX doesn't handle the login flow
+	if auth := authForRequests(irs); auth != nil {
+		if auth.BasicAuthSecret != "" {
+			annotations[nginxAnnotationPrefix+"auth-type"] = "basic"
+			annotations[nginxAnnotationPrefix+"auth-secret"] = BasicAuthSecretName(ingress.Name)
+			annotations[nginxAnnotationPrefix+"auth-realm"] = "Authentication Required"
+		}
+		if len(auth.AllowedCIDRs) != 0 {
+			annotations[nginxAnnotationPrefix+"whitelist-source-range"] = strings.Join(auth.AllowedCIDRs, ",")
+		}
+	}
+
+	// NGINX is served by a single load balancer, internal requests are limited to private networks instead
+	whitelistKey := nginxAnnotationPrefix + "whitelist-source-range"
+	if isInternal(irs) && annotations[whitelistKey] == "" {
+		annotations[whitelistKey] = strings.Join(privateNetworks, ",")
+	}
+
+	if requiresHttps {
+		// force redirect even when TLS is terminated in front of nginx
+		annotations[nginxAnnotationPrefix+"ssl-redirect"] = "true"
+		annotations[nginxAnnotationPrefix+"force-ssl-redirect"] = "true"
+	} else {
+		annotations[nginxAnnotationPrefix+"ssl-redirect"] = "false"
+	}
+
+	// allow config overrides
+	for key, val := range customAnnotations {
+		if strings.HasPrefix(key, nginxAnnotationPrefix) || strings.HasPrefix(key, certManagerAnnotationPrefix) {
+			annotations[key] = val
+		}
+	}
+
+	if ingress.Annotations == nil {
+		ingress.Annotations = annotations
+	} else {
+		for key, val := range annotations {
+			ingress.Annotations[key] = val
+		}
+	}
+
+	return nil
+}
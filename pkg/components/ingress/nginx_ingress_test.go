@@ -0,0 +1,199 @@
+package ingress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	netv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func newFakeClient(cc *v1alpha1.ClusterConfig, objs ...runtime.Object) client.Client {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	cc.Name = "cluster"
+	return fake.NewFakeClientWithScheme(scheme, append(objs, cc)...)
+}
+
+func newNginxTestIngress() *netv1.Ingress {
+	in := &netv1.Ingress{
+		Spec: netv1.IngressSpec{
+			DefaultBackend: &netv1.IngressBackend{
+				Service: &netv1.IngressServiceBackend{
+					Name: "istio-ingressgateway",
+					Port: netv1.ServiceBackendPort{Number: 80},
+				},
+			},
+		},
+	}
+	in.Name = "production"
+	return in
+}
+
+func TestNginxConfigureIngress(t *testing.T) {
+	in := newNginxTestIngress()
+	irs := []*v1alpha1.IngressRequest{
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts:        []string{"www.example.com"},
+				RequireHTTPS: true,
+				Annotations: map[string]string{
+					"cert-manager.io/cluster-issuer":            "letsencrypt",
+					"nginx.ingress.kubernetes.io/proxy-timeout": "30",
+					"alb.ingress.kubernetes.io/scheme":          "internal",
+				},
+			},
+		},
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts: []string{"api.example.com", "www.example.com"},
+				Paths: []string{"/api"},
+			},
+		},
+	}
+
+	err := (&NginxIngress{}).ConfigureIngress(newFakeClient(&v1alpha1.ClusterConfig{}), in, irs)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "nginx", in.Annotations["kubernetes.io/ingress.class"])
+	assert.Equal(t, "true", in.Annotations["nginx.ingress.kubernetes.io/force-ssl-redirect"])
+	assert.Equal(t, "30", in.Annotations["nginx.ingress.kubernetes.io/proxy-timeout"])
+	assert.Equal(t, "letsencrypt", in.Annotations["cert-manager.io/cluster-issuer"])
+	assert.NotContains(t, in.Annotations, "alb.ingress.kubernetes.io/scheme")
+
+	assert.Len(t, in.Spec.Rules, 2)
+	assert.Equal(t, "www.example.com", in.Spec.Rules[0].Host)
+	paths := in.Spec.Rules[0].HTTP.Paths
+	assert.Len(t, paths, 2)
+	assert.Equal(t, "/", paths[0].Path)
+	assert.Equal(t, "/api", paths[1].Path)
+	assert.Equal(t, "istio-ingressgateway", paths[0].Backend.Service.Name)
+	assert.Equal(t, "api.example.com", in.Spec.Rules[1].Host)
+
+	assert.Equal(t, []netv1.IngressTLS{
+		{
+			Hosts:      []string{"www.example.com", "api.example.com"},
+			SecretName: "production-tls",
+		},
+	}, in.Spec.TLS)
+}
+
+func TestNginxConfigureIngressACME(t *testing.T) {
+	cc := &v1alpha1.ClusterConfig{}
+	cc.Spec.ComponentConfig = map[string]v1alpha1.ComponentConfig{
+		ComponentName: {ControllerKey: ControllerNginx, TLSIssuerKey: TLSIssuerACME},
+	}
+	newCert := func(domain, status string) *v1alpha1.CertificateRef {
+		return &v1alpha1.CertificateRef{
+			ObjectMeta: metav1.ObjectMeta{Name: "acme-" + domain},
+			Spec: v1alpha1.CertificateRefSpec{
+				Domain:     domain,
+				Status:     status,
+				Source:     v1alpha1.CertificateSourceACME,
+				SecretName: "acme-" + domain,
+			},
+		}
+	}
+	kclient := newFakeClient(cc,
+		newCert("www.example.com", "READY"),
+		newCert("api.example.com", "NOT_READY"),
+	)
+
+	in := newNginxTestIngress()
+	irs := []*v1alpha1.IngressRequest{
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts: []string{"www.example.com", "api.example.com"},
+			},
+		},
+	}
+	err := (&NginxIngress{}).ConfigureIngress(kclient, in, irs)
+	assert.NoError(t, err)
+
+	// challenges are routed to the operator
+	paths := in.Spec.Rules[0].HTTP.Paths
+	assert.Len(t, paths, 2)
+	assert.Equal(t, "/.well-known/acme-challenge/", paths[0].Path)
+	assert.Equal(t, "konstellation-acme", paths[0].Backend.Service.Name)
+
+	// only issued certificates are used
+	assert.Equal(t, []netv1.IngressTLS{
+		{
+			Hosts:      []string{"www.example.com"},
+			SecretName: "acme-www.example.com",
+		},
+	}, in.Spec.TLS)
+}
+
+func TestNginxConfigureIngressAuth(t *testing.T) {
+	in := newNginxTestIngress()
+	in.Name = "myapp-production-auth"
+	irs := []*v1alpha1.IngressRequest{
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts: []string{"admin.example.com"},
+				Auth: &v1alpha1.IngressAuth{
+					BasicAuthSecret: "credentials",
+					AllowedCIDRs:    []string{"10.0.0.0/8", "192.168.0.0/16"},
+				},
+			},
+		},
+	}
+	err := (&NginxIngress{}).ConfigureIngress(newFakeClient(&v1alpha1.ClusterConfig{}), in, irs)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "basic", in.Annotations["nginx.ingress.kubernetes.io/auth-type"])
+	assert.Equal(t, "myapp-production-auth-basic-auth", in.Annotations["nginx.ingress.kubernetes.io/auth-secret"])
+	assert.Equal(t, "10.0.0.0/8,192.168.0.0/16", in.Annotations["nginx.ingress.kubernetes.io/whitelist-source-range"])
+}
+
+func TestNginxConfigureIngressInternal(t *testing.T) {
+	in := newNginxTestIngress()
+	in.Name = "production-internal"
+	irs := []*v1alpha1.IngressRequest{
+		{
+			Spec: v1alpha1.IngressRequestSpec{
+				Hosts:      []string{"admin.example.com"},
+				Visibility: v1alpha1.IngressVisibilityInternal,
+			},
+		},
+	}
+	err := (&NginxIngress{}).ConfigureIngress(newFakeClient(&v1alpha1.ClusterConfig{}), in, irs)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16", in.Annotations["nginx.ingress.kubernetes.io/whitelist-source-range"])
+
+	// explicit allowlists are kept
+	irs[0].Spec.Auth = &v1alpha1.IngressAuth{AllowedCIDRs: []string{"10.1.0.0/16"}}
+	in = newNginxTestIngress()
+	err = (&NginxIngress{}).ConfigureIngress(newFakeClient(&v1alpha1.ClusterConfig{}), in, irs)
+	assert.NoError(t, err)
+	assert.Equal(t, "10.1.0.0/16", in.Annotations["nginx.ingress.kubernetes.io/whitelist-source-range"])
+}
+
+func TestNewIngressForCluster(t *testing.T) {
+	cc := &v1alpha1.ClusterConfig{}
+	cc.Spec.Cloud = "aws"
+	comp, err := NewIngressForCluster(cc)
+	assert.NoError(t, err)
+	assert.IsType(t, &AWSALBIngress{}, comp)
+
+	cc.Spec.ComponentConfig = map[string]v1alpha1.ComponentConfig{
+		ComponentName: {ControllerKey: ControllerNginx},
+	}
+	comp, err = NewIngressForCluster(cc)
+	assert.NoError(t, err)
+	assert.IsType(t, &NginxIngress{}, comp)
+
+	assert.False(t, ACMEEnabled(cc))
+	cc.Spec.ComponentConfig[ComponentName][TLSIssuerKey] = TLSIssuerACME
+	assert.True(t, ACMEEnabled(cc))
+
+	cc.Spec.ComponentConfig[ComponentName][ControllerKey] = "traefik"
+	_, err = NewIngressForCluster(cc)
+	assert.Error(t, err)
+}
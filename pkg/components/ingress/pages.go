@@ -0,0 +1,129 @@
+package ingress
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+const (
+	// port that the operator serves maintenance pages on
+	PagesPort = 8090
+	// set on requests routed to the operator, the name of the AppTarget under maintenance
+	PageTargetHeader = "x-konstellation-target"
+
+	DefaultMaintenancePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Down for maintenance</title>
+</head>
+<body>
+<h1>Down for maintenance</h1>
+<p>We'll be back shortly.</p>
+</body>
+</html>
+`
+)
+
+// MaintenancePages serves the maintenance page of apps that are under maintenance. the ingress routes their
+// traffic to the operator, since the app may not be running
+type MaintenancePages struct {
+	client client.Client
+}
+
+func NewMaintenancePages(kclient client.Client) *MaintenancePages {
+	return &MaintenancePages{
+		client: kclient,
+	}
+}
+
+func (p *MaintenancePages) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := r.Header.Get(PageTargetHeader)
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// the default page is served when the target can't be loaded, a maintenance page is better than an error
+	page := &v1alpha1.MaintenancePage{}
+	at := &v1alpha1.AppTarget{}
+	err := p.client.Get(context.TODO(), client.ObjectKey{Name: name}, at)
+	if err == nil && at.Spec.Ingress != nil && at.Spec.Ingress.MaintenancePage != nil {
+		page = at.Spec.Ingress.MaintenancePage
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if page.RedirectURL != "" {
+		http.Redirect(w, r, page.RedirectURL, http.StatusFound)
+		return
+	}
+	html := page.HTML
+	if html == "" {
+		html = DefaultMaintenancePage
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Retry-After", "300")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write([]byte(html))
+}
+
+// returns a Lua filter that replaces the body of error responses with the pages. only requests from browsers
+// are given the page, so that API clients still receive the app's response
+func ErrorPagesLua(pages map[string]string) string {
+	var codes []string
+	for code := range pages {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	var sb strings.Builder
+	sb.WriteString("local pages = {}\n")
+	for _, code := range codes {
+		fmt.Fprintf(&sb, "pages[\"%s\"] = %s\n", code, luaLongString(pages[code]))
+	}
+	sb.WriteString(`
+function envoy_on_request(handle)
+  local accept = handle:headers():get("accept") or ""
+  local html = string.find(accept, "text/html", 1, true) ~= nil
+  handle:streamInfo():dynamicMetadata():set("konstellation", "html", html)
+end
+
+function envoy_on_response(handle)
+  local metadata = handle:streamInfo():dynamicMetadata():get("konstellation")
+  if metadata == nil or not metadata["html"] then
+    return
+  end
+  local status = handle:headers():get(":status")
+  local page = pages[status] or pages[string.sub(status, 1, 1) .. "xx"]
+  if page == nil then
+    return
+  end
+  local body = handle:body()
+  if body == nil then
+    return
+  end
+  handle:headers():replace("content-type", "text/html; charset=utf-8")
+  handle:headers():remove("content-length")
+  body:setBytes(page)
+end
+`)
+	return sb.String()
+}
+
+// quotes the content with long brackets, using a level that doesn't appear in it
+func luaLongString(content string) string {
+	level := ""
+	// content could end with part of the closing bracket
+	for strings.Contains(content+"]", "]"+level+"]") {
+		level += "="
+	}
+	// a newline right after the opening bracket is skipped
+	return fmt.Sprintf("[%s[\n%s]%s]", level, content, level)
+}
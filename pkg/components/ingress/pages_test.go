@@ -0,0 +1,87 @@
+package ingress
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestMaintenancePages(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+	newTarget := func(name string, page *v1alpha1.MaintenancePage) *v1alpha1.AppTarget {
+		return &v1alpha1.AppTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.AppTargetSpec{
+				Maintenance: true,
+				Ingress: &v1alpha1.IngressConfig{
+					Hosts:           []string{"www.example.com"},
+					MaintenancePage: page,
+				},
+			},
+		}
+	}
+	kclient := fake.NewFakeClientWithScheme(scheme,
+		newTarget("web-production", &v1alpha1.MaintenancePage{HTML: "<h1>Be right back</h1>"}),
+		newTarget("web-staging", &v1alpha1.MaintenancePage{RedirectURL: "https://status.example.com"}),
+		newTarget("api-production", nil),
+	)
+	pages := NewMaintenancePages(kclient)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/some/path", nil)
+		if target != "" {
+			req.Header.Set(PageTargetHeader, target)
+		}
+		rec := httptest.NewRecorder()
+		pages.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("web-production")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "<h1>Be right back</h1>", rec.Body.String())
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/html")
+
+	rec = serve("web-staging")
+	assert.Equal(t, http.StatusFound, rec.Code)
+	assert.Equal(t, "https://status.example.com", rec.Header().Get("Location"))
+
+	// default page when it's not configured, or the target couldn't be found
+	for _, target := range []string{"api-production", "unknown"} {
+		rec = serve(target)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, DefaultMaintenancePage, rec.Body.String())
+	}
+
+	rec = serve("")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestErrorPagesLua(t *testing.T) {
+	code := ErrorPagesLua(map[string]string{
+		"5xx": "<h1>Something went wrong</h1>",
+		"404": "<h1>Not found</h1>",
+	})
+	assert.True(t, strings.HasPrefix(code, "local pages = {}\n"+
+		"pages[\"404\"] = [[\n<h1>Not found</h1>]]\n"+
+		"pages[\"5xx\"] = [[\n<h1>Something went wrong</h1>]]\n"))
+	assert.Contains(t, code, "function envoy_on_response(handle)")
+}
+
+func TestLuaLongString(t *testing.T) {
+	assert.Equal(t, "[[\n<p>hello</p>]]", luaLongString("<p>hello</p>"))
+	assert.Equal(t, "[=[\nitems[a[1]]]=]", luaLongString("items[a[1]]"))
+	// content ending with part of the closing bracket
+	assert.Equal(t, "[=[\na]]=]", luaLongString("a]"))
+	assert.Equal(t, "[[\na]=]]", luaLongString("a]="))
+	assert.Equal(t, "[==[\n]]a]=]==]", luaLongString("]]a]="))
+}
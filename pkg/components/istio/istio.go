@@ -112,6 +112,8 @@ func (i *IstioInstaller) InstallComponent(kclient client.Client) error {
 		"--set", "values.gateways.istio-ingressgateway.type=NodePort",
 		"--set", "values.gateways.enabled=true",
 		"--set", "meshConfig.defaultConfig.holdApplicationUntilProxyStarts=true",
+		// the gateway is behind a load balancer, which adds the client's address to X-Forwarded-For
+		"--set", "meshConfig.defaultConfig.gatewayTopology.numTrustedProxies=1",
 	)
 	if err != nil {
 		return err
@@ -24,6 +24,10 @@ const (
 	ComponentName   = "kube-prometheus"
 	DiskSizeKey     = "disk-size"
 	DefaultDiskSize = "100Gi"
+
+	// number of days before a certificate expires to alert on
+	CertExpiryAlertDaysKey     = "cert-expiry-alert-days"
+	DefaultCertExpiryAlertDays = 21
 )
 
 func init() {
@@ -0,0 +1,59 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promapi "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+var (
+	// address of Prometheus from within the cluster
+	ClusterAddress = fmt.Sprintf("http://prometheus-k8s.%s.svc.cluster.local:9090", resources.KonSystemNamespace)
+
+	// the query matched no series, the metric hasn't been recorded, which isn't the same as a value of 0
+	ErrNoSeries = fmt.Errorf("the query returned no series")
+)
+
+func NewQueryAPI(address string) (promapi.API, error) {
+	client, err := api.NewClient(api.Config{
+		Address: address,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promapi.NewAPI(client), nil
+}
+
+// runs a query that results in a single number. series with no samples are left out of results,
+// ErrNoSeries is returned when nothing is
+func QueryScalar(ctx context.Context, qapi promapi.API, query string, ts time.Time) (float64, error) {
+	val, _, err := qapi.Query(ctx, query, ts)
+	if err != nil {
+		return 0, err
+	}
+	switch v := val.(type) {
+	case *model.Scalar:
+		return float64(v.Value), nil
+	case model.Vector:
+		if len(v) == 0 {
+			return 0, ErrNoSeries
+		}
+		if len(v) > 1 {
+			return 0, fmt.Errorf("query returned %d series, expected one: %s", len(v), query)
+		}
+		return float64(v[0].Value), nil
+	}
+	return 0, fmt.Errorf("unexpected result type %s: %s", val.Type(), query)
+}
+
+// returns the number of requests that the app's service received within the window
+func ServiceRequestsQuery(namespace, service string, window time.Duration) string {
+	return fmt.Sprintf(`sum(increase(istio_requests_total{reporter="destination",destination_service_namespace="%s",destination_service_name="%s"}[%s]))`,
+		namespace, service, model.Duration(window))
+}
@@ -0,0 +1,52 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryScalar(t *testing.T) {
+	responses := map[string]string{
+		"one":    `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1614556800,"42.5"]}]}}`,
+		"empty":  `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+		"many":   `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1614556800,"1"]},{"metric":{"a":"2"},"value":[1614556800,"2"]}]}}`,
+		"scalar": `{"status":"success","data":{"resultType":"scalar","result":[1614556800,"3"]}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(responses[r.Form.Get("query")]))
+	}))
+	defer server.Close()
+
+	qapi, err := NewQueryAPI(server.URL)
+	assert.NoError(t, err)
+	ctx := context.TODO()
+	now := time.Now()
+
+	val, err := QueryScalar(ctx, qapi, "one", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 42.5, val)
+
+	// missing metrics are told apart from 0
+	_, err = QueryScalar(ctx, qapi, "empty", now)
+	assert.Equal(t, ErrNoSeries, err)
+
+	val, err = QueryScalar(ctx, qapi, "scalar", now)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, val)
+
+	_, err = QueryScalar(ctx, qapi, "many", now)
+	assert.Error(t, err)
+}
+
+func TestServiceRequestsQuery(t *testing.T) {
+	assert.Equal(t,
+		`sum(increase(istio_requests_total{reporter="destination",destination_service_namespace="staging",destination_service_name="web"}[15m]))`,
+		ServiceRequestsQuery("staging", "web", 15*time.Minute))
+}
@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	promapi "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// period of usage that recommendations are computed from by default
+	DefaultRecommendationWindow = 7 * 24 * time.Hour
+
+	// percentiles of observed usage that requests and limits are based on
+	RequestPercentile = 0.9
+	LimitPercentile   = 0.99
+
+	// headroom added on top of observed usage
+	recommendationMargin = 0.15
+	usageResolution      = 5 * time.Minute
+	minCPUMillis         = 10
+	minMemoryMi          = 32
+)
+
+// returns the percentile of a container's usage over the window, the highest across its pods
+func ContainerUsageQuery(namespace, container string, name corev1.ResourceName, percentile float64, window time.Duration) string {
+	selector := fmt.Sprintf(`namespace="%s",container="%s"`, namespace, container)
+	if name == corev1.ResourceCPU {
+		return fmt.Sprintf(`max(quantile_over_time(%g, rate(container_cpu_usage_seconds_total{%s}[%s])[%s:%s]))`,
+			percentile, selector, model.Duration(usageResolution), model.Duration(window), model.Duration(usageResolution))
+	}
+	return fmt.Sprintf(`max(quantile_over_time(%g, container_memory_working_set_bytes{%s}[%s]))`,
+		percentile, selector, model.Duration(window))
+}
+
+// computes requests and limits for a container from its CPU and memory usage in Prometheus
+func RecommendResources(ctx context.Context, qapi promapi.API, namespace, container string, window time.Duration, now time.Time) (*corev1.ResourceRequirements, error) {
+	reqs := &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{},
+		Limits:   corev1.ResourceList{},
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		for percentile, list := range map[float64]corev1.ResourceList{
+			RequestPercentile: reqs.Requests,
+			LimitPercentile:   reqs.Limits,
+		} {
+			query := ContainerUsageQuery(namespace, container, name, percentile, window)
+			usage, err := QueryScalar(ctx, qapi, query, now)
+			if err != nil && err != ErrNoSeries {
+				return nil, err
+			}
+			if usage == 0 {
+				return nil, fmt.Errorf("no %s usage found for %s in %s", name, container, namespace)
+			}
+			list[name] = RecommendedQuantity(name, usage)
+		}
+	}
+	return reqs, nil
+}
+
+// adds headroom to observed usage, rounding up to millicores for CPU and mebibytes for memory
+func RecommendedQuantity(name corev1.ResourceName, usage float64) resource.Quantity {
+	usage *= 1 + recommendationMargin
+	if name == corev1.ResourceCPU {
+		millis := int64(math.Ceil(usage * 1000))
+		if millis < minCPUMillis {
+			millis = minCPUMillis
+		}
+		return *resource.NewMilliQuantity(millis, resource.DecimalSI)
+	}
+	mi := int64(math.Ceil(usage / (1 << 20)))
+	if mi < minMemoryMi {
+		mi = minMemoryMi
+	}
+	return resource.MustParse(fmt.Sprintf("%dMi", mi))
+}
@@ -0,0 +1,72 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestContainerUsageQuery(t *testing.T) {
+	assert.Equal(t,
+		`max(quantile_over_time(0.9, rate(container_cpu_usage_seconds_total{namespace="staging",container="web"}[5m])[1w:5m]))`,
+		ContainerUsageQuery("staging", "web", corev1.ResourceCPU, 0.9, 7*24*time.Hour))
+	assert.Equal(t,
+		`max(quantile_over_time(0.99, container_memory_working_set_bytes{namespace="staging",container="web"}[1d]))`,
+		ContainerUsageQuery("staging", "web", corev1.ResourceMemory, 0.99, 24*time.Hour))
+}
+
+func TestRecommendedQuantity(t *testing.T) {
+	cpu := RecommendedQuantity(corev1.ResourceCPU, 0.2)
+	assert.Equal(t, "230m", cpu.String())
+	cpu = RecommendedQuantity(corev1.ResourceCPU, 0.001)
+	assert.Equal(t, "10m", cpu.String())
+
+	mem := RecommendedQuantity(corev1.ResourceMemory, 100*(1<<20))
+	assert.Equal(t, "115Mi", mem.String())
+	mem = RecommendedQuantity(corev1.ResourceMemory, 1<<20)
+	assert.Equal(t, "32Mi", mem.String())
+}
+
+func TestRecommendResources(t *testing.T) {
+	// 0.5 cores and 200Mi at the 90th percentile, 2.5 cores and 300Mi at the 99th
+	usage := map[string][]string{
+		"container_cpu_usage_seconds_total":  {"0.5", "2.5"},
+		"container_memory_working_set_bytes": {"209715200", "314572800"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		query := r.Form.Get("query")
+		result := `[]`
+		for metric, vals := range usage {
+			if strings.Contains(query, metric) && !strings.Contains(query, `namespace="empty"`) {
+				val := vals[0]
+				if strings.Contains(query, "0.99") {
+					val = vals[1]
+				}
+				result = `[{"metric":{},"value":[1614556800,"` + val + `"]}]`
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":` + result + `}}`))
+	}))
+	defer server.Close()
+
+	qapi, err := NewQueryAPI(server.URL)
+	assert.NoError(t, err)
+
+	reqs, err := RecommendResources(context.TODO(), qapi, "staging", "web", DefaultRecommendationWindow, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "575m", reqs.Requests.Cpu().String())
+	assert.Equal(t, "230Mi", reqs.Requests.Memory().String())
+	assert.Equal(t, "2875m", reqs.Limits.Cpu().String())
+	assert.Equal(t, "345Mi", reqs.Limits.Memory().String())
+
+	_, err = RecommendResources(context.TODO(), qapi, "empty", "web", DefaultRecommendationWindow, time.Now())
+	assert.Error(t, err)
+}
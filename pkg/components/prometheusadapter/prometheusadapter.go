@@ -0,0 +1,147 @@
+package prometheusadapter
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components"
+	"github.com/k11n/konstellation/pkg/resources"
+)
+
+func init() {
+	components.RegisterComponent(&PrometheusAdapter{})
+}
+
+const (
+	ComponentName    = "prometheus-adapter"
+	componentVersion = "0.8.2"
+
+	Namespace      = "kon-system"
+	DeploymentName = "custom-metrics-adapter"
+	ConfigMapName  = "custom-metrics-adapter-config"
+	ConfigKey      = "config.yaml"
+
+	// ComponentConfig keys
+	// comma separated prefixes of metric names that are served as external metrics, i.e. queue_,jobs_
+	ExternalMetricsPrefixesKey = "external-metrics-prefixes"
+)
+
+var (
+	metricPrefixRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+)
+
+// counters are exposed as rates per second, i.e. http_requests_total as http_requests_per_second
+const podRules = `rules:
+- seriesQuery: '{__name__=~"^.+_total$",namespace!="",pod!=""}'
+  resources:
+    overrides:
+      namespace: {resource: "namespace"}
+      pod: {resource: "pod"}
+  name:
+    matches: "^(.*)_total$"
+    as: "${1}_per_second"
+  metricsQuery: 'sum(rate(<<.Series>>{<<.LabelMatchers>>}[2m])) by (<<.GroupBy>>)'
+- seriesQuery: '{__name__!~"^.+_total$",namespace!="",pod!=""}'
+  resources:
+    overrides:
+      namespace: {resource: "namespace"}
+      pod: {resource: "pod"}
+  metricsQuery: 'sum(<<.Series>>{<<.LabelMatchers>>}) by (<<.GroupBy>>)'
+`
+
+// external metrics aren't tied to pods, so they are limited to the configured prefixes. otherwise any series in
+// Prometheus could be read through the external metrics API, including ones of other namespaces
+const externalRules = `externalRules:
+- seriesQuery: '{__name__=~"^%[1]s.+_total$"}'
+  resources:
+    overrides:
+      namespace: {resource: "namespace"}
+  name:
+    matches: "^(.*)_total$"
+    as: "${1}_per_second"
+  metricsQuery: 'sum(rate(<<.Series>>{<<.LabelMatchers>>}[2m])) by (<<.GroupBy>>)'
+- seriesQuery: '{__name__=~"^%[1]s.+$",__name__!~"^.+_total$"}'
+  resources:
+    overrides:
+      namespace: {resource: "namespace"}
+  metricsQuery: 'sum(<<.Series>>{<<.LabelMatchers>>}) by (<<.GroupBy>>)'
+`
+
+// PrometheusAdapter serves the custom and external metrics APIs from Prometheus, so that apps could scale
+// on their own metrics. resource metrics are served by the adapter that's part of kube-prometheus
+type PrometheusAdapter struct {
+}
+
+func (a *PrometheusAdapter) Name() string {
+	return ComponentName
+}
+
+func (a *PrometheusAdapter) VersionForKube(version string) string {
+	return componentVersion
+}
+
+type adapterConfig struct {
+	Version    string
+	ConfigHash string
+}
+
+func (a *PrometheusAdapter) InstallComponent(kclient client.Client) error {
+	cc, err := resources.GetClusterConfig(kclient)
+	if err != nil {
+		return err
+	}
+	cm := ConfigMap(cc.Spec.ComponentConfig[ComponentName])
+	if _, err = resources.UpdateResource(kclient, cm, nil, nil); err != nil {
+		return err
+	}
+
+	return components.ApplyTemplate("prometheus-adapter", adapterConfig{
+		Version:    componentVersion,
+		ConfigHash: ConfigHash(cm),
+	})
+}
+
+// returns prefixes of external metrics that are configured, invalid ones are ignored
+func ExternalMetricsPrefixes(config v1alpha1.ComponentConfig) []string {
+	var prefixes []string
+	for _, item := range strings.Split(config[ExternalMetricsPrefixesKey], ",") {
+		item = strings.TrimSpace(item)
+		if metricPrefixRegex.MatchString(item) {
+			prefixes = append(prefixes, item)
+		}
+	}
+	return prefixes
+}
+
+// returns the adapter's rules for the ComponentConfig. external metrics are served only when prefixes are configured
+func Config(config v1alpha1.ComponentConfig) string {
+	prefixes := ExternalMetricsPrefixes(config)
+	if len(prefixes) == 0 {
+		return podRules
+	}
+	return podRules + fmt.Sprintf(externalRules, "("+strings.Join(prefixes, "|")+")")
+}
+
+func ConfigMap(config v1alpha1.ComponentConfig) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: Namespace,
+			Name:      ConfigMapName,
+		},
+		Data: map[string]string{
+			ConfigKey: Config(config),
+		},
+	}
+}
+
+// hash of the rules, set on the adapter's pods so they are restarted when rules change
+func ConfigHash(cm *corev1.ConfigMap) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(cm.Data[ConfigKey])))
+}
@@ -0,0 +1,33 @@
+package prometheusadapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestExternalMetricsPrefixes(t *testing.T) {
+	assert.Empty(t, ExternalMetricsPrefixes(nil))
+	assert.Equal(t, []string{"queue_", "jobs:"}, ExternalMetricsPrefixes(v1alpha1.ComponentConfig{
+		ExternalMetricsPrefixesKey: "queue_, jobs:,,.*,a|b",
+	}))
+}
+
+func TestConfig(t *testing.T) {
+	config := Config(nil)
+	assert.Contains(t, config, "rules:")
+	assert.NotContains(t, config, "externalRules:")
+
+	config = Config(v1alpha1.ComponentConfig{
+		ExternalMetricsPrefixesKey: "queue_,jobs_",
+	})
+	assert.Contains(t, config, "externalRules:")
+	assert.Contains(t, config, `'{__name__=~"^(queue_|jobs_).+_total$"}'`)
+	assert.Contains(t, config, `'{__name__=~"^(queue_|jobs_).+$",__name__!~"^.+_total$"}'`)
+
+	assert.NotEqual(t, ConfigHash(ConfigMap(nil)), ConfigHash(ConfigMap(v1alpha1.ComponentConfig{
+		ExternalMetricsPrefixesKey: "queue_",
+	})))
+}
@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"sort"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+	"github.com/k11n/konstellation/pkg/components"
+)
+
+func init() {
+	components.RegisterComponent(&RateLimitService{})
+}
+
+const (
+	ComponentName    = "ratelimit"
+	componentVersion = "v1.4.0"
+
+	// descriptors of all apps are kept in a single domain, since the gateway filter is shared
+	Domain        = "konstellation"
+	ConfigMapName = "ratelimit-config"
+	ConfigKey     = "config.yaml"
+)
+
+// RateLimitService is Envoy's global rate limit service. the ingress gateway checks requests with it,
+// so that limits could be applied to each client, across all instances of the gateway
+type RateLimitService struct {
+}
+
+func (s *RateLimitService) Name() string {
+	return ComponentName
+}
+
+func (s *RateLimitService) VersionForKube(version string) string {
+	return componentVersion
+}
+
+type serviceConfig struct {
+	Version       string
+	Domain        string
+	ConfigMapName string
+}
+
+func (s *RateLimitService) InstallComponent(kclient client.Client) error {
+	return components.ApplyTemplate("ratelimit", serviceConfig{
+		Version:       componentVersion,
+		Domain:        Domain,
+		ConfigMapName: ConfigMapName,
+	})
+}
+
+// descriptor keys, matching the actions that the gateway sends for requests to hosts of apps
+const (
+	TargetKey        = "generic_key"
+	RemoteAddressKey = "remote_address"
+	HeaderKey        = "header"
+)
+
+type descriptor struct {
+	Key         string       `json:"key"`
+	Value       string       `json:"value,omitempty"`
+	RateLimit   *limit       `json:"rate_limit,omitempty"`
+	Descriptors []descriptor `json:"descriptors,omitempty"`
+}
+
+type limit struct {
+	Unit            string `json:"unit"`
+	RequestsPerUnit int32  `json:"requests_per_unit"`
+}
+
+// returns the rate limit service config with the limits of the targets. each target has its own counters,
+// for each client IP or header value
+func NewConfig(targets []v1alpha1.AppTarget) (string, error) {
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	config := struct {
+		Domain      string       `json:"domain"`
+		Descriptors []descriptor `json:"descriptors"`
+	}{
+		Domain:      Domain,
+		Descriptors: []descriptor{},
+	}
+	for _, at := range targets {
+		if !at.NeedsIngress() || at.Spec.Ingress.RateLimit == nil {
+			continue
+		}
+		rateLimit := at.Spec.Ingress.RateLimit
+		clientKey := RemoteAddressKey
+		if rateLimit.Header != "" {
+			clientKey = HeaderKey
+		}
+		config.Descriptors = append(config.Descriptors, descriptor{
+			Key:   TargetKey,
+			Value: at.Name,
+			Descriptors: []descriptor{
+				{
+					Key: clientKey,
+					RateLimit: &limit{
+						Unit:            "second",
+						RequestsPerUnit: rateLimit.RequestsPerSecond,
+					},
+				},
+			},
+		})
+	}
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestNewConfig(t *testing.T) {
+	newTarget := func(name string, rateLimit *v1alpha1.RateLimitConfig) v1alpha1.AppTarget {
+		return v1alpha1.AppTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.AppTargetSpec{
+				Ingress: &v1alpha1.IngressConfig{
+					Hosts:     []string{name + ".example.com"},
+					RateLimit: rateLimit,
+				},
+			},
+		}
+	}
+
+	config, err := NewConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "descriptors: []\ndomain: konstellation\n", config)
+
+	config, err = NewConfig([]v1alpha1.AppTarget{
+		newTarget("web-production", &v1alpha1.RateLimitConfig{RequestsPerSecond: 10}),
+		newTarget("docs-production", nil),
+		newTarget("api-production", &v1alpha1.RateLimitConfig{RequestsPerSecond: 100, Header: "x-api-key"}),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `descriptors:
+- descriptors:
+  - key: header
+    rate_limit:
+      requests_per_unit: 100
+      unit: second
+  key: generic_key
+  value: api-production
+- descriptors:
+  - key: remote_address
+    rate_limit:
+      requests_per_unit: 10
+      unit: second
+  key: generic_key
+  value: web-production
+domain: konstellation
+`, config)
+}
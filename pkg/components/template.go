@@ -0,0 +1,35 @@
+package components
+
+import (
+	"bytes"
+	"io/ioutil"
+	"text/template"
+
+	"github.com/k11n/konstellation/pkg/utils/assets"
+	"github.com/k11n/konstellation/pkg/utils/cli"
+)
+
+// renders deploy/templates/<name>.yaml with the config, and applies it to the current cluster
+func ApplyTemplate(name string, config interface{}) error {
+	box := assets.DeployResourcesBox()
+	f, err := box.Open("templates/" + name + ".yaml")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New(name).Parse(string(content))
+	if err != nil {
+		return err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = tmpl.Execute(buf, config); err != nil {
+		return err
+	}
+	return cli.KubeApplyReader(buf)
+}
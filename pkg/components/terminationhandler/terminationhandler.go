@@ -0,0 +1,39 @@
+package terminationhandler
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/pkg/components"
+)
+
+func init() {
+	components.RegisterComponent(&NodeTerminationHandler{})
+}
+
+const (
+	ComponentName    = "aws-node-termination-handler"
+	componentVersion = "1.13.0"
+)
+
+// NodeTerminationHandler cordons and drains spot nodes when they receive an interruption notice,
+// so that pods are rescheduled before the instance is reclaimed. it runs only on spot nodes
+type NodeTerminationHandler struct {
+}
+
+func (h *NodeTerminationHandler) Name() string {
+	return ComponentName
+}
+
+func (h *NodeTerminationHandler) VersionForKube(version string) string {
+	return componentVersion
+}
+
+type handlerConfig struct {
+	Version string
+}
+
+func (h *NodeTerminationHandler) InstallComponent(kclient client.Client) error {
+	return components.ApplyTemplate("aws-node-termination-handler", handlerConfig{
+		Version: componentVersion,
+	})
+}
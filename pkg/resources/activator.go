@@ -0,0 +1,63 @@
+package resources
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	ActivatorSecretName = "konstellation-activator"
+	activatorTokenKey   = "token"
+	activatorTokenBytes = 32
+)
+
+// returns the token that routes to the activator set on requests, so that it only acts on requests routed by
+// Istio, and not on ones sent to the pages Service directly. it's kept in a secret so that all replicas share it
+func EnsureActivatorToken(kclient client.Client) (string, error) {
+	secret, err := GetSecret(kclient, KonSystemNamespace, ActivatorSecretName)
+	if err == nil && len(secret.Data[activatorTokenKey]) != 0 {
+		return string(secret.Data[activatorTokenKey]), nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+	exists := err == nil
+
+	b := make([]byte, activatorTokenBytes)
+	if _, err = rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: KonSystemNamespace,
+				Name:      ActivatorSecretName,
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+	}
+	secret.Data = map[string][]byte{
+		activatorTokenKey: []byte(token),
+	}
+	if exists {
+		err = kclient.Update(context.TODO(), secret)
+	} else {
+		err = kclient.Create(context.TODO(), secret)
+	}
+	if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
+		// another replica got to it first, use theirs
+		return EnsureActivatorToken(kclient)
+	}
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
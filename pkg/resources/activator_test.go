@@ -0,0 +1,26 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureActivatorToken(t *testing.T) {
+	kclient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme)
+
+	token, err := EnsureActivatorToken(kclient)
+	assert.NoError(t, err)
+	assert.Len(t, token, 2*activatorTokenBytes)
+
+	secret, err := GetSecret(kclient, KonSystemNamespace, ActivatorSecretName)
+	assert.NoError(t, err)
+	assert.Equal(t, token, string(secret.Data[activatorTokenKey]))
+
+	// existing token is reused
+	existing, err := EnsureActivatorToken(kclient)
+	assert.NoError(t, err)
+	assert.Equal(t, token, existing)
+}
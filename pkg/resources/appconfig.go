@@ -1,14 +1,19 @@
 package resources
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+	"text/template"
 
+	pkgerrors "github.com/pkg/errors"
 	"github.com/thoas/go-funk"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -72,6 +77,9 @@ func SaveAppConfig(kclient client.Client, ac *v1alpha1.AppConfig) error {
 		existing.Annotations = ac.Annotations
 		existing.Type = ac.Type
 		existing.ConfigYaml = ac.ConfigYaml
+		existing.ListMerge = ac.ListMerge
+		existing.Format = ac.Format
+		existing.Schema = ac.Schema
 		return nil
 	})
 	return err
@@ -117,19 +125,201 @@ func GetMergedConfigForType(kclient client.Client, confType v1alpha1.ConfigType,
 	return baseConfig, nil
 }
 
-func CreateConfigMap(appName string, ac *v1alpha1.AppConfig, sharedConfigs []*v1alpha1.AppConfig) *corev1.ConfigMap {
+// finds the schema that applies to the config. app configs use schema defined on the App,
+// shared configs use the schema set on the base shared config
+func GetConfigSchema(kclient client.Client, ac *v1alpha1.AppConfig) (schema []byte, err error) {
+	if ac.Type == v1alpha1.ConfigTypeApp {
+		app, err := GetAppByName(kclient, ac.GetAppName())
+		if errors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if app.Spec.ConfigSchema != nil {
+			schema = app.Spec.ConfigSchema.Raw
+		}
+		return schema, nil
+	}
+
+	if ac.GetTarget() == "" {
+		return ac.Schema, nil
+	}
+	base, err := GetConfigForType(kclient, ac.Type, ac.GetSharedName(), "")
+	if err == ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return base.Schema, nil
+}
+
+// validates the config against its schema, before it's saved.
+// since target configs are merged onto the base config, validation is performed against the merged config
+// for each of the targets affected.
+func ValidateAppConfig(kclient client.Client, ac *v1alpha1.AppConfig, schema []byte) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	name := ac.GetSharedName()
+	if ac.Type == v1alpha1.ConfigTypeApp {
+		name = ac.GetAppName()
+	}
+
+	// target config, merge with base
+	if ac.GetTarget() != "" {
+		merged := ac.DeepCopy()
+		base, err := GetConfigForType(kclient, ac.Type, name, "")
+		if err == nil {
+			merged = base.DeepCopy()
+			merged.MergeWith(ac)
+		} else if err != ErrNotFound {
+			return err
+		}
+		return pkgerrors.Wrapf(merged.ValidateSchema(schema), "target %s", ac.GetTarget())
+	}
+
+	// base config, validate against each of the targets
+	cc, err := GetClusterConfig(kclient)
+	if err == ErrNotFound || (err == nil && len(cc.Spec.Targets) == 0) {
+		return ac.ValidateSchema(schema)
+	} else if err != nil {
+		return err
+	}
+	for _, target := range cc.Spec.Targets {
+		merged := ac.DeepCopy()
+		targetConf, err := GetConfigForType(kclient, ac.Type, name, target)
+		if err == nil {
+			merged.MergeWith(targetConf)
+		} else if err != ErrNotFound {
+			return err
+		}
+		if err = merged.ValidateSchema(schema); err != nil {
+			return pkgerrors.Wrapf(err, "target %s", target)
+		}
+	}
+	return nil
+}
+
+// data that's available to config templates, i.e. {{ .Target }}
+type ConfigTemplateData struct {
+	App     string
+	Target  string
+	Cluster string
+	Region  string
+}
+
+// renders templated config values for the app target. In addition to ConfigTemplateData fields,
+// {{ dependency "app" "port" }} resolves to the host:port of a dependency in the same target.
+// configs are left untouched unless templating is enabled on them
+func RenderConfig(kclient client.Client, ac *v1alpha1.AppConfig, app, target string) error {
+	if !ac.Templated {
+		return nil
+	}
+
+	data := ConfigTemplateData{
+		App:    app,
+		Target: target,
+	}
+	cc, err := GetClusterConfig(kclient)
+	if err == nil {
+		data.Cluster = cc.Name
+		data.Region = cc.Spec.Region
+	} else if err != ErrNotFound {
+		return err
+	}
+
+	funcs := template.FuncMap{
+		"dependency": func(name string, port ...string) (string, error) {
+			ref := v1alpha1.AppReference{Name: name}
+			if len(port) > 0 {
+				ref.Port = port[0]
+			}
+			deps, err := GetDependencyInfos(kclient, ref, target)
+			if err != nil {
+				return "", err
+			}
+			if len(deps) == 0 {
+				return "", fmt.Errorf("dependency %s does not have port %s", name, ref.Port)
+			}
+			return fmt.Sprintf("%s:%d", ServiceHostname(deps[0].Namespace, deps[0].Service), deps[0].Port), nil
+		},
+	}
+
+	config := ac.GetConfig()
+	if config == nil {
+		return nil
+	}
+	rendered, err := renderConfigValue(config, data, funcs)
+	if err != nil {
+		return fmt.Errorf("could not render config %s: %v", ac.Name, err)
+	}
+	return ac.SetConfig(rendered.(map[string]interface{}))
+}
+
+func renderConfigValue(val interface{}, data interface{}, funcs template.FuncMap) (interface{}, error) {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			rendered, err := renderConfigValue(item, data, funcs)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = rendered
+		}
+		return v, nil
+	case []interface{}:
+		for i, item := range v {
+			rendered, err := renderConfigValue(item, data, funcs)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+		return v, nil
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("config").Option("missingkey=error").Funcs(funcs).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		buf := bytes.NewBuffer(nil)
+		if err = tmpl.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	}
+	return val, nil
+}
+
+func CreateConfigMap(appName string, ac *v1alpha1.AppConfig, sharedConfigs []*v1alpha1.AppConfig, envSpec *v1alpha1.ConfigEnvSpec) (*corev1.ConfigMap, error) {
 	data := make(map[string]string)
+	// env vars that contain full configs, and the file names they'd be mounted as
+	configFiles := make(map[string]string)
+	var skipped []v1alpha1.SkippedEnvKey
 	if ac != nil {
-		data = ac.ToEnvMap()
+		data, skipped = ac.ToEnv(envSpec)
+		// full config is passed in the desired format
+		content, err := ac.GetConfigContent()
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "could not convert config to %s", ac.GetFormat())
+		}
+		data[v1alpha1.ConfigEnvVar] = string(content)
+		configFiles[v1alpha1.ConfigEnvVar] = "config" + ac.GetFormat().Extension()
 	}
 
 	for _, conf := range sharedConfigs {
-		// store these as straight YAML
-		name := conf.GetSharedName()
-		name = strings.ToUpper(name)
-		name = strings.ReplaceAll(name, "-", "_")
-
-		data[name] = string(conf.ConfigYaml)
+		// store these as files in the config's format
+		content, err := conf.GetConfigContent()
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "could not convert shared config %s to %s",
+				conf.GetSharedName(), conf.GetFormat())
+		}
+		envName := SharedConfigEnvName(conf.GetSharedName())
+		data[envName] = string(content)
+		configFiles[envName] = conf.GetSharedName() + conf.GetFormat().Extension()
 	}
 
 	// create sha
@@ -141,7 +331,7 @@ func CreateConfigMap(appName string, ac *v1alpha1.AppConfig, sharedConfigs []*v1
 	}
 	hash := fmt.Sprintf("%x", h.Sum(nil))
 
-	return &corev1.ConfigMap{
+	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: fmt.Sprintf("%s-%s", appName, hash[:6]),
 			Labels: map[string]string{
@@ -150,4 +340,53 @@ func CreateConfigMap(appName string, ac *v1alpha1.AppConfig, sharedConfigs []*v1
 		},
 		Data: data,
 	}
+
+	cm.Annotations = make(map[string]string)
+	if content, err := json.Marshal(configFiles); err == nil {
+		cm.Annotations[v1alpha1.ConfigFilesAnnotation] = string(content)
+	}
+	// keep track of keys that aren't available as env vars
+	if len(skipped) > 0 {
+		if content, err := json.Marshal(skipped); err == nil {
+			cm.Annotations[v1alpha1.ConfigSkippedKeysAnnotation] = string(content)
+		}
+	}
+	return cm, nil
+}
+
+// returns full configs in the ConfigMap keyed by file name, to be mounted into pods
+func GetConfigFiles(cm *corev1.ConfigMap) (configFiles map[string]string, err error) {
+	fileNames := make(map[string]string)
+	if content := cm.Annotations[v1alpha1.ConfigFilesAnnotation]; content != "" {
+		if err = json.Unmarshal([]byte(content), &fileNames); err != nil {
+			return
+		}
+	} else {
+		// created before file names were tracked, configs were always YAML
+		fileNames[v1alpha1.ConfigEnvVar] = "config" + v1alpha1.ConfigFormatYAML.Extension()
+	}
+
+	configFiles = make(map[string]string)
+	for key, fileName := range fileNames {
+		if val, ok := cm.Data[key]; ok {
+			configFiles[fileName] = val
+		}
+	}
+	return
+}
+
+// returns keys in the config that could not be converted into env vars
+func GetSkippedEnvKeys(cm *corev1.ConfigMap) (skipped []v1alpha1.SkippedEnvKey, err error) {
+	content := cm.Annotations[v1alpha1.ConfigSkippedKeysAnnotation]
+	if content == "" {
+		return
+	}
+	err = json.Unmarshal([]byte(content), &skipped)
+	return
+}
+
+// name of the env var that contains the shared config
+func SharedConfigEnvName(name string) string {
+	name = strings.ToUpper(name)
+	return strings.ReplaceAll(name, "-", "_")
 }
@@ -0,0 +1,74 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/k11n/konstellation/api/v1alpha1"
+)
+
+func TestRenderConfigValue(t *testing.T) {
+	data := ConfigTemplateData{
+		App:    "myapp",
+		Target: "prod",
+		Region: "us-west-2",
+	}
+	funcs := template.FuncMap{
+		"dependency": func(name string, port ...string) (string, error) {
+			return fmt.Sprintf("%s.%s.svc.cluster.local:80", name, data.Target), nil
+		},
+	}
+	config := map[string]interface{}{
+		"bucket": "{{ .App }}-{{ .Target }}",
+		"nested": map[string]interface{}{
+			"api":    `{{ dependency "api" "http" }}`,
+			"region": []interface{}{"{{ .Region }}", 1},
+		},
+		"count": 2,
+	}
+
+	rendered, err := renderConfigValue(config, data, funcs)
+	assert.NoError(t, err)
+	renderedMap := rendered.(map[string]interface{})
+	assert.Equal(t, "myapp-prod", renderedMap["bucket"])
+	assert.Equal(t, 2, renderedMap["count"])
+	nested := renderedMap["nested"].(map[string]interface{})
+	assert.Equal(t, "api.prod.svc.cluster.local:80", nested["api"])
+	assert.Equal(t, []interface{}{"us-west-2", 1}, nested["region"])
+
+	// unknown fields should result in errors
+	_, err = renderConfigValue("{{ .Unknown }}", data, funcs)
+	assert.Error(t, err)
+}
+
+func TestRenderConfigNotTemplated(t *testing.T) {
+	ac := v1alpha1.NewAppConfig("myapp", "")
+	assert.NoError(t, ac.SetConfigYAML([]byte("format: \"{{ .Target }} {{literal}}\"\n")))
+
+	// configs without templating should be passed through, even with values that aren't valid templates
+	assert.NoError(t, RenderConfig(nil, ac, "myapp", "prod"))
+	assert.Equal(t, "{{ .Target }} {{literal}}", ac.GetConfig()["format"])
+}
+
+func TestCreateConfigMapFiles(t *testing.T) {
+	ac := v1alpha1.NewAppConfig("myapp", "")
+	ac.Format = v1alpha1.ConfigFormatTOML
+	assert.NoError(t, ac.SetConfigYAML([]byte("name: myapp\n")))
+	shared := v1alpha1.NewSharedConfig("db-conn", "")
+	assert.NoError(t, shared.SetConfigYAML([]byte("host: localhost\n")))
+
+	cm, err := CreateConfigMap("myapp", ac, []*v1alpha1.AppConfig{shared}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp", cm.Data["NAME"])
+	assert.Contains(t, cm.Data, "DB_CONN")
+
+	files, err := GetConfigFiles(cm)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"config.toml":  "name = \"myapp\"\n",
+		"db-conn.yaml": "host: localhost\n",
+	}, files)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/k11n/konstellation/api/v1alpha1"
@@ -36,13 +37,17 @@ func GetCertificateForDomain(kclient client.Client, domain string) (cert *v1alph
 	return
 }
 
-func GetCertificatesForHosts(kclient client.Client, hosts []string) (certs map[string]*v1alpha1.CertificateRef, err error) {
+// returns the certificate from the source that covers each host, preferring ones that expire last
+func GetCertificatesForHosts(kclient client.Client, hosts []string, source v1alpha1.CertificateSource) (certs map[string]*v1alpha1.CertificateRef, err error) {
 	certs = make(map[string]*v1alpha1.CertificateRef)
 	if len(hosts) == 0 {
 		return
 	}
 	err = ForEach(kclient, &v1alpha1.CertificateRefList{}, func(item interface{}) error {
 		cert := item.(v1alpha1.CertificateRef)
+		if cert.GetSource() != source {
+			return nil
+		}
 		for _, host := range hosts {
 			if !CertificateCovers(cert.Spec.Domain, host) {
 				continue
@@ -56,6 +61,25 @@ func GetCertificatesForHosts(kclient client.Client, hosts []string) (certs map[s
 	return
 }
 
+// returns a certificate to be issued via ACME, along with the secret it's stored in
+func NewACMECertificate(domain string) *v1alpha1.CertificateRef {
+	name := "acme-" + strings.Replace(domain, "*", "wildcard", 1)
+	return &v1alpha1.CertificateRef{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				DomainLabel: TopLevelDomain(domain),
+			},
+		},
+		Spec: v1alpha1.CertificateRefSpec{
+			Domain:     domain,
+			Status:     "NOT_READY",
+			Source:     v1alpha1.CertificateSourceACME,
+			SecretName: name,
+		},
+	}
+}
+
 func TopLevelDomain(domain string) string {
 	parts := strings.Split(domain, ".")
 	if len(parts) <= 2 {
@@ -10,4 +10,17 @@ const (
 	IngressHealthPath  = "/healthz/ready"
 	IngressGatewayName = "ingressgateway"
 	MeshGatewayName    = "mesh"
+
+	// internal ingresses are routed through a separate port on the ingress gateway, so that hosts
+	// served by the private load balancer can't be reached through the public one
+	InternalIngressBackendName = "istio-ingressgateway-internal"
+	InternalGatewayName        = "ingressgateway-internal"
+	InternalGatewayPort        = 8081
+
+	// serves ACME http-01 challenges from the operator
+	ACMESolverServiceName = "konstellation-acme"
+	ACMEAccountSecretName = "konstellation-acme-account"
+
+	// serves maintenance pages from the operator
+	MaintenancePagesServiceName = "konstellation-pages"
 )
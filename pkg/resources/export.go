@@ -201,8 +201,27 @@ func (e *Exporter) ExportConfigs(configsDir string) error {
 			name = config.GetSharedName()
 		}
 
-		filename := fmt.Sprintf("%s.yaml", path.Join(configDir, name))
-		err = ioutil.WriteFile(filename, config.ConfigYaml, files.DefaultFileMode)
+		// write in the config's own format, so it can be detected on import.
+		// configs that can't be read back from that format are written as YAML
+		if err := config.CheckEditFormat(config.GetFormat()); err != nil {
+			fmt.Printf("exporting %s config %s as YAML: %v\n", config.Type, name, err)
+			config.Format = v1alpha1.ConfigFormatYAML
+		}
+		content, err := config.GetConfigContent()
+		if err != nil {
+			return err
+		}
+		filename := path.Join(configDir, name) + config.GetFormat().Extension()
+		err = ioutil.WriteFile(filename, content, files.DefaultFileMode)
+		if err == nil && len(config.Schema) != 0 {
+			schemaFile := fmt.Sprintf("%s%s", path.Join(configDir, name), schemaExtension)
+			err = ioutil.WriteFile(schemaFile, config.Schema, files.DefaultFileMode)
+		}
+		if err == nil && config.Templated {
+			// templating is flagged with an empty file next to the config
+			templatedFile := fmt.Sprintf("%s%s", path.Join(configDir, name), templatedExtension)
+			err = ioutil.WriteFile(templatedFile, nil, files.DefaultFileMode)
+		}
 		if err == nil && e.printStatus {
 			fmt.Printf("exported %s config: %s\n", config.Type, name)
 		}
@@ -312,6 +331,11 @@ func (i *Importer) ImportBuilds() error {
 	return nil
 }
 
+const (
+	schemaExtension    = ".schema.json"
+	templatedExtension = ".templated"
+)
+
 type configImport struct {
 	dir      string
 	confType v1alpha1.ConfigType
@@ -342,7 +366,7 @@ func (i *Importer) ImportConfigs() error {
 
 		// import files directly, and directories as targets
 		for _, f := range files {
-			if strings.HasPrefix(f.Name(), ".") {
+			if strings.HasPrefix(f.Name(), ".") || isConfigSidecar(f.Name()) {
 				continue
 			}
 			itemPath := path.Join(ci.dir, f.Name())
@@ -353,7 +377,7 @@ func (i *Importer) ImportConfigs() error {
 				}
 				target := f.Name()
 				for _, subf := range subfiles {
-					if strings.HasPrefix(f.Name(), ".") {
+					if strings.HasPrefix(subf.Name(), ".") || isConfigSidecar(subf.Name()) {
 						continue
 					} else if subf.IsDir() {
 						return fmt.Errorf("unexpected directory: %s", path.Join(itemPath, subf.Name()))
@@ -378,6 +402,11 @@ func (i *Importer) ImportConfigs() error {
 	return nil
 }
 
+// schema and templated files are stored next to configs, and aren't configs themselves
+func isConfigSidecar(name string) bool {
+	return strings.HasSuffix(name, schemaExtension) || strings.HasSuffix(name, templatedExtension)
+}
+
 func (i *Importer) ImportLinkedAccounts() error {
 	dir := path.Join(i.sourcePath, "linkedaccounts")
 	files, err := ioutil.ReadDir(dir)
@@ -429,9 +458,56 @@ func (i *Importer) importConfig(filename string, confType v1alpha1.ConfigType, t
 		return err
 	}
 
-	if err = conf.SetConfigYAML(data); err != nil {
+	format, err := v1alpha1.ConfigFormatForExtension(extension)
+	if err != nil {
+		return errors.Wrapf(err, "failed to import config: %s", filename)
+	}
+	if format != v1alpha1.ConfigFormatYAML {
+		conf.Format = format
+	}
+	if err = conf.SetConfigContent(data, format); err != nil {
+		return errors.Wrapf(err, "failed to import config: %s", filename)
+	}
+
+	// schema for shared configs are stored next to the base config
+	schemaFile := path.Join(path.Dir(filename), name+schemaExtension)
+	if confType == v1alpha1.ConfigTypeShared && target == "" {
+		if schema, err := ioutil.ReadFile(schemaFile); err == nil {
+			conf.Schema = schema
+		}
+	}
+
+	templatedFile := path.Join(path.Dir(filename), name+templatedExtension)
+	if _, err := os.Stat(templatedFile); err == nil {
+		conf.Templated = true
+	}
+
+	schema, err := i.schemaForConfig(conf)
+	if err != nil {
+		return err
+	}
+	if err = ValidateAppConfig(i.client, conf, schema); err != nil {
 		return errors.Wrapf(err, "failed to import config: %s", filename)
 	}
 
 	return SaveAppConfig(i.client, conf)
 }
+
+// apps are imported after configs, so prefer the schema from the app that's being imported
+func (i *Importer) schemaForConfig(conf *v1alpha1.AppConfig) ([]byte, error) {
+	if conf.Type == v1alpha1.ConfigTypeApp {
+		appFile := path.Join(i.sourcePath, "apps", conf.GetAppName()+".yaml")
+		if _, err := os.Stat(appFile); err == nil {
+			obj, err := ReadObjectFromFile(i.decoder, appFile, &v1alpha1.App{})
+			if err != nil {
+				return nil, err
+			}
+			app := obj.(*v1alpha1.App)
+			if app.Spec.ConfigSchema == nil {
+				return nil, nil
+			}
+			return app.Spec.ConfigSchema.Raw, nil
+		}
+	}
+	return GetConfigSchema(i.client, conf)
+}
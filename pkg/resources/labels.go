@@ -1,18 +1,21 @@
 package resources
 
 const (
-	NodepoolPrefix     = "kon-nodepool"
-	NodepoolLabel      = "k11n.dev/nodepool"
-	AppLabel           = "k11n.dev/app"
-	TargetLabel        = "k11n.dev/target"
-	BuildRegistryLabel = "k11n.dev/buildRegistry"
-	BuildImageLabel    = "k11n.dev/buildImage"
-	BuildLabel         = "k11n.dev/build"
-	BuildTypeLabel     = "k11n.dev/buildType"
-	AppReleaseLabel    = "k11n.dev/appRelease"
-	DomainLabel        = "k11n.dev/domain"
-	TargetReleaseLabel = "k11n.dev/targetRelease"
-	AppProtocolLabel   = "k11n.dev/appProtocol"
+	NodepoolPrefix         = "kon-nodepool"
+	NodepoolLabel          = "k11n.dev/nodepool"
+	AppLabel               = "k11n.dev/app"
+	TargetLabel            = "k11n.dev/target"
+	BuildRegistryLabel     = "k11n.dev/buildRegistry"
+	BuildImageLabel        = "k11n.dev/buildImage"
+	BuildLabel             = "k11n.dev/build"
+	BuildTypeLabel         = "k11n.dev/buildType"
+	AppReleaseLabel        = "k11n.dev/appRelease"
+	DomainLabel            = "k11n.dev/domain"
+	TargetReleaseLabel     = "k11n.dev/targetRelease"
+	AppProtocolLabel       = "k11n.dev/appProtocol"
+	IngressRoutesLabel     = "k11n.dev/ingressRoutes"
+	IngressAuthLabel       = "k11n.dev/ingressAuth"
+	IngressVisibilityLabel = "k11n.dev/ingressVisibility"
 
 	KubeManagedByLabel   = "app.kubernetes.io/managed-by"
 	KubeAppLabel         = "app"
@@ -27,6 +27,9 @@ const (
 )
 
 var (
+	// fields containing the content of objects, Data fields are used by ConfigMaps and Secrets
+	contentFields = []string{"Spec", "Data", "BinaryData"}
+
 	ErrNotFound = fmt.Errorf("the resource is not found")
 	Break       = fmt.Errorf("")
 	log         = logf.Log.WithName("resources")
@@ -41,7 +44,7 @@ func UpdateResourceWithMerge(kclient client.Client, object, owner metav1.Object,
 }
 
 // Create or update the resource
-// only handles updates to Annotations, Labels, and Spec
+// only handles updates to Annotations, Labels, and Spec (or Data for ConfigMaps and Secrets)
 // TODO: this is very core and need tests
 func updateResource(kclient client.Client, object, owner metav1.Object, scheme *runtime.Scheme, merge bool) (controllerutil.OperationResult, error) {
 	existingVal := reflect.New(reflect.TypeOf(object).Elem())
@@ -89,17 +92,22 @@ func updateResource(kclient client.Client, object, owner metav1.Object, scheme *
 	// updates and confirm
 	existingCopy := existingRuntimeObj.DeepCopyObject()
 
-	existingSpec := existingVal.Elem().FieldByName("Spec")
-	targetSpec := reflect.ValueOf(object).Elem().FieldByName("Spec")
-	if merge {
-		objects.MergeObject(existingSpec.Addr().Interface(), targetSpec.Addr().Interface())
-	} else {
-		existingSpec.Set(targetSpec)
-	}
-	copiedSpec := reflect.ValueOf(existingCopy).Elem().FieldByName("Spec")
-	if !apiequality.Semantic.DeepEqual(existingSpec.Addr().Interface(), copiedSpec.Addr().Interface()) {
-		//log.Info("changes detected", "old", copiedSpec.Addr().Interface(), "new", existingSpec.Addr().Interface())
-		changed = true
+	for _, field := range contentFields {
+		existingSpec := existingVal.Elem().FieldByName(field)
+		if !existingSpec.IsValid() {
+			continue
+		}
+		targetSpec := reflect.ValueOf(object).Elem().FieldByName(field)
+		if merge {
+			objects.MergeObject(existingSpec.Addr().Interface(), targetSpec.Addr().Interface())
+		} else {
+			existingSpec.Set(targetSpec)
+		}
+		copiedSpec := reflect.ValueOf(existingCopy).Elem().FieldByName(field)
+		if !apiequality.Semantic.DeepEqual(existingSpec.Addr().Interface(), copiedSpec.Addr().Interface()) {
+			//log.Info("changes detected", "old", copiedSpec.Addr().Interface(), "new", existingSpec.Addr().Interface())
+			changed = true
+		}
 	}
 
 	// copy over status if available
@@ -158,7 +166,7 @@ func ForEach(kclient client.Client, listObj runtime.Object, eachFunc func(item i
 		}
 
 		itemsField := listVal.FieldByName("Items")
-		if itemsField.IsZero() {
+		if !itemsField.IsValid() {
 			return fmt.Errorf("list object doesn't not contain Items field")
 		}
 
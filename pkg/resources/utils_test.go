@@ -0,0 +1,82 @@
+package resources
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestUpdateResourceWithData(t *testing.T) {
+	kclient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme)
+	newConfigMap := func(value string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "files",
+			},
+			Data: map[string]string{
+				"config.yaml": value,
+			},
+		}
+	}
+
+	op, err := UpdateResource(kclient, newConfigMap("key: value"), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, op)
+
+	op, err = UpdateResource(kclient, newConfigMap("key: value"), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultNone, op)
+
+	op, err = UpdateResource(kclient, newConfigMap("key: updated"), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, op)
+
+	cm := &corev1.ConfigMap{}
+	err = kclient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "files"}, cm)
+	assert.NoError(t, err)
+	assert.Equal(t, "key: updated", cm.Data["config.yaml"])
+}
+
+func TestUpdateResourceWithSecret(t *testing.T) {
+	kclient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme)
+	newSecret := func(data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "default",
+				Name:      "credentials",
+			},
+			Data: data,
+		}
+	}
+
+	op, err := UpdateResource(kclient, newSecret(map[string][]byte{"password": []byte("a")}), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultCreated, op)
+
+	// keys missing from the desired object are removed unless merging
+	op, err = UpdateResourceWithMerge(kclient, newSecret(map[string][]byte{"token": []byte("b")}), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, op)
+
+	secret := &corev1.Secret{}
+	err = kclient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "credentials"}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"password": []byte("a"), "token": []byte("b")}, secret.Data)
+
+	op, err = UpdateResource(kclient, newSecret(map[string][]byte{"token": []byte("b")}), nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdated, op)
+
+	secret = &corev1.Secret{}
+	err = kclient.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "credentials"}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"token": []byte("b")}, secret.Data)
+}
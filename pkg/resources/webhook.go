@@ -0,0 +1,114 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/k11n/konstellation/pkg/utils/tls"
+)
+
+const (
+	WebhookServiceName           = "webhook-service"
+	WebhookCertSecretName        = "webhook-server-cert"
+	ValidatingWebhookConfigName  = "validating-webhook-configuration"
+	webhookCertValidity          = 5 * 365 * 24 * time.Hour
+	webhookCertRenewBeforeExpiry = 30 * 24 * time.Hour
+)
+
+// makes sure the webhook server has a certificate that the API server trusts.
+// a self-signed certificate is kept in a secret so that all replicas share it, it's written to certDir,
+// and set as the CA bundle of the webhook configuration.
+// webhooks are shipped to ignore failures, since they can't be called without the CA bundle. once it's set,
+// requests are rejected when the webhook fails
+func EnsureWebhookCertificate(kclient client.Client, certDir string) error {
+	secret, err := getOrCreateWebhookSecret(kclient)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(certDir, 0700); err != nil {
+		return err
+	}
+	for _, key := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err = ioutil.WriteFile(filepath.Join(certDir, key), secret.Data[key], 0600); err != nil {
+			return err
+		}
+	}
+
+	config := &admissionv1beta1.ValidatingWebhookConfiguration{}
+	err = kclient.Get(context.TODO(), client.ObjectKey{Name: ValidatingWebhookConfigName}, config)
+	if err != nil {
+		return err
+	}
+	failurePolicy := admissionv1beta1.Fail
+	for i := range config.Webhooks {
+		config.Webhooks[i].ClientConfig.CABundle = secret.Data[corev1.TLSCertKey]
+		config.Webhooks[i].FailurePolicy = &failurePolicy
+	}
+	return kclient.Update(context.TODO(), config)
+}
+
+func getOrCreateWebhookSecret(kclient client.Client) (*corev1.Secret, error) {
+	secret, err := GetSecret(kclient, KonSystemNamespace, WebhookCertSecretName)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && isWebhookCertValid(secret) {
+		return secret, nil
+	}
+
+	hosts := []string{
+		fmt.Sprintf("%s.%s.svc", WebhookServiceName, KonSystemNamespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", WebhookServiceName, KonSystemNamespace),
+	}
+	certPEM, keyPEM, err := tls.GenerateSelfSigned(hosts, webhookCertValidity)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: KonSystemNamespace,
+				Name:      WebhookCertSecretName,
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+	}
+	secret.Data = map[string][]byte{
+		corev1.TLSCertKey:       certPEM,
+		corev1.TLSPrivateKeyKey: keyPEM,
+	}
+	if exists {
+		err = kclient.Update(context.TODO(), secret)
+	} else {
+		err = kclient.Create(context.TODO(), secret)
+	}
+	if errors.IsAlreadyExists(err) || errors.IsConflict(err) {
+		// another replica got to it first, use theirs
+		return GetSecret(kclient, KonSystemNamespace, WebhookCertSecretName)
+	}
+	return secret, err
+}
+
+func isWebhookCertValid(secret *corev1.Secret) bool {
+	if len(secret.Data[corev1.TLSPrivateKeyKey]) == 0 {
+		return false
+	}
+	cert, err := tls.ParseCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return false
+	}
+	return time.Now().Add(webhookCertRenewBeforeExpiry).Before(cert.NotAfter)
+}
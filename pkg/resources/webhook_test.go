@@ -0,0 +1,56 @@
+package resources
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureWebhookCertificate(t *testing.T) {
+	ignore := admissionv1beta1.Ignore
+	kclient := fake.NewFakeClientWithScheme(clientgoscheme.Scheme, &admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ValidatingWebhookConfigName,
+		},
+		Webhooks: []admissionv1beta1.ValidatingWebhook{
+			{Name: "vappconfig.k11n.dev", FailurePolicy: &ignore},
+		},
+	})
+	certDir, err := ioutil.TempDir("", "webhook-certs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(certDir)
+
+	err = EnsureWebhookCertificate(kclient, certDir)
+	assert.NoError(t, err)
+
+	secret, err := GetSecret(kclient, KonSystemNamespace, WebhookCertSecretName)
+	assert.NoError(t, err)
+	assert.True(t, isWebhookCertValid(secret))
+
+	certFile, err := ioutil.ReadFile(filepath.Join(certDir, corev1.TLSCertKey))
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Data[corev1.TLSCertKey], certFile)
+
+	config := &admissionv1beta1.ValidatingWebhookConfiguration{}
+	err = kclient.Get(context.TODO(), client.ObjectKey{Name: ValidatingWebhookConfigName}, config)
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Data[corev1.TLSCertKey], config.Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, admissionv1beta1.Fail, *config.Webhooks[0].FailurePolicy)
+
+	// existing certificate is reused
+	err = EnsureWebhookCertificate(kclient, certDir)
+	assert.NoError(t, err)
+	existing, err := GetSecret(kclient, KonSystemNamespace, WebhookCertSecretName)
+	assert.NoError(t, err)
+	assert.Equal(t, secret.Data, existing.Data)
+}
@@ -111,3 +111,31 @@ func (t mergeTransformers) Transformer(oType reflect.Type) func(dst, src reflect
 	}
 	return nil
 }
+
+// deep merges values from src into dest. nested maps are merged key by key,
+// while lists are either replaced or appended to
+func MergeMap(dest, src map[string]interface{}, appendLists bool) {
+	for key, srcVal := range src {
+		destVal, ok := dest[key]
+		if !ok {
+			dest[key] = srcVal
+			continue
+		}
+
+		switch sv := srcVal.(type) {
+		case map[string]interface{}:
+			if dv, ok := destVal.(map[string]interface{}); ok {
+				MergeMap(dv, sv, appendLists)
+				continue
+			}
+		case []interface{}:
+			if dv, ok := destVal.([]interface{}); ok && appendLists {
+				merged := make([]interface{}, 0, len(dv)+len(sv))
+				merged = append(merged, dv...)
+				dest[key] = append(merged, sv...)
+				continue
+			}
+		}
+		dest[key] = srcVal
+	}
+}
@@ -0,0 +1,36 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	hosts := []string{"webhook-service.kon-system.svc", "webhook-service.kon-system.svc.cluster.local"}
+	certPEM, keyPEM, err := GenerateSelfSigned(hosts, 24*time.Hour)
+	assert.NoError(t, err)
+
+	_, err = tls.X509KeyPair(certPEM, keyPEM)
+	assert.NoError(t, err)
+
+	cert, err := ParseCertificate(certPEM)
+	assert.NoError(t, err)
+	assert.Equal(t, hosts, cert.DNSNames)
+	assert.True(t, cert.NotAfter.After(time.Now().Add(23*time.Hour)))
+
+	// trusts itself when used as the CA bundle
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(certPEM))
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName: hosts[0],
+		Roots:   pool,
+	})
+	assert.NoError(t, err)
+
+	_, _, err = GenerateSelfSigned(nil, time.Hour)
+	assert.Error(t, err)
+}